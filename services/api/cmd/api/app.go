@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/auth"
+	"github.com/smartik/api/internal/config"
+	"github.com/smartik/api/internal/ocr"
+	"github.com/smartik/api/internal/repository"
+	"github.com/smartik/api/internal/repository/postgres"
+	"github.com/smartik/api/internal/repository/storage"
+	"github.com/smartik/api/internal/service"
+	"github.com/smartik/api/internal/worker"
+	"gorm.io/gorm"
+)
+
+// The connections, repositories and services shared by the commands
+type app struct {
+	cfg   *config.Env
+	db    *gorm.DB
+	store storage.Storage
+
+	auditRepo              *repository.AuditRepository
+	userRepo               *repository.UserRepository
+	sessionRepo            *repository.SessionRepository
+	studentRepo            *repository.StudentRepository
+	subjectRepo            *repository.SubjectRepository
+	examRepo               *repository.ExamRepository
+	answerScriptRepo       *repository.AnswerScriptRepository
+	memorandumRepo         *repository.MemorandumRepository
+	memorandumQuestionRepo *repository.MemorandumQuestionRepository
+	questionMarkRepo       *repository.QuestionMarkRepository
+	extractedAnswerRepo    *repository.ExtractedAnswerRepository
+	gradingJobRepo         *repository.GradingJobRepository
+	uploadSessionRepo      *repository.UploadSessionRepository
+	fileVersionRepo        *repository.FileVersionRepository
+
+	auditService              *service.AuditService
+	downloadLinkService       *service.DownloadLinkService
+	authService               *service.AuthService
+	userService               *service.UserService
+	studentService            *service.StudentService
+	subjectService            *service.SubjectService
+	examService               *service.ExamService
+	answerScriptService       *service.AnswerScriptService
+	uploadSessionService      *service.UploadSessionService
+	scriptLinkService         *service.ScriptLinkService
+	memorandumService         *service.MemorandumService
+	memorandumQuestionService *service.MemorandumQuestionService
+	questionMarkService       *service.QuestionMarkService
+	matchingService           *service.MatchingService
+	gradingService            *service.GradingService
+	exportService             *service.ExportService
+}
+
+// Connects to the database and storage and wires up the services
+func newApp(cfg *config.Env) *app {
+	a := &app{cfg: cfg, db: openDatabase(cfg)}
+
+	store, err := storage.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	a.store = store
+
+	// Initialize repositories
+	a.auditRepo = repository.NewAuditRepository(a.db)
+	a.userRepo = repository.NewUserRepository(a.db)
+	a.sessionRepo = repository.NewSessionRepository(a.db)
+	a.studentRepo = repository.NewStudentRepository(a.db)
+	a.subjectRepo = repository.NewSubjectRepository(a.db)
+	a.examRepo = repository.NewExamRepository(a.db)
+	a.answerScriptRepo = repository.NewAnswerScriptRepository(a.db)
+	a.memorandumRepo = repository.NewMemorandumRepository(a.db)
+	a.memorandumQuestionRepo = repository.NewMemorandumQuestionRepository(a.db)
+	a.questionMarkRepo = repository.NewQuestionMarkRepository(a.db)
+	a.extractedAnswerRepo = repository.NewExtractedAnswerRepository(a.db)
+	a.gradingJobRepo = repository.NewGradingJobRepository(a.db)
+	a.uploadSessionRepo = repository.NewUploadSessionRepository(a.db)
+	a.fileVersionRepo = repository.NewFileVersionRepository(a.db)
+
+	// Initialize services
+	a.auditService = service.NewAuditService(a.auditRepo)
+	signer, err := auth.NewSigner(cfg.DownloadLinkSecret)
+	if err != nil {
+		log.Fatalf("Failed to initialize download link signer: %v", err)
+	}
+	a.downloadLinkService = service.NewDownloadLinkService(a.userRepo, store, signer, cfg)
+	a.authService = service.NewAuthService(a.userRepo, a.sessionRepo, cfg.SessionTTL)
+	a.userService = service.NewUserService(a.userRepo, a.sessionRepo, a.auditService)
+	a.studentService = service.NewStudentService(a.studentRepo, a.examRepo, a.answerScriptRepo, a.auditService)
+	a.subjectService = service.NewSubjectService(a.subjectRepo, a.examRepo, a.answerScriptRepo, a.auditService)
+	a.examService = service.NewExamService(a.examRepo, a.subjectRepo, a.answerScriptRepo, a.memorandumQuestionRepo, a.gradingJobRepo, a.auditService)
+	a.answerScriptService = service.NewAnswerScriptService(a.answerScriptRepo, a.examRepo, a.fileVersionRepo, store, a.downloadLinkService, cfg, a.auditService)
+	a.uploadSessionService = service.NewUploadSessionService(a.uploadSessionRepo, a.examRepo, a.answerScriptService, store, cfg)
+	a.scriptLinkService = service.NewScriptLinkService(a.answerScriptRepo, a.studentRepo, a.subjectRepo, a.examRepo, a.auditService)
+	a.memorandumService = service.NewMemorandumService(a.memorandumRepo, a.examRepo, a.questionMarkRepo, a.fileVersionRepo, store, a.downloadLinkService, cfg, a.auditService)
+	a.memorandumQuestionService = service.NewMemorandumQuestionService(a.memorandumQuestionRepo, a.memorandumRepo, a.examRepo, a.questionMarkRepo, a.auditService)
+	a.questionMarkService = service.NewQuestionMarkService(a.questionMarkRepo, a.answerScriptRepo, a.memorandumQuestionRepo, a.examRepo, a.auditService)
+	a.matchingService = service.NewMatchingService(a.studentRepo, a.answerScriptRepo, cfg.MatchThreshold, a.auditService)
+	a.gradingService = service.NewGradingService(a.gradingJobRepo, a.examRepo, a.answerScriptRepo, a.memorandumQuestionRepo,
+		a.extractedAnswerRepo, a.questionMarkRepo, service.NewRuleBasedGrader(), a.auditService,
+	)
+	a.exportService = service.NewExportService(a.examRepo, a.subjectRepo, a.answerScriptRepo, a.memorandumQuestionRepo, a.questionMarkRepo)
+
+	return a
+}
+
+// Creates the worker that reads exam numbers from uploaded scripts
+func (a *app) newScriptProcessor() *worker.ScriptProcessor {
+	recognizer, err := ocr.New(a.cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize OCR recognizer: %v", err)
+	}
+	return worker.NewScriptProcessor(a.answerScriptService, a.store, recognizer, a.matchingService,
+		a.cfg.WorkerConcurrency, a.cfg.WorkerPollInterval,
+	)
+}
+
+// Closes the database connection
+func (a *app) close() {
+	closeDatabase(a.db)
+}
+
+// Connects to the database, applying pending migrations when MIGRATE_ON_START is set
+func openDatabase(cfg *config.Env) *gorm.DB {
+	db, err := postgres.NewConnection(cfg.PostgresURI)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	// Several processes may start at once, the migrator lets only one of them migrate
+	if cfg.MigrateOnStart {
+		migrator, err := postgres.NewMigrator(db)
+		if err != nil {
+			log.Fatalf("Failed to load migrations: %v", err)
+		}
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+		for _, migration := range applied {
+			log.Infof("Applied migration %d_%s", migration.Version, migration.Name)
+		}
+	}
+	return db
+}
+
+func closeDatabase(db *gorm.DB) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Failed to get database instance: %v", err)
+	}
+	if err := sqlDB.Close(); err != nil {
+		log.Fatalf("Failed to close database connection: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/config"
+	"github.com/smartik/api/internal/service"
+)
+
+// Writes the mark sheet of an exam or a subject, e.g.
+// api export -exam <id> -format xlsx -o marks.xlsx
+func runExport(cfg *config.Env, args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	examId := flags.String("exam", "", "Id of the exam to export")
+	subjectId := flags.String("subject", "", "Id of the subject to export")
+	formatName := flags.String("format", "csv", "csv, xlsx or pdf")
+	output := flags.String("o", "", `file to write to, "-" for standard output. Defaults to the name the API downloads it as`)
+	flags.Parse(args)
+
+	if (*examId == "") == (*subjectId == "") {
+		fmt.Fprintln(os.Stderr, "either -exam or -subject is required")
+		flags.Usage()
+		os.Exit(2)
+	}
+	format, err := service.ParseExportFormat(*formatName)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	a := newApp(cfg)
+	defer a.close()
+
+	var sheet *service.MarkSheet
+	if *examId != "" {
+		sheet, err = a.exportService.ExamMarkSheet(*examId)
+	} else {
+		sheet, err = a.exportService.SubjectMarkSheet(*subjectId)
+	}
+	if err != nil {
+		log.Fatalf("Failed to prepare mark sheet: %v", err)
+	}
+
+	path := *output
+	if path == "" {
+		path = sheet.FileName(format)
+	}
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", path, err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if err := sheet.Write(w, format); err != nil {
+		log.Fatalf("Failed to write mark sheet: %v", err)
+	}
+	if path != "-" {
+		fmt.Printf("wrote %s\n", path)
+	}
+}
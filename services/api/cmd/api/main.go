@@ -1,145 +1,71 @@
 package main
 
 import (
-	"context"
 	"fmt"
-	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
-	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
-	"github.com/smartik/api/internal/api/handlers"
-	"github.com/smartik/api/internal/api/routes"
 	"github.com/smartik/api/internal/config"
-	"github.com/smartik/api/internal/models"
-	"github.com/smartik/api/internal/repository"
-	"github.com/smartik/api/internal/repository/minio"
-	"github.com/smartik/api/internal/repository/postgres"
-	"github.com/smartik/api/internal/service"
 )
 
-var startTime time.Time
+// A subcommand of the api binary
+type command struct {
+	name    string
+	summary string
+	run     func(cfg *config.Env, args []string)
+}
 
-func main() {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		log.Warnf("Failed to load config: %v (Using defaults)", err)
-	}
+var commands = []command{
+	{"serve", "Serve the API (default)", runServe},
+	{"worker", "Process uploaded answer scripts without serving the API", runWorker},
+	{"migrate", "Apply, roll back or list database migrations", runMigrate},
+	{"seed", "Fill an empty database with example data", runSeed},
+	{"export", "Write the mark sheet of an exam or subject to a file", runExport},
+	{"rekey-storage", "Move stored files to keys derived from their record Id", runRekeyStorage},
+}
 
-	// Database connection
-	db, err := postgres.NewConnection(cfg.PostgresURI)
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+func main() {
+	// Without a command the API is served, as before commands existed
+	name, args := "serve", []string{}
+	if len(os.Args) > 1 {
+		name, args = os.Args[1], os.Args[2:]
 	}
-	defer func() {
-		sqlDB, err := db.DB()
-		if err != nil {
-			log.Fatalf("Failed to get database instance: %v", err)
-		}
-		if err := sqlDB.Close(); err != nil {
-			log.Fatalf("Failed to close database connection: %v", err)
-		}
-	}()
 
-	if err := db.AutoMigrate(models.GetAllModels()...); err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+	// Other commands print their results, keep the logs apart from them
+	if name != "serve" {
+		log.SetOutput(os.Stderr)
 	}
 
-	// Initialize MinIO client
-	minioClient, err := minio.NewMinioClient(cfg.MinioEndpointUrl,
-		cfg.MinioAccessId, cfg.MinioSecretKey, cfg,
-	)
+	// Load configuration
+	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("Something went wrong: %v", err)
+		log.Warnf("Failed to load config: %v (Using defaults)", err)
 	}
 
-	// Initialize repositories and handlers
-	studentRepo := repository.NewStudentRepository(db)
-	subjectRepo := repository.NewSubjectRepository(db)
-	examRepo := repository.NewExamRepository(db)
-	answerScriptRepo := repository.NewAnswerScriptRepository(db)
-	memorandumRepo := repository.NewMemorandumRepository(db)
-
-	// Initialize services
-	studentService := service.NewStudentService(studentRepo)
-	subjectService := service.NewSubjectService(subjectRepo)
-	examService := service.NewExamService(examRepo)
-	answerScriptService := service.NewAnswerScriptService(answerScriptRepo, minioClient, cfg)
-	memorandumService := service.NewMemorandumService(memorandumRepo, minioClient, cfg)
-
-	// Initialize handlers
-	studentHandler := handlers.NewStudentHandler(studentService)
-	subjectHandler := handlers.NewSubjectHandler(subjectService)
-	examHandler := handlers.NewExamHandler(examService)
-	answerScriptHandler := handlers.NewAnswerScriptHandler(answerScriptService)
-	memorandumHandler := handlers.NewMemorandumHandler(memorandumService)
-
-	// Create Echo instance
-	e := echo.New()
-	e.Validator = NewCustomValidator()
-	addr := fmt.Sprintf(":%s", cfg.Port)
-	startTime = time.Now() // Record the start time
-
-	// Middlware
-	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
-		LogLevel: log.ERROR,
-	}))
-
-	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
-		Format: "[${time_rfc3339}] ${remote_ip} ${method} ${path} ${status}\n",
-	}))
-
-	// Routes
-	v1 := e.Group("/api/v1")
-	{
-		v1.Any("/health", func(c echo.Context) error {
-			t := time.Since(startTime)
-
-			return c.JSON(http.StatusOK, echo.Map{
-				"status": "healthy",
-				"time":   time.Now().Format(time.RFC3339),
-				"uptime": string(fmt.Sprintf("%d Hours, %d Minutes, %d Seconds",
-					int(t.Abs().Hours()),
-					int(t.Abs().Minutes()),
-					int(t.Abs().Seconds()),
-				)),
-			})
-		})
-
-		v1.GET("/reference", func(c echo.Context) error {
-			return c.JSON(http.StatusOK, echo.Map{
-				"message": "Available Routes Reference",
-				"routes":  e.Routes(),
-			})
-		})
-
-		routes.RegisterStudentRoutes(v1, studentHandler)
-		routes.RegisterSubjectRoutes(v1, subjectHandler)
-		routes.RegisterExamRoutes(v1, examHandler)
-		routes.RegisterAnswerScriptRoutes(v1, answerScriptHandler)
-		routes.RegisterMemorandumRoutes(v1, memorandumHandler)
+	switch name {
+	case "help", "-h", "-help", "--help":
+		usage(os.Stdout)
+		return
 	}
-
-	go func() {
-		if err := e.Start(addr); err != nil {
-			e.Logger.Infof("Shutting down server: %v", err)
+	for _, command := range commands {
+		if command.name == name {
+			command.run(cfg, args)
+			return
 		}
-	}()
-
-	// Graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-	<-quit
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	usage(os.Stderr)
+	os.Exit(2)
+}
 
-	if err := e.Shutdown(ctx); err != nil {
-		e.Logger.Fatalf("Failed to gracefully shutdown server: %v", err)
+func usage(w *os.File) {
+	fmt.Fprintln(w, "Usage: api <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, command := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", command.name, command.summary)
 	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "api <command> -h" for the arguments of a command.`)
 }
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/config"
+	"github.com/smartik/api/internal/service"
+)
+
+// Moves answer script and memorandum files that were stored under their
+// original file name to collision-safe keys derived from their record Id.
+//
+// Safe to run more than once: records that already have a storage key are skipped.
+func runRekeyStorage(cfg *config.Env, args []string) {
+	flags := flag.NewFlagSet("rekey-storage", flag.ExitOnError)
+	flags.Parse(args)
+
+	a := newApp(cfg)
+	defer a.close()
+
+	scripts, err := a.answerScriptService.MigrateStorageKeys(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to re-key answer scripts: %v", err)
+	}
+	report("answer scripts", scripts)
+
+	memorandums, err := a.memorandumService.MigrateStorageKeys(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to re-key memorandums: %v", err)
+	}
+	report("memorandums", memorandums)
+}
+
+func report(kind string, result *service.StorageKeyMigrationResult) {
+	fmt.Printf("Re-keyed %d %s\n", result.Migrated, kind)
+	for _, failure := range result.Failed {
+		fmt.Printf("  failed %s: %s\n", failure.Filename, failure.Error)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/config"
+	"github.com/smartik/api/internal/repository"
+)
+
+// Fills an empty database with example students, subjects, exams and
+// answer scripts. Refused in production unless -force is given.
+func runSeed(cfg *config.Env, args []string) {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	force := flags.Bool("force", false, "seed even when GO_ENV is production")
+	flags.Parse(args)
+
+	if cfg.GoEnv == config.GoEnvProduction && !*force {
+		log.Fatalf("Refusing to seed a production database, use -force to do it anyway")
+	}
+
+	db := openDatabase(cfg)
+	defer closeDatabase(db)
+
+	if err := repository.SeedDatabase(db); err != nil {
+		log.Fatalf("Failed to seed database: %v", err)
+	}
+	fmt.Println("database seeded, databases that already had students are left as they were")
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/api/handlers"
+	apimiddleware "github.com/smartik/api/internal/api/middleware"
+	"github.com/smartik/api/internal/api/routes"
+	"github.com/smartik/api/internal/config"
+)
+
+var startTime time.Time
+
+// Serves the API, and processes uploaded scripts in the background unless
+// -worker=false is given because a separate worker process does that
+func runServe(cfg *config.Env, args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	runWorker := flags.Bool("worker", true, "process uploaded scripts in this process")
+	flags.Parse(args)
+
+	// Without a secret download links are signed with a random key, which
+	// other instances do not share and a restart throws away
+	if cfg.DownloadLinkSecret == "" {
+		if cfg.GoEnv == config.GoEnvProduction {
+			log.Fatal("DOWNLOAD_LINK_SECRET has to be set when GO_ENV is production")
+		}
+		log.Warn("DOWNLOAD_LINK_SECRET is not set, download links stop working when the API restarts")
+	}
+
+	a := newApp(cfg)
+	defer a.close()
+
+	if err := a.authService.EnsureAdmin(cfg.AdminEmail, cfg.AdminPassword); err != nil {
+		log.Fatalf("Failed to create admin user: %v", err)
+	}
+	if err := a.gradingService.FailInterruptedJobs(); err != nil {
+		log.Fatalf("Failed to clean up abandoned grading jobs: %v", err)
+	}
+
+	// Initialize handlers
+	authHandler := handlers.NewAuthHandler(a.authService)
+	userHandler := handlers.NewUserHandler(a.userService)
+	studentHandler := handlers.NewStudentHandler(a.studentService)
+	subjectHandler := handlers.NewSubjectHandler(a.subjectService)
+	examHandler := handlers.NewExamHandler(a.examService)
+	answerScriptHandler := handlers.NewAnswerScriptHandler(a.answerScriptService)
+	uploadSessionHandler := handlers.NewUploadSessionHandler(a.uploadSessionService)
+	scriptLinkHandler := handlers.NewScriptLinkHandler(a.scriptLinkService)
+	memorandumHandler := handlers.NewMemorandumHandler(a.memorandumService)
+	memorandumQuestionHandler := handlers.NewMemorandumQuestionHandler(a.memorandumQuestionService)
+	questionMarkHandler := handlers.NewQuestionMarkHandler(a.questionMarkService)
+	reviewHandler := handlers.NewReviewHandler(a.matchingService)
+	gradingHandler := handlers.NewGradingHandler(a.gradingService)
+	exportHandler := handlers.NewExportHandler(a.exportService)
+	auditHandler := handlers.NewAuditHandler(a.auditService)
+	downloadHandler := handlers.NewDownloadHandler(a.downloadLinkService)
+
+	// Create Echo instance
+	e := echo.New()
+	e.Validator = NewCustomValidator()
+	addr := fmt.Sprintf(":%s", cfg.Port)
+	startTime = time.Now() // Record the start time
+
+	// Middlware
+	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
+		LogLevel: log.ERROR,
+	}))
+
+	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
+		Format: "[${time_rfc3339}] ${remote_ip} ${method} ${path} ${status}\n",
+	}))
+
+	// Routes
+	v1 := e.Group("/api/v1")
+	{
+		v1.Any("/health", func(c echo.Context) error {
+			t := time.Since(startTime)
+
+			return c.JSON(http.StatusOK, echo.Map{
+				"status": "healthy",
+				"time":   time.Now().Format(time.RFC3339),
+				"uptime": string(fmt.Sprintf("%d Hours, %d Minutes, %d Seconds",
+					int(t.Abs().Hours()),
+					int(t.Abs().Minutes()),
+					int(t.Abs().Seconds()),
+				)),
+			})
+		})
+
+		v1.GET("/reference", func(c echo.Context) error {
+			return c.JSON(http.StatusOK, echo.Map{
+				"message": "Available Routes Reference",
+				"routes":  e.Routes(),
+			})
+		})
+
+		authenticate := apimiddleware.Authenticate(a.authService)
+		routes.RegisterAuthRoutes(v1, authHandler, authenticate)
+		routes.RegisterDownloadRoutes(v1, downloadHandler)
+
+		// Everything else requires a signed in user. The middleware is
+		// attached per resource rather than to a group without a prefix,
+		// whose catch all would answer unknown routes with 401 instead of 404.
+		routes.RegisterUserRoutes(v1, userHandler, authenticate)
+		routes.RegisterStudentRoutes(v1, studentHandler, authenticate)
+		routes.RegisterSubjectRoutes(v1, subjectHandler, authenticate)
+		routes.RegisterExamRoutes(v1, examHandler, authenticate)
+		routes.RegisterAnswerScriptRoutes(v1, answerScriptHandler, authenticate)
+		routes.RegisterUploadSessionRoutes(v1, uploadSessionHandler, authenticate)
+		routes.RegisterScriptLinkRoutes(v1, scriptLinkHandler, authenticate)
+		routes.RegisterMemorandumRoutes(v1, memorandumHandler, authenticate)
+		routes.RegisterMemorandumQuestionRoutes(v1, memorandumQuestionHandler, authenticate)
+		routes.RegisterQuestionMarkRoutes(v1, questionMarkHandler, authenticate)
+		routes.RegisterReviewRoutes(v1, reviewHandler, authenticate)
+		routes.RegisterGradingRoutes(v1, gradingHandler, authenticate)
+		routes.RegisterExportRoutes(v1, exportHandler, authenticate)
+		routes.RegisterAuditRoutes(v1, auditHandler, authenticate)
+	}
+
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	defer stopWorker()
+	if *runWorker {
+		go a.newScriptProcessor().Run(workerCtx)
+	}
+
+	go func() {
+		if err := e.Start(addr); err != nil {
+			e.Logger.Infof("Shutting down server: %v", err)
+		}
+	}()
+
+	// Graceful shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+	stopWorker()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := e.Shutdown(ctx); err != nil {
+		e.Logger.Fatalf("Failed to gracefully shutdown server: %v", err)
+	}
+}
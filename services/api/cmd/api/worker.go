@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/config"
+)
+
+// Processes uploaded answer scripts until interrupted. Any number of
+// workers can run next to API processes started with -worker=false.
+func runWorker(cfg *config.Env, args []string) {
+	flags := flag.NewFlagSet("worker", flag.ExitOnError)
+	flags.Parse(args)
+
+	a := newApp(cfg)
+	defer a.close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Infof("Processing answer scripts with %d workers", cfg.WorkerConcurrency)
+	a.newScriptProcessor().Run(ctx)
+}
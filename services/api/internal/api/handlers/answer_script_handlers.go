@@ -1,12 +1,15 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
 	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/pdfsplit"
+	"github.com/smartik/api/internal/repository"
 	"github.com/smartik/api/internal/service"
 	"gorm.io/gorm"
 )
@@ -39,8 +42,22 @@ func (h *AnswerScriptHandler) UploadScripts(c echo.Context) error {
 		})
 	}
 
-	result, err := h.service.UploadFiles(files)
+	// Optionally link the uploaded scripts to an exam
+	var examId *string
+	if values := form.Value["exam_id"]; len(values) > 0 && values[0] != "" {
+		examId = &values[0]
+	}
+
+	result, err := h.service.UploadFiles(c.Request().Context(), files, examId)
 	if err != nil {
+		if errors.Is(err, service.ErrUnknownExam) {
+			return fieldInvalid(c, "exam_id", "exists", err)
+		}
+		if isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
 		log.Errorf("Upload service error: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
 			"message": "Upload service error",
@@ -65,10 +82,98 @@ func (h *AnswerScriptHandler) UploadScripts(c echo.Context) error {
 	})
 }
 
+// Splits a batch PDF into one answer script per learner
+func (h *AnswerScriptHandler) UploadBatch(c echo.Context) error {
+	file, err := c.FormFile("batch")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "No batch provided",
+			"error":   err.Error(),
+		})
+	}
+
+	opts := pdfsplit.Options{
+		Mode:   pdfsplit.Mode(c.FormValue("mode")),
+		Marker: c.FormValue("marker"),
+	}
+	if value := c.FormValue("pages_per_script"); value != "" {
+		if opts.PagesPerScript, err = strconv.Atoi(value); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": "Invalid input",
+				"error":   "pages_per_script must be a number",
+			})
+		}
+	}
+
+	// Optionally link the scripts to an exam
+	var examId *string
+	if value := c.FormValue("exam_id"); value != "" {
+		examId = &value
+	}
+
+	result, err := h.service.UploadBatch(c.Request().Context(), file, examId, opts)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownExam) {
+			return fieldInvalid(c, "exam_id", "exists", err)
+		}
+		if rejected := fileRejection(err); rejected != nil {
+			return c.JSON(fileRejectionStatus(rejected), echo.Map{
+				"message": rejected.Error(),
+				"code":    rejected.Code,
+			})
+		}
+		if errors.Is(err, pdfsplit.ErrInvalidPdf) || errors.Is(err, pdfsplit.ErrInvalidOptions) || errors.Is(err, pdfsplit.ErrNoScripts) {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": err.Error(),
+			})
+		}
+		if isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
+		log.Errorf("Failed to split batch: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to split batch",
+		})
+	}
+
+	// Return partial content if some scripts failed
+	if len(result.FailedUploads) > 0 {
+		return c.JSON(http.StatusPartialContent, echo.Map{
+			"message":            "Some answer scripts of the batch failed to upload",
+			"successful_uploads": len(result.SuccessfulUploads),
+			"failed_uploads":     len(result.FailedUploads),
+			"errors":             result.FailedUploads,
+			"answer_scripts":     result.SuccessfulUploads,
+		})
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"message":            "Batch split into answer scripts successfully",
+		"successful_uploads": len(result.SuccessfulUploads),
+		"answer_scripts":     result.SuccessfulUploads,
+	})
+}
+
 // Retrieves all answer scripts from the database
 func (h *AnswerScriptHandler) GetAllScripts(c echo.Context) error {
-	answerScripts, err := h.service.GetAll()
+	params, err := listParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	answerScripts, err := h.service.GetAll(params)
 	if err != nil {
+		if errors.Is(err, repository.ErrInvalidListParams) {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": "Invalid input",
+				"error":   err.Error(),
+			})
+		}
 		log.Errorf("Failed to get all answer scripts: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
 			"message": "Failed to retrieve answer scripts",
@@ -77,7 +182,8 @@ func (h *AnswerScriptHandler) GetAllScripts(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, echo.Map{
 		"message":        "Answer scripts retrieved successfully",
-		"answer_scripts": answerScripts,
+		"answer_scripts": answerScripts.Items,
+		"pagination":     answerScripts.Pagination,
 	})
 }
 
@@ -121,22 +227,37 @@ func (h *AnswerScriptHandler) ServeAnswerScript(c echo.Context) error {
 			"message": "Failed to retrieve answer script file",
 		})
 	}
-	defer fileStream.Content.Close()
 
-	// Set response headers for file serving
-	c.Response().Header().Set(echo.HeaderContentType, fileStream.ContentType)
-	c.Response().Header().Set(echo.HeaderContentLength, fmt.Sprintf("%d", fileStream.Size))
-	c.Response().Header().Set(echo.HeaderContentDisposition,
-		fmt.Sprintf("inline; filename=\"%s\"", fileStream.Filename))
+	return streamFile(c, fileStream)
+}
 
-	return c.Stream(http.StatusOK, fileStream.ContentType, fileStream.Content)
+// Issues a short lived link the answer script file can be downloaded from directly
+func (h *AnswerScriptHandler) GetScriptDownloadLink(c echo.Context) error {
+	link, err := h.service.GetDownloadLink(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Answer script not found",
+			})
+		}
+
+		log.Errorf("Failed to issue download link: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to issue download link",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message": "Download link issued successfully",
+		"link":    link,
+	})
 }
 
 // Updates an existing answer script record
 func (h *AnswerScriptHandler) UpdateScript(c echo.Context) error {
 	id := c.Param("id")
 
-	var updateData models.AnswerScript
+	var updateData models.UpdateAnswerScript
 	if err := c.Bind(&updateData); err != nil {
 		return c.JSON(http.StatusBadRequest, echo.Map{
 			"message": "Invalid input",
@@ -144,13 +265,30 @@ func (h *AnswerScriptHandler) UpdateScript(c echo.Context) error {
 		})
 	}
 
-	updatedScript, err := h.service.Update(id, &updateData)
+	if err := c.Validate(&updateData); err != nil {
+		return validationFailed(c, err)
+	}
+
+	updatedScript, err := h.service.Update(c.Request().Context(), id, &updateData)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.JSON(http.StatusNotFound, echo.Map{
 				"message": "Answer script not found",
 			})
 		}
+		if errors.Is(err, service.ErrUnknownExam) {
+			return fieldInvalid(c, "exam_id", "exists", err)
+		}
+		if errors.Is(err, service.ErrSubjectMismatch) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
+		if isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
 
 		log.Errorf("Failed to update answer script: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
@@ -164,16 +302,206 @@ func (h *AnswerScriptHandler) UpdateScript(c echo.Context) error {
 	})
 }
 
+// Overrides the processing status of an answer script
+func (h *AnswerScriptHandler) UpdateScriptStatus(c echo.Context) error {
+	var data models.UpdateAnswerScriptStatus
+	if err := c.Bind(&data); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	if err := c.Validate(&data); err != nil {
+		return validationFailed(c, err)
+	}
+
+	answerScript, err := h.service.UpdateStatus(c.Request().Context(), c.Param("id"), &data)
+	return h.respondOverride(c, answerScript, err, "status")
+}
+
+// Overrides what matching found for an answer script
+func (h *AnswerScriptHandler) UpdateScriptMatching(c echo.Context) error {
+	var data models.UpdateAnswerScriptMatching
+	if err := c.Bind(&data); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	if err := c.Validate(&data); err != nil {
+		return validationFailed(c, err)
+	}
+
+	answerScript, err := h.service.UpdateMatching(c.Request().Context(), c.Param("id"), &data)
+	return h.respondOverride(c, answerScript, err, "matching")
+}
+
+func (h *AnswerScriptHandler) respondOverride(c echo.Context, answerScript *models.AnswerScript, err error, what string) error {
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Answer script not found",
+			})
+		}
+		if isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
+
+		log.Errorf("Failed to update answer script %s: %v", what, err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to update answer script " + what,
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":       "Answer script " + what + " updated successfully",
+		"answer_script": answerScript,
+	})
+}
+
+// Queues an answer script to have its exam number read again
+func (h *AnswerScriptHandler) ReprocessScript(c echo.Context) error {
+	id := c.Param("id")
+
+	answerScript, err := h.service.Reprocess(c.Request().Context(), id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Answer script not found",
+			})
+		}
+		if isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
+
+		log.Errorf("Failed to reprocess answer script: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to reprocess answer script",
+		})
+	}
+
+	return c.JSON(http.StatusAccepted, echo.Map{
+		"message":       "Answer script queued for processing",
+		"answer_script": answerScript,
+	})
+}
+
+// Replaces the file of an answer script with a new upload sent as "file"
+func (h *AnswerScriptHandler) ReplaceScriptFile(c echo.Context) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "No file provided",
+			"error":   err.Error(),
+		})
+	}
+
+	answerScript, err := h.service.ReplaceFile(c.Request().Context(), c.Param("id"), file)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Answer script not found",
+			})
+		}
+		if rejected := fileRejection(err); rejected != nil {
+			return c.JSON(fileRejectionStatus(rejected), echo.Map{
+				"message": rejected.Error(),
+				"code":    rejected.Code,
+			})
+		}
+		if errors.Is(err, service.ErrFileChanged) || isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
+
+		log.Errorf("Failed to replace answer script file: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to replace answer script file",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":       "Answer script file replaced successfully",
+		"answer_script": answerScript,
+	})
+}
+
+// Lists every file an answer script has had, newest first
+func (h *AnswerScriptHandler) GetScriptVersions(c echo.Context) error {
+	versions, err := h.service.GetVersions(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Answer script not found",
+			})
+		}
+
+		log.Errorf("Failed to get answer script versions: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to retrieve answer script versions",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":  "Answer script versions retrieved successfully",
+		"versions": versions,
+	})
+}
+
+// Serves a version of the file of an answer script
+func (h *AnswerScriptHandler) ServeScriptVersion(c echo.Context) error {
+	version, ok := versionParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   "the version must be a positive number",
+		})
+	}
+
+	fileStream, err := h.service.GetVersionFileStream(c.Param("id"), version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Answer script not found",
+			})
+		}
+		if errors.Is(err, service.ErrUnknownFileVersion) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": err.Error(),
+			})
+		}
+
+		log.Errorf("Failed to get file stream: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to retrieve answer script file",
+		})
+	}
+
+	return streamFile(c, fileStream)
+}
+
 // Removes an answer script from both database and storage
 func (h *AnswerScriptHandler) DeleteScript(c echo.Context) error {
 	id := c.Param("id")
 
-	if err := h.service.Delete(id); err != nil {
+	if err := h.service.Delete(c.Request().Context(), id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.JSON(http.StatusNotFound, echo.Map{
 				"message": "Answer script not found",
 			})
 		}
+		if isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
 
 		log.Errorf("Failed to delete answer script: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
@@ -183,3 +511,24 @@ func (h *AnswerScriptHandler) DeleteScript(c echo.Context) error {
 
 	return c.JSON(http.StatusNoContent, nil)
 }
+
+// Returns why an uploaded file was refused, nil when err has another cause
+func fileRejection(err error) *service.FileRejectedError {
+	var rejected *service.FileRejectedError
+	if errors.As(err, &rejected) {
+		return rejected
+	}
+	return nil
+}
+
+// Maps the reason a file was refused to a response status
+func fileRejectionStatus(rejected *service.FileRejectedError) int {
+	switch rejected.Code {
+	case service.UploadErrorTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case service.UploadErrorUnsupported:
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/repository"
+	"github.com/smartik/api/internal/service"
+)
+
+// Handles HTTP requests for the audit log
+type AuditHandler struct {
+	service *service.AuditService
+}
+
+// Creates a new instance of AuditHandler
+func NewAuditHandler(service *service.AuditService) *AuditHandler {
+	return &AuditHandler{service}
+}
+
+// Searches the audit log, newest entries first unless sorted otherwise
+func (h *AuditHandler) GetEntries(c echo.Context) error {
+	params, err := listParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	entries, err := h.service.GetAll(params)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidListParams) {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": "Invalid input",
+				"error":   err.Error(),
+			})
+		}
+		log.Errorf("Failed to search audit log: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to retrieve audit entries",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":    "Audit entries retrieved successfully",
+		"entries":    entries.Items,
+		"pagination": entries.Pagination,
+	})
+}
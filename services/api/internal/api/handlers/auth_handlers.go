@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/api/middleware"
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/service"
+)
+
+// Handles HTTP requests for signing in and out
+type AuthHandler struct {
+	service *service.AuthService
+}
+
+// Creates a new instance of AuthHandler
+func NewAuthHandler(service *service.AuthService) *AuthHandler {
+	return &AuthHandler{service}
+}
+
+// Signs a user in and returns a session token
+func (h *AuthHandler) Login(c echo.Context) error {
+	var data models.Login
+	if err := c.Bind(&data); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	if err := c.Validate(&data); err != nil {
+		return validationFailed(c, err)
+	}
+
+	token, session, user, err := h.service.Login(&data)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			return c.JSON(http.StatusUnauthorized, echo.Map{
+				"message": "Invalid email or password",
+			})
+		}
+
+		log.Errorf("Failed to sign in: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to sign in",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":    "Signed in successfully",
+		"token":      token,
+		"expires_at": session.ExpiresAt,
+		"user":       user,
+	})
+}
+
+// Ends the current session
+func (h *AuthHandler) Logout(c echo.Context) error {
+	if err := h.service.Logout(middleware.BearerToken(c)); err != nil {
+		log.Errorf("Failed to sign out: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to sign out",
+		})
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+// Retrieves the signed in user
+func (h *AuthHandler) Me(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{
+		"message": "User retrieved successfully",
+		"user":    middleware.CurrentUser(c),
+	})
+}
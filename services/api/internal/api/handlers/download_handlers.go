@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/service"
+)
+
+// Handles HTTP requests following download links
+type DownloadHandler struct {
+	service *service.DownloadLinkService
+}
+
+// Creates a new instance of DownloadHandler
+func NewDownloadHandler(service *service.DownloadLinkService) *DownloadHandler {
+	return &DownloadHandler{service}
+}
+
+// Redirects to the file a download link was issued for, or serves it when
+// the storage cannot hand it out directly
+func (h *DownloadHandler) Download(c echo.Context) error {
+	target, err := h.service.Resolve(c.Request().Context(), c.Param("token"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidDownloadLink) {
+			return c.JSON(http.StatusForbidden, echo.Map{
+				"message": err.Error(),
+			})
+		}
+
+		log.Errorf("Failed to resolve download link: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to retrieve file",
+		})
+	}
+
+	if target.RedirectUrl != "" {
+		return c.Redirect(http.StatusFound, target.RedirectUrl)
+	}
+	return streamFile(c, target.File)
+}
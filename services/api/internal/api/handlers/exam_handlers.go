@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
 	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository"
 	"github.com/smartik/api/internal/service"
 	"gorm.io/gorm"
 )
@@ -31,13 +33,13 @@ func (h *ExamHandler) CreateExam(c echo.Context) error {
 	}
 
 	if err := c.Validate(&exam); err != nil {
-		return c.JSON(http.StatusBadRequest, echo.Map{
-			"message": "Validation failed",
-			"errors":  err.Error(),
-		})
+		return validationFailed(c, err)
 	}
 
-	if err := h.service.Create(&exam); err != nil {
+	if err := h.service.Create(c.Request().Context(), &exam); err != nil {
+		if errors.Is(err, service.ErrUnknownSubject) {
+			return fieldInvalid(c, "subject_id", "exists", err)
+		}
 		log.Errorf("Failed to create exam: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
 			"message": "Failed to create exam",
@@ -52,8 +54,22 @@ func (h *ExamHandler) CreateExam(c echo.Context) error {
 
 // Retrieves all exams from the database
 func (h *ExamHandler) GetAllExams(c echo.Context) error {
-	exams, err := h.service.GetAll()
+	params, err := listParams(c)
 	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	exams, err := h.service.GetAll(params)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidListParams) {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": "Invalid input",
+				"error":   err.Error(),
+			})
+		}
 		log.Errorf("Failed to retrieve exams: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
 			"message": "Failed to retrieve exams",
@@ -61,8 +77,9 @@ func (h *ExamHandler) GetAllExams(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, echo.Map{
-		"message": "Exams retrieved successfully",
-		"exams":   exams,
+		"message":    "Exams retrieved successfully",
+		"exams":      exams.Items,
+		"pagination": exams.Pagination,
 	})
 }
 
@@ -100,20 +117,30 @@ func (h *ExamHandler) UpdateExam(c echo.Context) error {
 	}
 
 	if err := c.Validate(&updateData); err != nil {
-		return c.JSON(http.StatusBadRequest, echo.Map{
-			"message": "Validation failed",
-			"errors":  err.Error(),
-		})
+		return validationFailed(c, err)
 	}
 
 	id := c.Param("id")
-	updatedExam, err := h.service.Update(id, &updateData)
+	updatedExam, err := h.service.Update(c.Request().Context(), id, &updateData)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.JSON(http.StatusNotFound, echo.Map{
 				"message": "Exam not found",
 			})
 		}
+		if errors.Is(err, service.ErrUnknownSubject) {
+			return fieldInvalid(c, "subject_id", "exists", err)
+		}
+		if errors.Is(err, service.ErrSubjectMismatch) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
+		if isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
 
 		log.Errorf("Failed to update exam: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
@@ -130,12 +157,17 @@ func (h *ExamHandler) UpdateExam(c echo.Context) error {
 // Removes an exam from the database
 func (h *ExamHandler) DeleteExam(c echo.Context) error {
 	id := c.Param("id")
-	if err := h.service.Delete(id); err != nil {
+	if err := h.service.Delete(c.Request().Context(), id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.JSON(http.StatusNotFound, echo.Map{
 				"message": "Exam not found",
 			})
 		}
+		if isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
 
 		log.Errorf("Failed to delete exam: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
@@ -145,3 +177,39 @@ func (h *ExamHandler) DeleteExam(c echo.Context) error {
 
 	return c.JSON(http.StatusNoContent, nil)
 }
+
+// Returns a handler that moves an exam along its lifecycle. Transitions
+// that are not possible from the exam's status are refused with 409.
+func (h *ExamHandler) TransitionExam(transition service.ExamTransition) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		exam, err := h.service.Transition(c.Request().Context(), c.Param("id"), transition)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return c.JSON(http.StatusNotFound, echo.Map{
+					"message": "Exam not found",
+				})
+			}
+			if isExamStatusError(err) {
+				return c.JSON(http.StatusConflict, echo.Map{
+					"message": err.Error(),
+				})
+			}
+
+			log.Errorf("Failed to %s exam: %v", transition, err)
+			return c.JSON(http.StatusInternalServerError, echo.Map{
+				"message": "Failed to change exam status",
+			})
+		}
+
+		return c.JSON(http.StatusOK, echo.Map{
+			"message": "Exam status changed successfully",
+			"exam":    exam,
+		})
+	}
+}
+
+// Reports whether an operation was refused because of the status of an exam
+func isExamStatusError(err error) bool {
+	var statusErr *service.ExamStatusError
+	return errors.As(err, &statusErr)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/service"
+	"gorm.io/gorm"
+)
+
+// Handles HTTP requests for mark sheet exports
+type ExportHandler struct {
+	service *service.ExportService
+}
+
+// Creates a new instance of ExportHandler
+func NewExportHandler(service *service.ExportService) *ExportHandler {
+	return &ExportHandler{service: service}
+}
+
+// Downloads the mark sheet of an exam
+func (h *ExportHandler) ExportExam(c echo.Context) error {
+	format, err := service.ParseExportFormat(c.QueryParam("format"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	sheet, err := h.service.ExamMarkSheet(c.Param("id"))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Exam not found",
+			})
+		}
+		log.Errorf("Failed to prepare exam mark sheet: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to export marks",
+		})
+	}
+
+	return streamMarkSheet(c, sheet, format)
+}
+
+// Downloads the mark sheet of a subject, with a section per exam
+func (h *ExportHandler) ExportSubject(c echo.Context) error {
+	format, err := service.ParseExportFormat(c.QueryParam("format"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	sheet, err := h.service.SubjectMarkSheet(c.Param("id"))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Subject not found",
+			})
+		}
+		log.Errorf("Failed to prepare subject mark sheet: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to export marks",
+		})
+	}
+
+	return streamMarkSheet(c, sheet, format)
+}
+
+// Writes a mark sheet into the response. The status is sent before the
+// rows are read, so a failure halfway can only be logged and ends the
+// download early. CSV rows are sent as they are read, workbooks and PDFs
+// are buffered until the last row and only then sent.
+func streamMarkSheet(c echo.Context, sheet *service.MarkSheet, format service.ExportFormat) error {
+	c.Response().Header().Set(echo.HeaderContentType, format.ContentType())
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"%s\"", sheet.FileName(format)))
+	c.Response().WriteHeader(http.StatusOK)
+
+	if err := sheet.Write(c.Response(), format); err != nil {
+		log.Errorf("Failed to write %s mark sheet: %v", format, err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/smartik/api/internal/service"
+)
+
+// Writes a stored file as the response body and closes it. Range requests
+// are answered with the requested parts only, and conditional requests for
+// a file the client already has with 304 Not Modified.
+func streamFile(c echo.Context, fileStream *service.FileStreamResult) error {
+	defer fileStream.Content.Close()
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, fileStream.ContentType)
+	header.Set(echo.HeaderContentDisposition,
+		fmt.Sprintf("inline; filename=\"%s\"", fileStream.Filename))
+	if fileStream.ETag != "" {
+		header.Set("ETag", quoteETag(fileStream.ETag))
+	}
+
+	// Sets Content-Length and Last-Modified and checks If-None-Match,
+	// If-Modified-Since and Range against them
+	http.ServeContent(c.Response(), c.Request(), fileStream.Filename, fileStream.LastModified, fileStream.Content)
+	return nil
+}
+
+// Storage backends report ETags with or without the quotes HTTP requires
+func quoteETag(etag string) string {
+	if strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// Reads the version number from the path
+func versionParam(c echo.Context) (int, bool) {
+	version, err := strconv.Atoi(c.Param("version"))
+	return version, err == nil && version > 0
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/service"
+	"gorm.io/gorm"
+)
+
+// Handles HTTP requests for extracted answers and automated grading
+type GradingHandler struct {
+	service *service.GradingService
+}
+
+// Creates a new instance of GradingHandler
+func NewGradingHandler(service *service.GradingService) *GradingHandler {
+	return &GradingHandler{service}
+}
+
+// Retrieves the answers extracted from an answer script
+func (h *GradingHandler) GetAnswers(c echo.Context) error {
+	answers, err := h.service.GetAnswers(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Answer script not found",
+			})
+		}
+
+		log.Errorf("Failed to get extracted answers: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to retrieve answers",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message": "Answers retrieved successfully",
+		"answers": answers,
+	})
+}
+
+// Records the answers extracted from an answer script
+func (h *GradingHandler) SaveAnswers(c echo.Context) error {
+	var data models.RecordExtractedAnswers
+	if err := c.Bind(&data); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	if err := c.Validate(&data); err != nil {
+		return validationFailed(c, err)
+	}
+
+	answers, err := h.service.SaveAnswers(c.Request().Context(), c.Param("id"), &data)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Answer script not found",
+			})
+		}
+		if errors.Is(err, service.ErrInvalidAnswer) {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": "Invalid input",
+				"error":   err.Error(),
+			})
+		}
+		if isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
+
+		log.Errorf("Failed to save extracted answers: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to save answers",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message": "Answers saved successfully",
+		"answers": answers,
+	})
+}
+
+// Starts grading every answer script of an exam in the background
+func (h *GradingHandler) GradeExam(c echo.Context) error {
+	var data models.StartGradingJob
+	if err := c.Bind(&data); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	job, err := h.service.StartJob(c.Param("id"), &data)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Exam not found",
+			})
+		}
+		if errors.Is(err, service.ErrGradingJobActive) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
+		if isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
+
+		log.Errorf("Failed to start grading job: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to start grading",
+		})
+	}
+
+	return c.JSON(http.StatusAccepted, echo.Map{
+		"message": "Grading started",
+		"job":     job,
+	})
+}
+
+// Retrieves the grading jobs of an exam
+func (h *GradingHandler) GetExamJobs(c echo.Context) error {
+	jobs, err := h.service.GetJobs(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Exam not found",
+			})
+		}
+
+		log.Errorf("Failed to get grading jobs: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to retrieve grading jobs",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message": "Grading jobs retrieved successfully",
+		"jobs":    jobs,
+	})
+}
+
+// Retrieves the progress of a grading job
+func (h *GradingHandler) GetJob(c echo.Context) error {
+	job, err := h.service.GetJob(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Grading job not found",
+			})
+		}
+
+		log.Errorf("Failed to get grading job: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to retrieve grading job",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message": "Grading job retrieved successfully",
+		"job":     job,
+	})
+}
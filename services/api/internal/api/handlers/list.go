@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/smartik/api/internal/repository"
+)
+
+// Query parameters that control paging rather than filtering
+var pagingParams = map[string]bool{"limit": true, "offset": true, "cursor": true, "sort": true}
+
+// Reads the paging, sorting and filter query parameters of a list request.
+// Every parameter that does not control paging is treated as a filter and is
+// checked against the fields the list allows.
+func listParams(c echo.Context) (repository.ListParams, error) {
+	params := repository.ListParams{
+		Cursor:  c.QueryParam("cursor"),
+		Sort:    c.QueryParam("sort"),
+		Filters: map[string]string{},
+	}
+
+	for _, name := range []string{"limit", "offset"} {
+		value := c.QueryParam(name)
+		if value == "" {
+			continue
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return params, fmt.Errorf("%w: %s must be a whole number", repository.ErrInvalidListParams, name)
+		}
+		if name == "limit" {
+			params.Limit = number
+		} else {
+			params.Offset = number
+		}
+	}
+
+	for name, values := range c.QueryParams() {
+		if pagingParams[name] || len(values) == 0 || values[0] == "" {
+			continue
+		}
+		params.Filters[name] = values[0]
+	}
+	return params, nil
+}
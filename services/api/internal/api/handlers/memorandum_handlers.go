@@ -1,11 +1,12 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/repository"
 	"github.com/smartik/api/internal/service"
 	"gorm.io/gorm"
 )
@@ -46,7 +47,7 @@ func (h *MemorandumHandler) UploadMemorandum(c echo.Context) error {
 
 	examId := examIdSlice[0]
 
-	result, err := h.service.UploadFile(file[0], examId, &service.MemorandumUploadResult{})
+	result, err := h.service.UploadFile(c.Request().Context(), file[0], examId, &service.MemorandumUploadResult{})
 	if err != nil {
 		log.Errorf("Upload service error: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
@@ -74,8 +75,22 @@ func (h *MemorandumHandler) UploadMemorandum(c echo.Context) error {
 
 // Retrieves all memorandums
 func (h *MemorandumHandler) GetAllMemorandums(c echo.Context) error {
-	memorandums, err := h.service.GetAll()
+	params, err := listParams(c)
 	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	memorandums, err := h.service.GetAll(params)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidListParams) {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": "Invalid input",
+				"error":   err.Error(),
+			})
+		}
 		log.Errorf("Failed to get all memorandums: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
 			"message": "Failed to retrieve memorandums",
@@ -84,7 +99,8 @@ func (h *MemorandumHandler) GetAllMemorandums(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, echo.Map{
 		"message":     "Memorandums retrieved successfully",
-		"memorandums": memorandums,
+		"memorandums": memorandums.Items,
+		"pagination":  memorandums.Pagination,
 	})
 }
 
@@ -128,25 +144,141 @@ func (h *MemorandumHandler) ServeMemorandumFile(c echo.Context) error {
 			"message": "Failed to retrieve memorandum file",
 		})
 	}
-	defer fileStream.Content.Close()
 
-	// Set the response headers for file download
-	c.Response().Header().Set(echo.HeaderContentType, fileStream.ContentType)
-	c.Response().Header().Set(echo.HeaderContentLength, fmt.Sprintf("%d", fileStream.Size))
-	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("inline; filename=\"%s\"", fileStream.Filename))
+	return streamFile(c, fileStream)
+}
 
-	return c.Stream(http.StatusOK, fileStream.ContentType, fileStream.Content)
+// Issues a short lived link the memorandum file can be downloaded from directly
+func (h *MemorandumHandler) GetMemorandumDownloadLink(c echo.Context) error {
+	link, err := h.service.GetDownloadLink(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Memorandum not found",
+			})
+		}
+
+		log.Errorf("Failed to issue download link: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to issue download link",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message": "Download link issued successfully",
+		"link":    link,
+	})
+}
+
+// Replaces the file of a memorandum with a new upload sent as "file"
+func (h *MemorandumHandler) ReplaceMemorandumFile(c echo.Context) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "No file provided",
+			"error":   err.Error(),
+		})
+	}
+
+	memorandum, err := h.service.ReplaceFile(c.Request().Context(), c.Param("id"), file)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Memorandum not found",
+			})
+		}
+		if rejected := fileRejection(err); rejected != nil {
+			return c.JSON(fileRejectionStatus(rejected), echo.Map{
+				"message": rejected.Error(),
+				"code":    rejected.Code,
+			})
+		}
+		if errors.Is(err, service.ErrFileChanged) || isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
+
+		log.Errorf("Failed to replace memorandum file: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to replace memorandum file",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":    "Memorandum file replaced successfully",
+		"memorandum": memorandum,
+	})
+}
+
+// Lists every file a memorandum has had, newest first
+func (h *MemorandumHandler) GetMemorandumVersions(c echo.Context) error {
+	versions, err := h.service.GetVersions(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Memorandum not found",
+			})
+		}
+
+		log.Errorf("Failed to get memorandum versions: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to retrieve memorandum versions",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":  "Memorandum versions retrieved successfully",
+		"versions": versions,
+	})
+}
+
+// Serves a version of the file of a memorandum
+func (h *MemorandumHandler) ServeMemorandumVersion(c echo.Context) error {
+	version, ok := versionParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   "the version must be a positive number",
+		})
+	}
+
+	fileStream, err := h.service.GetVersionFileStream(c.Param("id"), version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Memorandum not found",
+			})
+		}
+		if errors.Is(err, service.ErrUnknownFileVersion) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": err.Error(),
+			})
+		}
+
+		log.Errorf("Failed to get file stream: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to retrieve memorandum file",
+		})
+	}
+
+	return streamFile(c, fileStream)
 }
 
 // Deletes a memorandum by ID
 func (h *MemorandumHandler) DeleteMemorandum(c echo.Context) error {
 	id := c.Param("id")
-	if err := h.service.Delete(id); err != nil {
+	if err := h.service.Delete(c.Request().Context(), id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.JSON(http.StatusNotFound, echo.Map{
 				"message": "Memorandum not found",
 			})
 		}
+		if isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
 
 		log.Errorf("Failed to delete memorandum: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/service"
+	"gorm.io/gorm"
+)
+
+// Handles HTTP requests for the structure of memorandums
+type MemorandumQuestionHandler struct {
+	service *service.MemorandumQuestionService
+}
+
+// Creates a new instance of MemorandumQuestionHandler
+func NewMemorandumQuestionHandler(service *service.MemorandumQuestionService) *MemorandumQuestionHandler {
+	return &MemorandumQuestionHandler{service}
+}
+
+// Retrieves the questions of a memorandum as a tree
+func (h *MemorandumQuestionHandler) GetQuestions(c echo.Context) error {
+	questions, err := h.service.GetQuestions(c.Param("id"))
+	if err != nil {
+		return h.handleError(c, err, "Failed to retrieve memorandum questions")
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":   "Memorandum questions retrieved successfully",
+		"questions": questions,
+	})
+}
+
+// Replaces every question of a memorandum
+func (h *MemorandumQuestionHandler) ReplaceQuestions(c echo.Context) error {
+	var body struct {
+		Questions []models.MemorandumQuestionInput `json:"questions" validate:"required,dive"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	if err := c.Validate(&body); err != nil {
+		return validationFailed(c, err)
+	}
+
+	questions, report, err := h.service.ReplaceQuestions(c.Request().Context(), c.Param("id"), body.Questions)
+	if err != nil {
+		return h.handleError(c, err, "Failed to save memorandum questions")
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":    "Memorandum questions saved successfully",
+		"questions":  questions,
+		"allocation": report,
+	})
+}
+
+// Adds a question or sub-question to a memorandum
+func (h *MemorandumQuestionHandler) CreateQuestion(c echo.Context) error {
+	var data models.CreateMemorandumQuestion
+	if err := c.Bind(&data); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	if err := c.Validate(&data); err != nil {
+		return validationFailed(c, err)
+	}
+
+	question, report, err := h.service.CreateQuestion(c.Request().Context(), c.Param("id"), &data)
+	if err != nil {
+		return h.handleError(c, err, "Failed to create memorandum question")
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"message":    "Memorandum question created successfully",
+		"question":   question,
+		"allocation": report,
+	})
+}
+
+// Updates a question of a memorandum
+func (h *MemorandumQuestionHandler) UpdateQuestion(c echo.Context) error {
+	var data models.UpdateMemorandumQuestion
+	if err := c.Bind(&data); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	if err := c.Validate(&data); err != nil {
+		return validationFailed(c, err)
+	}
+
+	question, report, err := h.service.UpdateQuestion(c.Request().Context(), c.Param("id"), c.Param("questionId"), &data)
+	if err != nil {
+		return h.handleError(c, err, "Failed to update memorandum question")
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":    "Memorandum question updated successfully",
+		"question":   question,
+		"allocation": report,
+	})
+}
+
+// Removes a question, and its sub-questions, from a memorandum
+func (h *MemorandumQuestionHandler) DeleteQuestion(c echo.Context) error {
+	if err := h.service.DeleteQuestion(c.Request().Context(), c.Param("id"), c.Param("questionId")); err != nil {
+		return h.handleError(c, err, "Failed to delete memorandum question")
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+// Reports whether the mark allocation of a memorandum is complete
+func (h *MemorandumQuestionHandler) ValidateAllocation(c echo.Context) error {
+	report, err := h.service.ValidateAllocation(c.Param("id"))
+	if err != nil {
+		return h.handleError(c, err, "Failed to validate memorandum")
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":    "Memorandum validated successfully",
+		"allocation": report,
+	})
+}
+
+// Maps service errors onto HTTP responses
+func (h *MemorandumQuestionHandler) handleError(c echo.Context, err error, message string) error {
+	var allocationErr *service.AllocationError
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, echo.Map{
+			"message": "Memorandum or question not found",
+		})
+	case errors.Is(err, service.ErrParentQuestionNotFound):
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	case errors.Is(err, service.ErrQuestionTooDeep):
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	case isExamStatusError(err):
+		return c.JSON(http.StatusConflict, echo.Map{
+			"message": err.Error(),
+		})
+	case errors.Is(err, service.ErrMarksAlreadyAwarded):
+		return c.JSON(http.StatusConflict, echo.Map{
+			"message": "Marks already awarded exceed the question's marks",
+			"error":   err.Error(),
+		})
+	case errors.As(err, &allocationErr):
+		return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+			"message":    "Mark allocation is invalid",
+			"allocation": allocationErr.Report,
+		})
+	}
+
+	log.Errorf("%s: %v", message, err)
+	return c.JSON(http.StatusInternalServerError, echo.Map{
+		"message": message,
+	})
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/api/middleware"
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/service"
+	"gorm.io/gorm"
+)
+
+// Handles HTTP requests for marks awarded per question
+type QuestionMarkHandler struct {
+	service *service.QuestionMarkService
+}
+
+// Creates a new instance of QuestionMarkHandler
+func NewQuestionMarkHandler(service *service.QuestionMarkService) *QuestionMarkHandler {
+	return &QuestionMarkHandler{service}
+}
+
+// Retrieves the marks recorded for an answer script
+func (h *QuestionMarkHandler) GetMarks(c echo.Context) error {
+	marks, err := h.service.GetMarks(c.Param("id"))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Answer script not found",
+			})
+		}
+
+		log.Errorf("Failed to get question marks: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to retrieve marks",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message": "Marks retrieved successfully",
+		"marks":   marks,
+	})
+}
+
+// Records the marks of one or more questions of an answer script
+func (h *QuestionMarkHandler) RecordMarks(c echo.Context) error {
+	var data models.RecordQuestionMarks
+	if err := c.Bind(&data); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	if err := c.Validate(&data); err != nil {
+		return validationFailed(c, err)
+	}
+
+	data.MarkedBy = middleware.CurrentUser(c).Id
+
+	answerScript, marks, err := h.service.RecordMarks(c.Request().Context(), c.Param("id"), &data)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Answer script not found",
+			})
+		}
+		if errors.Is(err, service.ErrInvalidMark) {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": "Invalid input",
+				"error":   err.Error(),
+			})
+		}
+		if isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
+
+		log.Errorf("Failed to record question marks: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to record marks",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":       "Marks recorded successfully",
+		"answer_script": answerScript,
+		"marks":         marks,
+	})
+}
+
+// Removes the mark of a single question of an answer script
+func (h *QuestionMarkHandler) DeleteMark(c echo.Context) error {
+	answerScript, marks, err := h.service.DeleteMark(c.Request().Context(), c.Param("id"), c.Param("questionId"))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Mark not found",
+			})
+		}
+		if isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
+
+		log.Errorf("Failed to delete question mark: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to delete mark",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":       "Mark deleted successfully",
+		"answer_script": answerScript,
+		"marks":         marks,
+	})
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/api/middleware"
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository"
+	"github.com/smartik/api/internal/service"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultCandidateLimit = 5
+	maxCandidateLimit     = 20
+)
+
+// Handles HTTP requests for reviewing low confidence student matches
+type ReviewHandler struct {
+	service *service.MatchingService
+}
+
+// Creates a new instance of ReviewHandler
+func NewReviewHandler(service *service.MatchingService) *ReviewHandler {
+	return &ReviewHandler{service: service}
+}
+
+// Retrieves the answer scripts waiting for manual review
+func (h *ReviewHandler) GetReviewQueue(c echo.Context) error {
+	params, err := listParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	answerScripts, err := h.service.GetReviewQueue(params)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidListParams) {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": "Invalid input",
+				"error":   err.Error(),
+			})
+		}
+		log.Errorf("Failed to get review queue: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to retrieve review queue",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":        "Review queue retrieved successfully",
+		"threshold":      h.service.Threshold(),
+		"answer_scripts": answerScripts.Items,
+		"pagination":     answerScripts.Pagination,
+	})
+}
+
+// Proposes the students an answer script most likely belongs to
+func (h *ReviewHandler) GetCandidates(c echo.Context) error {
+	id := c.Param("id")
+
+	limit := defaultCandidateLimit
+	if value := c.QueryParam("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxCandidateLimit {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": "Invalid input",
+				"error":   "limit must be a number between 1 and 20",
+			})
+		}
+		limit = parsed
+	}
+
+	answerScript, candidates, err := h.service.GetCandidates(id, limit)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Answer script not found",
+			})
+		}
+
+		log.Errorf("Failed to get match candidates: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to retrieve match candidates",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":       "Match candidates retrieved successfully",
+		"answer_script": answerScript,
+		"candidates":    candidates,
+	})
+}
+
+// Confirms the student an answer script is linked to
+func (h *ReviewHandler) ConfirmMatch(c echo.Context) error {
+	return h.review(c, h.service.ConfirmMatch, "Match confirmed successfully")
+}
+
+// Links an answer script to a different student
+func (h *ReviewHandler) ReassignMatch(c echo.Context) error {
+	return h.review(c, h.service.ReassignMatch, "Match reassigned successfully")
+}
+
+// Rejects the match of an answer script
+func (h *ReviewHandler) RejectMatch(c echo.Context) error {
+	return h.review(c, h.service.RejectMatch, "Match rejected successfully")
+}
+
+// Binds and validates a review decision, then applies it using decide
+func (h *ReviewHandler) review(
+	c echo.Context,
+	decide func(ctx context.Context, id string, review *models.MatchReview) (*models.AnswerScript, error),
+	message string,
+) error {
+	id := c.Param("id")
+
+	var review models.MatchReview
+	if err := c.Bind(&review); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	if err := c.Validate(&review); err != nil {
+		return validationFailed(c, err)
+	}
+
+	review.ReviewedBy = middleware.CurrentUser(c).Id
+
+	answerScript, err := decide(c.Request().Context(), id, &review)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Answer script not found",
+			})
+		case errors.Is(err, service.ErrStudentNotFound):
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Student not found",
+			})
+		case errors.Is(err, service.ErrNoStudentToConfirm):
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": "Invalid input",
+				"error":   "student_id is required",
+			})
+		}
+
+		log.Errorf("Failed to review match: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to review match",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":       message,
+		"answer_script": answerScript,
+	})
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/service"
+	"gorm.io/gorm"
+)
+
+// Handles HTTP requests attaching answer scripts to students, subjects and exams
+type ScriptLinkHandler struct {
+	service *service.ScriptLinkService
+}
+
+// Creates a new instance of ScriptLinkHandler
+func NewScriptLinkHandler(service *service.ScriptLinkService) *ScriptLinkHandler {
+	return &ScriptLinkHandler{service}
+}
+
+// Names of the records scripts are attached to, as used in responses
+var scriptOwnerNames = map[service.ScriptOwner]string{
+	service.ScriptOwnerStudent: "Student",
+	service.ScriptOwnerSubject: "Subject",
+	service.ScriptOwnerExam:    "Exam",
+}
+
+// Attaches answer scripts to the record in the path
+func (h *ScriptLinkHandler) AttachScripts(owner service.ScriptOwner) echo.HandlerFunc {
+	return h.linkScripts(owner, h.service.Attach, "attach", "Answer scripts attached successfully")
+}
+
+// Detaches answer scripts from the record in the path
+func (h *ScriptLinkHandler) DetachScripts(owner service.ScriptOwner) echo.HandlerFunc {
+	return h.linkScripts(owner, h.service.Detach, "detach", "Answer scripts detached successfully")
+}
+
+type scriptLinkFunc func(ctx context.Context, owner service.ScriptOwner, ownerId string, ids []string) (*[]models.AnswerScript, error)
+
+func (h *ScriptLinkHandler) linkScripts(owner service.ScriptOwner, link scriptLinkFunc, action, success string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var data models.AnswerScriptLinks
+		if err := c.Bind(&data); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": "Invalid input",
+				"error":   err.Error(),
+			})
+		}
+
+		if err := c.Validate(&data); err != nil {
+			return validationFailed(c, err)
+		}
+
+		answerScripts, err := link(c.Request().Context(), owner, c.Param("id"), data.AnswerScriptIds)
+		if err != nil {
+			var notFound *service.ScriptsNotFoundError
+			if errors.As(err, &notFound) {
+				return c.JSON(http.StatusBadRequest, echo.Map{
+					"message":   "Some answer scripts were not found",
+					"not_found": notFound.Ids,
+				})
+			}
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return c.JSON(http.StatusNotFound, echo.Map{
+					"message": scriptOwnerNames[owner] + " not found",
+				})
+			}
+			if errors.Is(err, service.ErrSubjectMismatch) || isExamStatusError(err) {
+				return c.JSON(http.StatusConflict, echo.Map{
+					"message": err.Error(),
+				})
+			}
+
+			log.Errorf("Failed to %s answer scripts: %v", action, err)
+			return c.JSON(http.StatusInternalServerError, echo.Map{
+				"message": "Failed to " + action + " answer scripts",
+			})
+		}
+
+		return c.JSON(http.StatusOK, echo.Map{
+			"message":        success,
+			"answer_scripts": answerScripts,
+		})
+	}
+}
@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
 	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository"
 	"github.com/smartik/api/internal/service"
 	"gorm.io/gorm"
 )
@@ -31,13 +34,10 @@ func (h *StudentHandler) CreateStudent(c echo.Context) error {
 	}
 
 	if err := c.Validate(&newStudent); err != nil {
-		return c.JSON(http.StatusBadRequest, echo.Map{
-			"message": "Validation failed",
-			"errors":  err.Error(),
-		})
+		return validationFailed(c, err)
 	}
 
-	if err := h.service.Create(&newStudent); err != nil {
+	if err := h.service.Create(c.Request().Context(), &newStudent); err != nil {
 		log.Errorf("Failed to create student: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
 			"message": "Failed to create student",
@@ -50,10 +50,78 @@ func (h *StudentHandler) CreateStudent(c echo.Context) error {
 	})
 }
 
+// Creates and updates students from an uploaded CSV or XLSX class list
+func (h *StudentHandler) ImportStudents(c echo.Context) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "No class list provided",
+			"error":   err.Error(),
+		})
+	}
+
+	dryRun := false
+	if value := c.FormValue("dry_run"); value != "" {
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": "Invalid input",
+				"error":   "dry_run must be true or false",
+			})
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Errorf("Failed to open class list: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to import students",
+		})
+	}
+	defer file.Close()
+
+	result, err := h.service.Import(c.Request().Context(), file, fileHeader.Filename, dryRun, c.Validate)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidImportFile) {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": "Invalid class list",
+				"error":   err.Error(),
+			})
+		}
+		log.Errorf("Failed to import students: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to import students",
+		})
+	}
+
+	message := "Students imported successfully"
+	if dryRun {
+		message = "Class list checked, no students were saved"
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"message": message,
+		"import":  result,
+	})
+}
+
 // Retrieves all students from the database
 func (h *StudentHandler) GetAllStudents(c echo.Context) error {
-	students, err := h.service.GetAll()
+	params, err := listParams(c)
 	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	students, err := h.service.GetAll(params)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidListParams) {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": "Invalid input",
+				"error":   err.Error(),
+			})
+		}
 		log.Errorf("Failed to retrieve students: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
 			"message": "Failed to retrieve students",
@@ -61,8 +129,9 @@ func (h *StudentHandler) GetAllStudents(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, echo.Map{
-		"message":  "Students retrieved successfully",
-		"students": students,
+		"message":    "Students retrieved successfully",
+		"students":   students.Items,
+		"pagination": students.Pagination,
 	})
 }
 
@@ -103,13 +172,10 @@ func (h *StudentHandler) UpdateStudent(c echo.Context) error {
 	}
 
 	if err := c.Validate(&updateData); err != nil {
-		return c.JSON(http.StatusBadRequest, echo.Map{
-			"message": "Validation failed",
-			"errors":  err.Error(),
-		})
+		return validationFailed(c, err)
 	}
 
-	updatedStudent, err := h.service.Update(id, &updateData)
+	updatedStudent, err := h.service.Update(c.Request().Context(), id, &updateData)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.JSON(http.StatusNotFound, echo.Map{
@@ -133,12 +199,17 @@ func (h *StudentHandler) UpdateStudent(c echo.Context) error {
 func (h *StudentHandler) DeleteStudent(c echo.Context) error {
 	id := c.Param("id")
 
-	if err := h.service.Delete(id); err != nil {
+	if err := h.service.Delete(c.Request().Context(), id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.JSON(http.StatusNotFound, echo.Map{
 				"message": "Student not found",
 			})
 		}
+		if isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
 
 		log.Errorf("Failed to delete student: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
 	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository"
 	"github.com/smartik/api/internal/service"
 	"gorm.io/gorm"
 )
@@ -32,13 +34,10 @@ func (h *SubjectHandler) CreateSubject(c echo.Context) error {
 	}
 
 	if err := c.Validate(&subject); err != nil {
-		return c.JSON(http.StatusBadRequest, echo.Map{
-			"message": "Validation failed",
-			"errors":  err.Error(),
-		})
+		return validationFailed(c, err)
 	}
 
-	if err := h.service.Create(&subject); err != nil {
+	if err := h.service.Create(c.Request().Context(), &subject); err != nil {
 		log.Errorf("Failed to create subject: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
 			"message": "Failed to create subject",
@@ -53,8 +52,22 @@ func (h *SubjectHandler) CreateSubject(c echo.Context) error {
 
 // Retrieves all subjects from the database
 func (h *SubjectHandler) GetAllSubjects(c echo.Context) error {
-	subjects, err := h.service.GetAll()
+	params, err := listParams(c)
 	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	subjects, err := h.service.GetAll(params)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidListParams) {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": "Invalid input",
+				"error":   err.Error(),
+			})
+		}
 		log.Errorf("Failed to retrieve subjects: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
 			"message": "Failed to retrieve subjects",
@@ -62,8 +75,9 @@ func (h *SubjectHandler) GetAllSubjects(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, echo.Map{
-		"message":  "Subjects retrieved successfully",
-		"subjects": subjects,
+		"message":    "Subjects retrieved successfully",
+		"subjects":   subjects.Items,
+		"pagination": subjects.Pagination,
 	})
 }
 
@@ -103,13 +117,10 @@ func (h *SubjectHandler) UpdateSubject(c echo.Context) error {
 	}
 
 	if err := c.Validate(&updateData); err != nil {
-		return c.JSON(http.StatusBadRequest, echo.Map{
-			"message": "Validation failed",
-			"errors":  err.Error(),
-		})
+		return validationFailed(c, err)
 	}
 
-	updatedSubject, err := h.service.Update(id, &updateData)
+	updatedSubject, err := h.service.Update(c.Request().Context(), id, &updateData)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.JSON(http.StatusNotFound, echo.Map{
@@ -132,12 +143,17 @@ func (h *SubjectHandler) UpdateSubject(c echo.Context) error {
 // Removes a subject from the database
 func (h *SubjectHandler) DeleteSubject(c echo.Context) error {
 	id := c.Param("id")
-	if err := h.service.Delete(id); err != nil {
+	if err := h.service.Delete(c.Request().Context(), id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.JSON(http.StatusNotFound, echo.Map{
 				"message": "Subject not found",
 			})
 		}
+		if errors.Is(err, service.ErrSubjectHasExams) || isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
 
 		log.Errorf("Failed to delete subject: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/service"
+	"gorm.io/gorm"
+)
+
+// Handles HTTP requests for resumable uploads of answer scripts
+type UploadSessionHandler struct {
+	service *service.UploadSessionService
+}
+
+// Creates a new instance of UploadSessionHandler
+func NewUploadSessionHandler(service *service.UploadSessionService) *UploadSessionHandler {
+	return &UploadSessionHandler{service}
+}
+
+// Starts a resumable upload
+func (h *UploadSessionHandler) CreateUpload(c echo.Context) error {
+	var data models.CreateUploadSession
+	if err := c.Bind(&data); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	if err := c.Validate(&data); err != nil {
+		return validationFailed(c, err)
+	}
+
+	upload, err := h.service.Create(c.Request().Context(), &data)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownExam) {
+			return fieldInvalid(c, "exam_id", "exists", err)
+		}
+		if rejected := fileRejection(err); rejected != nil {
+			return c.JSON(fileRejectionStatus(rejected), echo.Map{
+				"message": rejected.Error(),
+				"code":    rejected.Code,
+			})
+		}
+		if isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
+
+		log.Errorf("Failed to start upload: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to start upload",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"message": "Upload started successfully",
+		"upload":  upload,
+	})
+}
+
+// Retrieves an upload with the parts received so far
+func (h *UploadSessionHandler) GetUpload(c echo.Context) error {
+	upload, err := h.service.GetById(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Upload not found",
+			})
+		}
+
+		log.Errorf("Failed to get upload: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to retrieve upload",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message": "Upload retrieved successfully",
+		"upload":  upload,
+	})
+}
+
+// Stores a part of an upload sent as the raw request body
+func (h *UploadSessionHandler) UploadPart(c echo.Context) error {
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   "the part number must be a number",
+		})
+	}
+
+	// Content-MD5 carries the base64 encoded digest of the body
+	var checksum string
+	if header := c.Request().Header.Get("Content-MD5"); header != "" {
+		digest, err := base64.StdEncoding.DecodeString(header)
+		if err != nil || len(digest) != 16 {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": "Invalid input",
+				"error":   "Content-MD5 must be a base64 encoded MD5 digest",
+			})
+		}
+		checksum = hex.EncodeToString(digest)
+	}
+
+	request := c.Request()
+	part, err := h.service.UploadPart(request.Context(), c.Param("id"), number, request.Body, request.ContentLength, checksum)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Upload not found",
+			})
+		}
+		if errors.Is(err, service.ErrInvalidUploadPart) {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": err.Error(),
+			})
+		}
+		if errors.Is(err, service.ErrUploadNotActive) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
+		if errors.Is(err, service.ErrUploadExpired) {
+			return c.JSON(http.StatusGone, echo.Map{
+				"message": err.Error(),
+			})
+		}
+
+		log.Errorf("Failed to store upload part: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to store upload part",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message": "Part uploaded successfully",
+		"part":    part,
+	})
+}
+
+// Joins the parts of an upload into an answer script
+func (h *UploadSessionHandler) CompleteUpload(c echo.Context) error {
+	answerScript, created, err := h.service.Complete(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Upload not found",
+			})
+		}
+		if errors.Is(err, service.ErrUnknownExam) {
+			return fieldInvalid(c, "exam_id", "exists", err)
+		}
+		if errors.Is(err, service.ErrUploadIncomplete) || errors.Is(err, service.ErrUploadNotActive) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
+		if errors.Is(err, service.ErrUploadExpired) {
+			return c.JSON(http.StatusGone, echo.Map{
+				"message": err.Error(),
+			})
+		}
+		if rejected := fileRejection(err); rejected != nil {
+			return c.JSON(fileRejectionStatus(rejected), echo.Map{
+				"message": rejected.Error(),
+				"code":    rejected.Code,
+			})
+		}
+		if isExamStatusError(err) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
+
+		log.Errorf("Failed to complete upload: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to complete upload",
+		})
+	}
+
+	status := http.StatusCreated
+	if !created {
+		status = http.StatusOK
+	}
+	return c.JSON(status, echo.Map{
+		"message":       "Answer script uploaded successfully",
+		"answer_script": answerScript,
+	})
+}
+
+// Cancels an upload and removes the parts received so far
+func (h *UploadSessionHandler) AbortUpload(c echo.Context) error {
+	if err := h.service.Abort(c.Request().Context(), c.Param("id")); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "Upload not found",
+			})
+		}
+		if errors.Is(err, service.ErrUploadNotActive) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
+
+		log.Errorf("Failed to abort upload: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to abort upload",
+		})
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
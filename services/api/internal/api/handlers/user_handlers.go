@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository"
+	"github.com/smartik/api/internal/service"
+	"gorm.io/gorm"
+)
+
+// Handles HTTP requests for managing user accounts
+type UserHandler struct {
+	service *service.UserService
+}
+
+// Creates a new instance of UserHandler
+func NewUserHandler(service *service.UserService) *UserHandler {
+	return &UserHandler{service}
+}
+
+// Creates a new user account
+func (h *UserHandler) CreateUser(c echo.Context) error {
+	var data models.CreateUser
+	if err := c.Bind(&data); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	if err := c.Validate(&data); err != nil {
+		return validationFailed(c, err)
+	}
+
+	user, err := h.service.Create(c.Request().Context(), &data)
+	if err != nil {
+		if errors.Is(err, service.ErrEmailTaken) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+			})
+		}
+
+		log.Errorf("Failed to create user: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to create user",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"message": "User created successfully",
+		"user":    user,
+	})
+}
+
+// Retrieves all user accounts
+func (h *UserHandler) GetAllUsers(c echo.Context) error {
+	params, err := listParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	users, err := h.service.GetAll(params)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidListParams) {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"message": "Invalid input",
+				"error":   err.Error(),
+			})
+		}
+		log.Errorf("Failed to get users: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to retrieve users",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":    "Users retrieved successfully",
+		"users":      users.Items,
+		"pagination": users.Pagination,
+	})
+}
+
+// Retrieves a user account by its ID
+func (h *UserHandler) GetUserById(c echo.Context) error {
+	user, err := h.service.GetById(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{
+				"message": "User not found",
+			})
+		}
+
+		log.Errorf("Failed to get user: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to retrieve user",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message": "User retrieved successfully",
+		"user":    user,
+	})
+}
+
+// Updates a user account
+func (h *UserHandler) UpdateUser(c echo.Context) error {
+	var data models.UpdateUser
+	if err := c.Bind(&data); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	if err := c.Validate(&data); err != nil {
+		return validationFailed(c, err)
+	}
+
+	user, err := h.service.Update(c.Request().Context(), c.Param("id"), &data)
+	if err != nil {
+		return h.handleError(c, err, "Failed to update user")
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message": "User updated successfully",
+		"user":    user,
+	})
+}
+
+// Deletes a user account
+func (h *UserHandler) DeleteUser(c echo.Context) error {
+	if err := h.service.Delete(c.Request().Context(), c.Param("id")); err != nil {
+		return h.handleError(c, err, "Failed to delete user")
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+// Maps service errors onto HTTP responses
+func (h *UserHandler) handleError(c echo.Context, err error, message string) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, echo.Map{
+			"message": "User not found",
+		})
+	case errors.Is(err, service.ErrEmailTaken):
+		return c.JSON(http.StatusConflict, echo.Map{
+			"message": err.Error(),
+		})
+	case errors.Is(err, service.ErrSelfModification):
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "Invalid input",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Errorf("%s: %v", message, err)
+	return c.JSON(http.StatusInternalServerError, echo.Map{
+		"message": message,
+	})
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+// A field of a request that failed validation
+type fieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Responds with 422 and every field of the request that failed validation
+func validationFailed(c echo.Context, err error) error {
+	return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+		"message": "Validation failed",
+		"errors":  fieldErrors(err),
+	})
+}
+
+// Responds with 422 for a single field the service refused, e.g. an Id
+// of a record that does not exist
+func fieldInvalid(c echo.Context, field, rule string, err error) error {
+	return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+		"message": "Validation failed",
+		"errors":  []fieldError{{Field: field, Rule: rule, Message: err.Error()}},
+	})
+}
+
+func fieldErrors(err error) []fieldError {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return []fieldError{{Message: err.Error()}}
+	}
+
+	fields := make([]fieldError, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		// The namespace starts with the name of the validated struct
+		_, field, _ := strings.Cut(fe.Namespace(), ".")
+		fields = append(fields, fieldError{
+			Field:   field,
+			Rule:    fe.Tag(),
+			Message: field + " " + describeFieldError(fe),
+		})
+	}
+	return fields
+}
+
+// Describes what a failed validation rule expects
+func describeFieldError(fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min", "max":
+		bound := "at least"
+		if fe.Tag() == "max" {
+			bound = "at most"
+		}
+		switch fe.Kind() {
+		case reflect.String:
+			return fmt.Sprintf("must be %s %s characters long", bound, param)
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return fmt.Sprintf("must have %s %s items", bound, param)
+		default:
+			return fmt.Sprintf("must be %s %s", bound, param)
+		}
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "numeric":
+		return "must be a number"
+	}
+	if param != "" {
+		return fmt.Sprintf("failed the %s=%s rule", fe.Tag(), param)
+	}
+	return fmt.Sprintf("failed the %s rule", fe.Tag())
+}
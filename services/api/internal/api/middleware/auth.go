@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/auth"
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/service"
+)
+
+// Key the signed in user is stored under in the echo context
+const userContextKey = "user"
+
+// Requires a valid session token in the Authorization header and stores
+// the signed in user in the echo context and the request's context
+func Authenticate(authService *service.AuthService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token := BearerToken(c)
+			if token == "" {
+				return c.JSON(http.StatusUnauthorized, echo.Map{
+					"message": "Authentication required",
+				})
+			}
+
+			user, err := authService.Authenticate(token)
+			if err != nil {
+				if errors.Is(err, service.ErrInvalidSession) {
+					return c.JSON(http.StatusUnauthorized, echo.Map{
+						"message": "Invalid or expired session",
+					})
+				}
+
+				log.Errorf("Failed to authenticate request: %v", err)
+				return c.JSON(http.StatusInternalServerError, echo.Map{
+					"message": "Failed to authenticate",
+				})
+			}
+
+			c.Set(userContextKey, user)
+			c.SetRequest(c.Request().WithContext(auth.WithUser(c.Request().Context(), user)))
+			return next(c)
+		}
+	}
+}
+
+// Only lets users with one of the given roles through. Must run after Authenticate.
+func RequireRole(roles ...models.Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user := CurrentUser(c)
+			if user == nil {
+				return c.JSON(http.StatusUnauthorized, echo.Map{
+					"message": "Authentication required",
+				})
+			}
+			if !slices.Contains(roles, user.Role) {
+				return c.JSON(http.StatusForbidden, echo.Map{
+					"message": "You do not have permission to perform this action",
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+// Returns the signed in user, or nil on routes without authentication
+func CurrentUser(c echo.Context) *models.User {
+	user, _ := c.Get(userContextKey).(*models.User)
+	return user
+}
+
+// Returns the token of an "Authorization: Bearer <token>" header
+func BearerToken(c echo.Context) string {
+	header := c.Request().Header.Get(echo.HeaderAuthorization)
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
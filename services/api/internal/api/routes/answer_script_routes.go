@@ -5,13 +5,21 @@ import (
 	"github.com/smartik/api/internal/api/handlers"
 )
 
-func RegisterAnswerScriptRoutes(e *echo.Group, handlers *handlers.AnswerScriptHandler) {
-	answerScripts := e.Group("/scripts")
+func RegisterAnswerScriptRoutes(e *echo.Group, handlers *handlers.AnswerScriptHandler, authenticate echo.MiddlewareFunc) {
+	answerScripts := e.Group("/scripts", authenticate)
 
-	answerScripts.POST("/upload", handlers.UploadScripts).Name = "upload_answer_scripts"
+	answerScripts.POST("/upload", handlers.UploadScripts, canManage).Name = "upload_answer_scripts"
+	answerScripts.POST("/upload/batch", handlers.UploadBatch, canManage).Name = "upload_answer_script_batch"
 	answerScripts.GET("", handlers.GetAllScripts).Name = "get_all_answer_scripts"
 	answerScripts.GET("/:id", handlers.GetScriptById).Name = "get_answer_script_by_id"
 	answerScripts.GET("/serve/:id", handlers.ServeAnswerScript).Name = "serve_answer_script_file"
-	answerScripts.PATCH("/update/:id", handlers.UpdateScript).Name = "update_answer_script"
-	answerScripts.DELETE("/delete/:id", handlers.DeleteScript).Name = "delete_answer_script"
+	answerScripts.GET("/:id/download-link", handlers.GetScriptDownloadLink).Name = "get_answer_script_download_link"
+	answerScripts.PUT("/:id/file", handlers.ReplaceScriptFile, canManage).Name = "replace_answer_script_file"
+	answerScripts.GET("/:id/versions", handlers.GetScriptVersions).Name = "get_answer_script_versions"
+	answerScripts.GET("/:id/versions/:version/serve", handlers.ServeScriptVersion).Name = "serve_answer_script_version"
+	answerScripts.PATCH("/update/:id", handlers.UpdateScript, canManage).Name = "update_answer_script"
+	answerScripts.PATCH("/:id/status", handlers.UpdateScriptStatus, adminOnly).Name = "update_answer_script_status"
+	answerScripts.PATCH("/:id/matching", handlers.UpdateScriptMatching, adminOnly).Name = "update_answer_script_matching"
+	answerScripts.POST("/reprocess/:id", handlers.ReprocessScript, canManage).Name = "reprocess_answer_script"
+	answerScripts.DELETE("/delete/:id", handlers.DeleteScript, adminOnly).Name = "delete_answer_script"
 }
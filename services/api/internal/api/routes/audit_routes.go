@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/smartik/api/internal/api/handlers"
+)
+
+func RegisterAuditRoutes(e *echo.Group, handler *handlers.AuditHandler, authenticate echo.MiddlewareFunc) {
+	audit := e.Group("/audit", authenticate, canAudit)
+
+	audit.GET("", handler.GetEntries).Name = "get_audit_entries"
+}
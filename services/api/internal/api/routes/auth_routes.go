@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/smartik/api/internal/api/handlers"
+)
+
+// Registers the sign in routes. Signing in is public, the other routes
+// need the authenticate middleware.
+func RegisterAuthRoutes(e *echo.Group, handler *handlers.AuthHandler, authenticate echo.MiddlewareFunc) {
+	auth := e.Group("/auth")
+
+	auth.POST("/login", handler.Login).Name = "login"
+	auth.POST("/logout", handler.Logout, authenticate).Name = "logout"
+	auth.GET("/me", handler.Me, authenticate).Name = "get_current_user"
+}
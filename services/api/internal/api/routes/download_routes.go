@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/smartik/api/internal/api/handlers"
+)
+
+// Registers the route download links lead to. It is public, the signed
+// token in the path stands in for the session.
+func RegisterDownloadRoutes(e *echo.Group, handler *handlers.DownloadHandler) {
+	e.GET("/downloads/:token", handler.Download).Name = "download_file"
+}
@@ -3,14 +3,23 @@ package routes
 import (
 	"github.com/labstack/echo/v4"
 	"github.com/smartik/api/internal/api/handlers"
+	"github.com/smartik/api/internal/service"
 )
 
-func RegisterExamRoutes(e *echo.Group, handler *handlers.ExamHandler) {
-	exams := e.Group("/exams")
+func RegisterExamRoutes(e *echo.Group, handler *handlers.ExamHandler, authenticate echo.MiddlewareFunc) {
+	exams := e.Group("/exams", authenticate)
 
 	exams.GET("", handler.GetAllExams).Name = "get_all_exams"
-	exams.POST("/create", handler.CreateExam).Name = "create_exam"
+	exams.POST("/create", handler.CreateExam, canManage).Name = "create_exam"
 	exams.GET("/:id", handler.GetExamById).Name = "get_exam_by_id"
-	exams.PATCH("/update/:id", handler.UpdateExam).Name = "update_exam"
-	exams.DELETE("/delete/:id", handler.DeleteExam).Name = "delete_exam"
+	exams.PATCH("/update/:id", handler.UpdateExam, canManage).Name = "update_exam"
+	exams.DELETE("/delete/:id", handler.DeleteExam, adminOnly).Name = "delete_exam"
+
+	// Lifecycle: draft -> open -> marking <-> moderation -> finalized -> published
+	exams.POST("/:id/open", handler.TransitionExam(service.ExamTransitionOpen), canManage).Name = "open_exam"
+	exams.POST("/:id/start-marking", handler.TransitionExam(service.ExamTransitionStartMarking), canManage).Name = "start_exam_marking"
+	exams.POST("/:id/submit-moderation", handler.TransitionExam(service.ExamTransitionSubmitModeration), canManage).Name = "submit_exam_moderation"
+	exams.POST("/:id/return-to-marking", handler.TransitionExam(service.ExamTransitionReturnToMarking), canManage).Name = "return_exam_to_marking"
+	exams.POST("/:id/finalize", handler.TransitionExam(service.ExamTransitionFinalize), canManage).Name = "finalize_exam"
+	exams.POST("/:id/publish", handler.TransitionExam(service.ExamTransitionPublish), adminOnly).Name = "publish_exam"
 }
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/smartik/api/internal/api/handlers"
+)
+
+func RegisterExportRoutes(e *echo.Group, handler *handlers.ExportHandler, authenticate echo.MiddlewareFunc) {
+	e.GET("/exams/:id/export", handler.ExportExam, authenticate).Name = "export_exam_marks"
+	e.GET("/subjects/:id/export", handler.ExportSubject, authenticate).Name = "export_subject_marks"
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/smartik/api/internal/api/handlers"
+)
+
+func RegisterGradingRoutes(e *echo.Group, handler *handlers.GradingHandler, authenticate echo.MiddlewareFunc) {
+	e.GET("/scripts/:id/answers", handler.GetAnswers, authenticate).Name = "get_extracted_answers"
+	e.PUT("/scripts/:id/answers", handler.SaveAnswers, authenticate, canMark).Name = "save_extracted_answers"
+
+	e.POST("/exams/:id/grade", handler.GradeExam, authenticate, canManage).Name = "grade_exam"
+	e.GET("/exams/:id/grading-jobs", handler.GetExamJobs, authenticate).Name = "get_exam_grading_jobs"
+	e.GET("/grading-jobs/:id", handler.GetJob, authenticate).Name = "get_grading_job"
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/smartik/api/internal/api/handlers"
+)
+
+func RegisterMemorandumQuestionRoutes(e *echo.Group, handler *handlers.MemorandumQuestionHandler, authenticate echo.MiddlewareFunc) {
+	questions := e.Group("/memorandums/:id/questions", authenticate)
+
+	questions.GET("", handler.GetQuestions).Name = "get_memorandum_questions"
+	questions.PUT("", handler.ReplaceQuestions, canManage).Name = "replace_memorandum_questions"
+	questions.GET("/validate", handler.ValidateAllocation).Name = "validate_memorandum_allocation"
+	questions.POST("/create", handler.CreateQuestion, canManage).Name = "create_memorandum_question"
+	questions.PATCH("/update/:questionId", handler.UpdateQuestion, canManage).Name = "update_memorandum_question"
+	questions.DELETE("/delete/:questionId", handler.DeleteQuestion, canManage).Name = "delete_memorandum_question"
+}
@@ -5,13 +5,16 @@ import (
 	"github.com/smartik/api/internal/api/handlers"
 )
 
-func RegisterMemorandumRoutes(e *echo.Group, handlers *handlers.MemorandumHandler) {
-	memorandums := e.Group("/memorandums")
+func RegisterMemorandumRoutes(e *echo.Group, handlers *handlers.MemorandumHandler, authenticate echo.MiddlewareFunc) {
+	memorandums := e.Group("/memorandums", authenticate)
 
-	memorandums.POST("/upload", handlers.UploadMemorandum).Name = "upload_memorandum"
+	memorandums.POST("/upload", handlers.UploadMemorandum, canManage).Name = "upload_memorandum"
 	memorandums.GET("", handlers.GetAllMemorandums).Name = "get_all_memorandums"
 	memorandums.GET("/:id", handlers.GetMemorandumById).Name = "get_memorandum_by_id"
 	memorandums.GET("/serve/:id", handlers.ServeMemorandumFile).Name = "serve_memorandum_file"
-	// TODO: Implement update functionality
-	memorandums.DELETE("/delete/:id", handlers.DeleteMemorandum).Name = "delete_memorandum"
+	memorandums.GET("/:id/download-link", handlers.GetMemorandumDownloadLink).Name = "get_memorandum_download_link"
+	memorandums.PUT("/:id/file", handlers.ReplaceMemorandumFile, canManage).Name = "replace_memorandum_file"
+	memorandums.GET("/:id/versions", handlers.GetMemorandumVersions).Name = "get_memorandum_versions"
+	memorandums.GET("/:id/versions/:version/serve", handlers.ServeMemorandumVersion).Name = "serve_memorandum_version"
+	memorandums.DELETE("/delete/:id", handlers.DeleteMemorandum, adminOnly).Name = "delete_memorandum"
 }
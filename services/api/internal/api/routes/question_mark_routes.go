@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/smartik/api/internal/api/handlers"
+)
+
+func RegisterQuestionMarkRoutes(e *echo.Group, handler *handlers.QuestionMarkHandler, authenticate echo.MiddlewareFunc) {
+	marks := e.Group("/scripts/:id/marks", authenticate)
+
+	marks.GET("", handler.GetMarks).Name = "get_question_marks"
+	marks.PUT("", handler.RecordMarks, canMark).Name = "record_question_marks"
+	marks.DELETE("/delete/:questionId", handler.DeleteMark, canMark).Name = "delete_question_mark"
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/smartik/api/internal/api/handlers"
+)
+
+func RegisterReviewRoutes(e *echo.Group, handler *handlers.ReviewHandler, authenticate echo.MiddlewareFunc) {
+	review := e.Group("/scripts/review", authenticate)
+
+	review.GET("", handler.GetReviewQueue).Name = "get_review_queue"
+	review.GET("/:id/candidates", handler.GetCandidates).Name = "get_match_candidates"
+	review.POST("/:id/confirm", handler.ConfirmMatch, canMark).Name = "confirm_match"
+	review.POST("/:id/reassign", handler.ReassignMatch, canMark).Name = "reassign_match"
+	review.POST("/:id/reject", handler.RejectMatch, canMark).Name = "reject_match"
+}
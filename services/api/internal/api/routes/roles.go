@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/smartik/api/internal/api/middleware"
+	"github.com/smartik/api/internal/models"
+)
+
+// Role requirements of the routes. Every signed in user can read, so read
+// routes only need the authenticate middleware, which has to come before
+// these.
+var (
+	// Setting up records, memorandums and grading
+	canManage = middleware.RequireRole(models.RoleAdmin, models.RoleExaminer)
+
+	// Recording marks and deciding on student matches
+	canMark = middleware.RequireRole(models.RoleAdmin, models.RoleExaminer, models.RoleModerator)
+
+	// Reading the audit log
+	canAudit = middleware.RequireRole(models.RoleAdmin, models.RoleModerator)
+
+	// Deleting records and managing users
+	adminOnly = middleware.RequireRole(models.RoleAdmin)
+)
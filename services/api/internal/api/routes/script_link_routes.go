@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/smartik/api/internal/api/handlers"
+	"github.com/smartik/api/internal/service"
+)
+
+func RegisterScriptLinkRoutes(e *echo.Group, handler *handlers.ScriptLinkHandler, authenticate echo.MiddlewareFunc) {
+	e.POST("/students/:id/scripts", handler.AttachScripts(service.ScriptOwnerStudent), authenticate, canManage).Name = "attach_student_scripts"
+	e.DELETE("/students/:id/scripts", handler.DetachScripts(service.ScriptOwnerStudent), authenticate, canManage).Name = "detach_student_scripts"
+	e.POST("/subjects/:id/scripts", handler.AttachScripts(service.ScriptOwnerSubject), authenticate, canManage).Name = "attach_subject_scripts"
+	e.DELETE("/subjects/:id/scripts", handler.DetachScripts(service.ScriptOwnerSubject), authenticate, canManage).Name = "detach_subject_scripts"
+	e.POST("/exams/:id/scripts", handler.AttachScripts(service.ScriptOwnerExam), authenticate, canManage).Name = "attach_exam_scripts"
+	e.DELETE("/exams/:id/scripts", handler.DetachScripts(service.ScriptOwnerExam), authenticate, canManage).Name = "detach_exam_scripts"
+}
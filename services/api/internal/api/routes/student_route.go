@@ -5,12 +5,13 @@ import (
 	"github.com/smartik/api/internal/api/handlers"
 )
 
-func RegisterStudentRoutes(e *echo.Group, studentHandler *handlers.StudentHandler) {
-	students := e.Group("/students")
+func RegisterStudentRoutes(e *echo.Group, studentHandler *handlers.StudentHandler, authenticate echo.MiddlewareFunc) {
+	students := e.Group("/students", authenticate)
 
 	students.GET("", studentHandler.GetAllStudents).Name = "get_all_students"
-	students.POST("/create", studentHandler.CreateStudent).Name = "create_student"
+	students.POST("/create", studentHandler.CreateStudent, canManage).Name = "create_student"
+	students.POST("/import", studentHandler.ImportStudents, canManage).Name = "import_students"
 	students.GET("/:id", studentHandler.GetStudentById).Name = "get_student_by_exam_number"
-	students.PATCH("/update/:id", studentHandler.UpdateStudent).Name = "update_student"
-	students.DELETE("/delete/:id", studentHandler.DeleteStudent).Name = "delete_student"
+	students.PATCH("/update/:id", studentHandler.UpdateStudent, canManage).Name = "update_student"
+	students.DELETE("/delete/:id", studentHandler.DeleteStudent, adminOnly).Name = "delete_student"
 }
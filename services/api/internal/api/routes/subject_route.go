@@ -8,12 +8,13 @@ import (
 func RegisterSubjectRoutes(
 	e *echo.Group,
 	subjectHandler *handlers.SubjectHandler,
+	authenticate echo.MiddlewareFunc,
 ) {
-	subjects := e.Group("/subjects")
+	subjects := e.Group("/subjects", authenticate)
 
 	subjects.GET("", subjectHandler.GetAllSubjects).Name = "get_all_subjects"
-	subjects.POST("/create", subjectHandler.CreateSubject).Name = "create_subject"
+	subjects.POST("/create", subjectHandler.CreateSubject, canManage).Name = "create_subject"
 	subjects.GET("/:id", subjectHandler.GetSubjectById).Name = "get_subject_by_id"
-	subjects.PATCH("/update/:id", subjectHandler.UpdateSubject).Name = "update_subject"
-	subjects.DELETE("/delete/:id", subjectHandler.DeleteSubject).Name = "delete_subject"
+	subjects.PATCH("/update/:id", subjectHandler.UpdateSubject, canManage).Name = "update_subject"
+	subjects.DELETE("/delete/:id", subjectHandler.DeleteSubject, adminOnly).Name = "delete_subject"
 }
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/smartik/api/internal/api/handlers"
+)
+
+func RegisterUploadSessionRoutes(e *echo.Group, handlers *handlers.UploadSessionHandler, authenticate echo.MiddlewareFunc) {
+	uploads := e.Group("/scripts/uploads", authenticate, canManage)
+
+	uploads.POST("", handlers.CreateUpload).Name = "create_upload"
+	uploads.GET("/:id", handlers.GetUpload).Name = "get_upload"
+	uploads.PUT("/:id/parts/:number", handlers.UploadPart).Name = "upload_part"
+	uploads.POST("/:id/complete", handlers.CompleteUpload).Name = "complete_upload"
+	uploads.DELETE("/:id", handlers.AbortUpload).Name = "abort_upload"
+}
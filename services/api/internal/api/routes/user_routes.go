@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/smartik/api/internal/api/handlers"
+)
+
+func RegisterUserRoutes(e *echo.Group, handler *handlers.UserHandler, authenticate echo.MiddlewareFunc) {
+	users := e.Group("/users", authenticate, adminOnly)
+
+	users.GET("", handler.GetAllUsers).Name = "get_all_users"
+	users.POST("/create", handler.CreateUser).Name = "create_user"
+	users.GET("/:id", handler.GetUserById).Name = "get_user_by_id"
+	users.PATCH("/update/:id", handler.UpdateUser).Name = "update_user"
+	users.DELETE("/delete/:id", handler.DeleteUser).Name = "delete_user"
+}
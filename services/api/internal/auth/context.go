@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/smartik/api/internal/models"
+)
+
+type contextKey int
+
+const (
+	userKey contextKey = iota
+	systemActorKey
+)
+
+// Returns a copy of ctx carrying the signed in user
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, userKey, user)
+}
+
+// Returns the signed in user carried by ctx, or nil
+func UserFromContext(ctx context.Context) *models.User {
+	user, _ := ctx.Value(userKey).(*models.User)
+	return user
+}
+
+// Returns a copy of ctx for work done by the system rather than a user,
+// e.g. a background worker, under the given name
+func WithSystemActor(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, systemActorKey, name)
+}
+
+// Returns the name of the system actor carried by ctx, or "system"
+func SystemActorFromContext(ctx context.Context) string {
+	if name, ok := ctx.Value(systemActorKey).(string); ok && name != "" {
+		return name
+	}
+	return "system"
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// Returned when a signed token was not issued by the signer or was altered
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Signs tokens that carry their own claims, such as download links, so
+// they can be checked without storing them
+type Signer struct {
+	key []byte
+}
+
+// Creates a new instance of Signer. Without a secret a random key is used,
+// tokens are then only valid within the running process.
+func NewSigner(secret string) (*Signer, error) {
+	if secret != "" {
+		return &Signer{[]byte(secret)}, nil
+	}
+
+	key := make([]byte, tokenBytes)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &Signer{key}, nil
+}
+
+// Returns a URL safe token holding the payload and its signature
+func (s *Signer) Sign(payload []byte) string {
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.signature(encoded)
+}
+
+// Returns the payload of a token issued by Sign
+func (s *Signer) Verify(token string) ([]byte, error) {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(s.signature(encoded))) {
+		return nil, ErrInvalidSignature
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	return payload, nil
+}
+
+func (s *Signer) signature(encoded string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
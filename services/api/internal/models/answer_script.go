@@ -10,34 +10,82 @@ const (
 	StatusFailed     ProcessingStatus = "failed"
 )
 
+type MatchStatus string
+
+const (
+	MatchStatusUnmatched   MatchStatus = "unmatched"    // No exam number has been matched yet
+	MatchStatusAutoMatched MatchStatus = "auto_matched" // Linked to a student without human review
+	MatchStatusNeedsReview MatchStatus = "needs_review" // The best candidate was below the confidence threshold
+	MatchStatusConfirmed   MatchStatus = "confirmed"    // A reviewer confirmed or assigned the student
+	MatchStatusRejected    MatchStatus = "rejected"     // A reviewer rejected the match, the script belongs to no known student
+)
+
 type AnswerScript struct {
 	BaseModel
-	FileName           string           `json:"file_name" gorm:"type:varchar(255);not null" validate:"required,min=3,max=255"`
-	FileUrl            *string          `json:"file_url" gorm:"type:text" validate:"omitempty"`
-	StudentId          *string          `json:"student_id" gorm:"type:varchar(25)" validate:"omitempty"`
-	Student            *Student         `json:"student,omitempty" gorm:"foreignKey:StudentId;references:Id;constraint:OnDelete:SET NULL" validate:"-"`
-	SubjectId          *string          `json:"subject_id" gorm:"type:varchar(25)" validate:"omitempty"`
-	Subject            *Subject         `json:"subject,omitempty" gorm:"foreignKey:SubjectId;references:Id;constraint:OnDelete:SET NULL" validate:"-"`
-	ExamId             *string          `json:"exam_id" gorm:"type:varchar(25)" validate:"omitempty"`
-	Exam               *Exam            `json:"exam,omitempty" gorm:"foreignKey:ExamId;references:Id;constraint:OnDelete:CASCADE" validate:"-"`
-	TotalMarks         *int             `json:"total_marks" gorm:"type:int;default:NULL" validate:"omitempty,numeric,min=0"`
-	MaxMarks           *int             `json:"max_marks" gorm:"type:int;default:NULL" validate:"omitempty,numeric,min=0"`
-	ScannedExamNumber  *string          `json:"scanned_exam_number" gorm:"type:varchar(20)" validate:"omitempty,min=4,max=20"`
-	Status             ProcessingStatus `json:"processing_status" gorm:"type:varchar(20);default:processing" validate:"omitempty,oneof=processing uploaded failed"` // can be 'processing', 'uploaded', or 'failed'
-	MatchedAt          *time.Time       `json:"matched_at" gorm:"type:timestamp;default:NULL" validate:"omitempty"`
-	MatchingConfidence *float32         `json:"matching_confidence" gorm:"type:float" validate:"omitempty,numeric"` // Confidence interval for the OCR extracted scanned exam number
+	FileName            string           `json:"file_name" gorm:"type:varchar(255);not null" validate:"required,min=3,max=255"` // Original name of the uploaded file
+	StorageKey          string           `json:"storage_key" gorm:"type:varchar(512);index" validate:"-"`                       // Key of the file in object storage
+	Version             int              `json:"version" gorm:"not null;default:1" validate:"-"`                                // Number of the current file, see FileVersion
+	FileUrl             *string          `json:"file_url" gorm:"type:text" validate:"omitempty"`
+	StudentId           *string          `json:"student_id" gorm:"type:varchar(25)" validate:"omitempty"`
+	Student             *Student         `json:"student,omitempty" gorm:"foreignKey:StudentId;references:Id;constraint:OnDelete:SET NULL" validate:"-"`
+	SubjectId           *string          `json:"subject_id" gorm:"type:varchar(25)" validate:"omitempty"`
+	Subject             *Subject         `json:"subject,omitempty" gorm:"foreignKey:SubjectId;references:Id;constraint:OnDelete:SET NULL" validate:"-"`
+	ExamId              *string          `json:"exam_id" gorm:"type:varchar(25)" validate:"omitempty"`
+	Exam                *Exam            `json:"exam,omitempty" gorm:"foreignKey:ExamId;references:Id;constraint:OnDelete:CASCADE" validate:"-"`
+	TotalMarks          *int             `json:"total_marks" gorm:"type:int;default:NULL" validate:"omitempty,numeric,min=0"`
+	MaxMarks            *int             `json:"max_marks" gorm:"type:int;default:NULL" validate:"omitempty,numeric,min=0"`
+	ScannedExamNumber   *string          `json:"scanned_exam_number" gorm:"type:varchar(20)" validate:"omitempty,min=4,max=20"`
+	Status              ProcessingStatus `json:"processing_status" gorm:"type:varchar(20);default:processing" validate:"omitempty,oneof=processing uploaded failed"` // can be 'processing', 'uploaded', or 'failed'
+	MatchedAt           *time.Time       `json:"matched_at" gorm:"type:timestamp;default:NULL" validate:"omitempty"`
+	MatchingConfidence  *float32         `json:"matching_confidence" gorm:"type:float" validate:"omitempty,numeric"` // Confidence interval for the OCR extracted scanned exam number
+	MatchScore          *float32         `json:"match_score" gorm:"type:float" validate:"omitempty,numeric"`         // OCR confidence times the similarity to the best matching student
+	MatchStatus         MatchStatus      `json:"match_status" gorm:"type:varchar(20);not null;default:unmatched;index" validate:"-"`
+	ReviewedBy          *string          `json:"reviewed_by" gorm:"type:varchar(100)" validate:"-"` // Who last decided on the student
+	ReviewedAt          *time.Time       `json:"reviewed_at" gorm:"type:timestamp" validate:"-"`
+	ProcessingError     *string          `json:"processing_error" gorm:"type:text" validate:"-"` // Why processing failed, when the status is 'failed'
+	ProcessingClaimedAt *time.Time       `json:"-" gorm:"type:timestamp;index" validate:"-"`     // When a worker picked the script up for processing
+}
+
+// Returns the key the file is stored under. Records uploaded before
+// storage keys were introduced were stored under their file name.
+func (a *AnswerScript) ObjectKey() string {
+	if a.StorageKey != "" {
+		return a.StorageKey
+	}
+	return a.FileName
+}
+
+// A reviewer's decision about the student an answer script belongs to
+type MatchReview struct {
+	StudentId  *string `json:"student_id,omitempty" validate:"omitempty"`
+	ReviewedBy string  `json:"-" validate:"-"` // Set to the Id of the signed in user
+}
+
+// Answer scripts to attach to or detach from a student, subject or exam
+type AnswerScriptLinks struct {
+	AnswerScriptIds []string `json:"answer_script_ids" validate:"required,min=1,max=500,dive,required"`
 }
 
+// Fields of an answer script users may change. Everything else is
+// derived from the file, the marks or the matching and has its own endpoint.
 type UpdateAnswerScript struct {
-	FileName           *string           `json:"file_name,omitempty" validate:"omitempty,min=3,max=255"`
-	FileUrl            *string           `json:"file_url,omitempty" validate:"omitempty,url"`
-	StudentId          *string           `json:"student_id,omitempty" validate:"omitempty"`
-	SubjectId          *string           `json:"subject_id,omitempty" validate:"omitempty"`
-	ExamId             *string           `json:"exam_id,omitempty" validate:"omitempty"`
-	TotalMarks         *int              `json:"total_marks,omitempty" validate:"omitempty,numeric,min=0"`
-	MaxMarks           *int              `json:"max_marks,omitempty" validate:"omitempty,numeric,min=0"`
-	ScannedExamNumber  *string           `json:"scanned_exam_number,omitempty" validate:"omitempty,min=4,max=20"`
-	Status             *ProcessingStatus `json:"processing_status,omitempty" validate:"omitempty,oneof=processing uploaded failed"` // can be 'processing', 'uploaded', or 'failed'
-	MatchingConfidence *float32          `json:"matching_confidence,omitempty" validate:"omitempty,numeric"`
-	MatchedAt          *time.Time        `json:"matched_at,omitempty" validate:"omitempty"`
+	FileName  *string `json:"file_name,omitempty" validate:"omitempty,min=3,max=255"`
+	FileUrl   *string `json:"file_url,omitempty" validate:"omitempty,url"`
+	SubjectId *string `json:"subject_id,omitempty" validate:"omitempty"`
+	ExamId    *string `json:"exam_id,omitempty" validate:"omitempty"`
+}
+
+// Overrides the processing status of an answer script
+type UpdateAnswerScriptStatus struct {
+	Status          ProcessingStatus `json:"processing_status" validate:"required,oneof=processing uploaded failed"`
+	ProcessingError *string          `json:"processing_error,omitempty" validate:"omitempty,max=1000"` // Only kept when the status is 'failed'
+}
+
+// Overrides what matching found for an answer script. The student is
+// linked or unlinked through the match review and attach endpoints.
+type UpdateAnswerScriptMatching struct {
+	ScannedExamNumber  *string      `json:"scanned_exam_number,omitempty" validate:"omitempty,min=4,max=20"`
+	MatchingConfidence *float32     `json:"matching_confidence,omitempty" validate:"omitempty,min=0,max=1"`
+	MatchScore         *float32     `json:"match_score,omitempty" validate:"omitempty,min=0,max=1"`
+	MatchStatus        *MatchStatus `json:"match_status,omitempty" validate:"omitempty,oneof=unmatched auto_matched needs_review confirmed rejected"`
 }
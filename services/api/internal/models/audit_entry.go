@@ -0,0 +1,60 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Returned when trying to change or remove an audit entry
+var ErrAuditEntryImmutable = errors.New("audit entries cannot be changed or deleted")
+
+type AuditAction string
+
+const (
+	AuditActionCreate        AuditAction = "create"
+	AuditActionUpdate        AuditAction = "update"
+	AuditActionDelete        AuditAction = "delete"
+	AuditActionAutoMatch     AuditAction = "auto_match"
+	AuditActionConfirmMatch  AuditAction = "confirm_match"
+	AuditActionReassignMatch AuditAction = "reassign_match"
+	AuditActionRejectMatch   AuditAction = "reject_match"
+	AuditActionReprocess     AuditAction = "reprocess"
+	AuditActionReplaceFile   AuditAction = "replace_file"
+)
+
+// A field that changed, with its values before and after the change
+type AuditChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// A record of a single change to an entity. Entries are only ever added.
+type AuditEntry struct {
+	Id         string                 `json:"id" gorm:"primaryKey;type:varchar(25)"`
+	CreatedAt  time.Time              `json:"created_at" gorm:"autoCreateTime;index"`
+	ActorId    *string                `json:"actor_id" gorm:"type:varchar(25);index"`  // Not set for changes made by the system
+	Actor      string                 `json:"actor" gorm:"type:varchar(255);not null"` // Email of the user, or the name of the system component
+	Action     AuditAction            `json:"action" gorm:"type:varchar(30);not null;index"`
+	EntityType string                 `json:"entity_type" gorm:"type:varchar(50);not null;index:idx_audit_entity"`
+	EntityId   string                 `json:"entity_id" gorm:"type:varchar(25);not null;index:idx_audit_entity"`
+	Before     map[string]interface{} `json:"before" gorm:"type:jsonb;serializer:json"`
+	After      map[string]interface{} `json:"after" gorm:"type:jsonb;serializer:json"`
+	Changes    map[string]AuditChange `json:"changes" gorm:"type:jsonb;serializer:json"`
+}
+
+func (a *AuditEntry) BeforeCreate(tx *gorm.DB) error {
+	if a.Id == "" {
+		return SetId(&a.Id)
+	}
+	return nil
+}
+
+func (a *AuditEntry) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditEntryImmutable
+}
+
+func (a *AuditEntry) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditEntryImmutable
+}
@@ -2,15 +2,40 @@ package models
 
 import "time"
 
+// Where an exam is in its lifecycle. The status only changes through
+// the transitions of the exam service.
+type ExamStatus string
+
+const (
+	ExamStatusDraft      ExamStatus = "draft"      // The exam is being set up
+	ExamStatusOpen       ExamStatus = "open"       // Answer scripts can be uploaded
+	ExamStatusMarking    ExamStatus = "marking"    // Scripts are being marked, late scripts can still be uploaded
+	ExamStatusModeration ExamStatus = "moderation" // A moderator checks the marking
+	ExamStatusFinalized  ExamStatus = "finalized"  // Marks are locked
+	ExamStatusPublished  ExamStatus = "published"  // Results were released
+)
+
+// A paper of a subject written on a specific date
 type Exam struct {
 	BaseModel
-	Date          time.Time      `json:"date" gorm:"index:idx_exam_date;not null" validate:"required"`
-	TotalMarks    int            `json:"total_marks" gorm:"type:int;default:1;not null" validate:"numeric,min=0"`
-	AnswerScripts []AnswerScript `json:"answer_scripts,omitempty" gorm:"foreignKey:ExamId;references:Id;constraint:OnDelete:CASCADE" validate:"-"`
+	SubjectId       *string        `json:"subject_id" gorm:"type:varchar(25);index" validate:"required"` // Only empty for exams created before exams belonged to subjects
+	Subject         *Subject       `json:"subject,omitempty" gorm:"foreignKey:SubjectId;references:Id;constraint:OnDelete:RESTRICT" validate:"-"`
+	Name            string         `json:"name" gorm:"type:varchar(150);not null;default:''" validate:"required,min=3,max=150"`
+	PaperNumber     int            `json:"paper_number" gorm:"type:int;not null;default:1" validate:"omitempty,min=1,max=9"`
+	AcademicYear    int            `json:"academic_year" gorm:"type:int;not null;default:0;index" validate:"omitempty,min=2000,max=2100"` // Defaults to the year of the date
+	Date            time.Time      `json:"date" gorm:"index:idx_exam_date;not null" validate:"required"`
+	DurationMinutes int            `json:"duration_minutes" gorm:"type:int;not null;default:0" validate:"omitempty,min=1,max=720"`
+	TotalMarks      int            `json:"total_marks" gorm:"type:int;default:1;not null" validate:"numeric,min=0"`
+	Status          ExamStatus     `json:"status" gorm:"type:varchar(20);not null;default:draft;index" validate:"-"`
+	AnswerScripts   []AnswerScript `json:"answer_scripts,omitempty" gorm:"foreignKey:ExamId;references:Id;constraint:OnDelete:CASCADE" validate:"-"`
 }
 
 type UpdateExam struct {
-	Date          *time.Time `json:"date" validate:"omitempty"`
-	TotalMarks    *int       `json:"total_marks,omitempty" validate:"omitempty,numeric,min=0"`
-	AnswerScripts *[]string  `json:"answer_scripts,omitempty" validate:"omitempty"` // IDs of answer scripts to add to attach to the exam
+	SubjectId       *string    `json:"subject_id,omitempty" validate:"omitempty"`
+	Name            *string    `json:"name,omitempty" validate:"omitempty,min=3,max=150"`
+	PaperNumber     *int       `json:"paper_number,omitempty" validate:"omitempty,min=1,max=9"`
+	AcademicYear    *int       `json:"academic_year,omitempty" validate:"omitempty,min=2000,max=2100"`
+	Date            *time.Time `json:"date" validate:"omitempty"`
+	DurationMinutes *int       `json:"duration_minutes,omitempty" validate:"omitempty,min=1,max=720"`
+	TotalMarks      *int       `json:"total_marks,omitempty" validate:"omitempty,numeric,min=0"`
 }
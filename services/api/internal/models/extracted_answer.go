@@ -0,0 +1,22 @@
+package models
+
+// The answer a student gave to a single memorandum question, as read from
+// their answer script. Extracted answers are what automated grading works on.
+type ExtractedAnswer struct {
+	BaseModel
+	AnswerScriptId string              `json:"answer_script_id" gorm:"type:varchar(25);not null;uniqueIndex:idx_extracted_answer_script_question" validate:"-"`
+	AnswerScript   *AnswerScript       `json:"-" gorm:"foreignKey:AnswerScriptId;references:Id;constraint:OnDelete:CASCADE" validate:"-"`
+	QuestionId     string              `json:"question_id" gorm:"type:varchar(25);not null;uniqueIndex:idx_extracted_answer_script_question;index" validate:"-"`
+	Question       *MemorandumQuestion `json:"-" gorm:"foreignKey:QuestionId;references:Id;constraint:OnDelete:CASCADE" validate:"-"`
+	Text           string              `json:"text" gorm:"type:text" validate:"max=10000"`
+}
+
+type ExtractedAnswerInput struct {
+	QuestionId string `json:"question_id" validate:"required"`
+	Text       string `json:"text" validate:"max=10000"`
+}
+
+// A batch of answers extracted from one answer script
+type RecordExtractedAnswers struct {
+	Answers []ExtractedAnswerInput `json:"answers" validate:"required,min=1,dive"`
+}
@@ -0,0 +1,24 @@
+package models
+
+// The kind of record a file belongs to
+type FileRecordType string
+
+const (
+	FileRecordAnswerScript FileRecordType = "answer_script"
+	FileRecordMemorandum   FileRecordType = "memorandum"
+)
+
+// A file that is or was behind an answer script or memorandum. Replacing
+// the file of a record keeps the previous one in storage under its own key.
+type FileVersion struct {
+	BaseModel
+	RecordType  FileRecordType `json:"record_type" gorm:"type:varchar(20);not null;uniqueIndex:idx_file_version_record" validate:"-"`
+	RecordId    string         `json:"record_id" gorm:"type:varchar(25);not null;uniqueIndex:idx_file_version_record" validate:"-"`
+	Version     int            `json:"version" gorm:"not null;uniqueIndex:idx_file_version_record" validate:"-"` // Numbered from 1 for the first file
+	FileName    string         `json:"file_name" gorm:"type:varchar(255);not null" validate:"-"`
+	StorageKey  string         `json:"-" gorm:"type:varchar(512);not null" validate:"-"`
+	ContentType string         `json:"content_type" gorm:"type:varchar(255);not null" validate:"-"`
+	Size        int64          `json:"size" gorm:"not null" validate:"-"`
+	UploadedBy  *string        `json:"uploaded_by" gorm:"type:varchar(25)" validate:"-"` // Unknown for files uploaded before versions were kept
+	Current     bool           `json:"current" gorm:"-" validate:"-"`                    // Whether the record still uses this file
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+type GradingJobStatus string
+
+const (
+	GradingJobQueued    GradingJobStatus = "queued"
+	GradingJobRunning   GradingJobStatus = "running"
+	GradingJobCompleted GradingJobStatus = "completed"
+	GradingJobFailed    GradingJobStatus = "failed"
+)
+
+// A run of the automated grader over the answer scripts of an exam
+type GradingJob struct {
+	BaseModel
+	ExamId         string           `json:"exam_id" gorm:"type:varchar(25);not null;index" validate:"-"`
+	Exam           *Exam            `json:"-" gorm:"foreignKey:ExamId;references:Id;constraint:OnDelete:CASCADE" validate:"-"`
+	Grader         string           `json:"grader" gorm:"type:varchar(50);not null" validate:"-"`
+	Overwrite      bool             `json:"overwrite" gorm:"not null;default:false" validate:"-"` // Whether marks awarded by people are replaced
+	Status         GradingJobStatus `json:"status" gorm:"type:varchar(20);not null;default:queued;index" validate:"-"`
+	TotalScripts   int              `json:"total_scripts" gorm:"type:int;not null;default:0" validate:"-"`
+	GradedScripts  int              `json:"graded_scripts" gorm:"type:int;not null;default:0" validate:"-"`
+	SkippedScripts int              `json:"skipped_scripts" gorm:"type:int;not null;default:0" validate:"-"` // Scripts without extracted answers
+	FailedScripts  int              `json:"failed_scripts" gorm:"type:int;not null;default:0" validate:"-"`
+	Error          *string          `json:"error" gorm:"type:text" validate:"-"`
+	StartedAt      *time.Time       `json:"started_at" gorm:"type:timestamp" validate:"-"`
+	FinishedAt     *time.Time       `json:"finished_at" gorm:"type:timestamp" validate:"-"`
+	HeartbeatAt    *time.Time       `json:"heartbeat_at" gorm:"type:timestamp" validate:"-"` // Refreshed while the job runs
+}
+
+type StartGradingJob struct {
+	Overwrite bool `json:"overwrite"`
+}
@@ -2,7 +2,18 @@ package models
 
 type Memorandum struct {
 	BaseModel
-	FileName string `json:"file_name" gorm:"type:varchar(255);not null" validate:"omitempty"`
-	ExamId   string `json:"exam_id" gorm:"type:varchar(25);not null" validate:"required"`
-	Exam     *Exam  `json:"exam,omitempty" gorm:"foreignKey:ExamId;references:Id;constraint:OnDelete:CASCADE"`
+	FileName   string `json:"file_name" gorm:"type:varchar(255);not null" validate:"omitempty"` // Original name of the uploaded file
+	StorageKey string `json:"storage_key" gorm:"type:varchar(512);index" validate:"-"`          // Key of the file in object storage
+	Version    int    `json:"version" gorm:"not null;default:1" validate:"-"`                   // Number of the current file, see FileVersion
+	ExamId     string `json:"exam_id" gorm:"type:varchar(25);not null" validate:"required"`
+	Exam       *Exam  `json:"exam,omitempty" gorm:"foreignKey:ExamId;references:Id;constraint:OnDelete:CASCADE"`
+}
+
+// Returns the key the file is stored under. Records uploaded before
+// storage keys were introduced were stored under their file name.
+func (m *Memorandum) ObjectKey() string {
+	if m.StorageKey != "" {
+		return m.StorageKey
+	}
+	return m.FileName
 }
@@ -0,0 +1,69 @@
+package models
+
+// How an answer is compared with the expected answer when graded automatically
+type MatchMode string
+
+const (
+	MatchModeExact    MatchMode = "exact"    // The answer has to equal the expected or an alternative answer
+	MatchModeKeywords MatchMode = "keywords" // Marks are awarded in proportion to the keywords found in the answer
+	MatchModeNumeric  MatchMode = "numeric"  // The answer has to be a number within the tolerance of the expected one
+)
+
+// A question or sub-question of a structured memorandum
+type MemorandumQuestion struct {
+	BaseModel
+	MemorandumId       string               `json:"memorandum_id" gorm:"type:varchar(25);not null;index" validate:"-"`
+	Memorandum         *Memorandum          `json:"-" gorm:"foreignKey:MemorandumId;references:Id;constraint:OnDelete:CASCADE" validate:"-"`
+	ExamId             string               `json:"exam_id" gorm:"type:varchar(25);not null;index" validate:"-"`
+	Exam               *Exam                `json:"-" gorm:"foreignKey:ExamId;references:Id;constraint:OnDelete:CASCADE" validate:"-"`
+	ParentId           *string              `json:"parent_id" gorm:"type:varchar(25);index" validate:"-"` // Set for sub-questions
+	SubQuestions       []MemorandumQuestion `json:"sub_questions,omitempty" gorm:"foreignKey:ParentId;references:Id;constraint:OnDelete:CASCADE" validate:"-"`
+	Number             string               `json:"number" gorm:"type:varchar(20);not null" validate:"required,max=20"` // e.g. "1", "1.2" or "3(a)"
+	Position           int                  `json:"position" gorm:"type:int;not null;default:0" validate:"min=0"`       // Order among its siblings
+	Prompt             string               `json:"prompt" gorm:"type:text" validate:"omitempty,max=5000"`
+	ExpectedAnswer     string               `json:"expected_answer" gorm:"type:text" validate:"omitempty,max=5000"`
+	AlternativeAnswers []string             `json:"alternative_answers" gorm:"type:jsonb;serializer:json" validate:"omitempty,dive,max=5000"`
+	Marks              int                  `json:"marks" gorm:"type:int;not null" validate:"min=0"`
+	MatchMode          MatchMode            `json:"match_mode" gorm:"type:varchar(20);not null;default:exact" validate:"omitempty,oneof=exact keywords numeric"`
+	Keywords           []string             `json:"keywords" gorm:"type:jsonb;serializer:json" validate:"omitempty,dive,max=200"`
+	Tolerance          float64              `json:"tolerance" gorm:"type:double precision;not null;default:0" validate:"min=0"` // Allowed absolute difference for numeric answers
+}
+
+// A question, with its sub-questions, as submitted when replacing the
+// whole structure of a memorandum
+type MemorandumQuestionInput struct {
+	Number             string                    `json:"number" validate:"required,max=20"`
+	Prompt             string                    `json:"prompt" validate:"omitempty,max=5000"`
+	ExpectedAnswer     string                    `json:"expected_answer" validate:"omitempty,max=5000"`
+	AlternativeAnswers []string                  `json:"alternative_answers" validate:"omitempty,dive,max=5000"`
+	Marks              int                       `json:"marks" validate:"min=0"`
+	MatchMode          MatchMode                 `json:"match_mode" validate:"omitempty,oneof=exact keywords numeric"`
+	Keywords           []string                  `json:"keywords" validate:"omitempty,dive,max=200"`
+	Tolerance          float64                   `json:"tolerance" validate:"min=0"`
+	SubQuestions       []MemorandumQuestionInput `json:"sub_questions,omitempty" validate:"omitempty,dive"`
+}
+
+type CreateMemorandumQuestion struct {
+	ParentId           *string   `json:"parent_id,omitempty" validate:"omitempty"`
+	Number             string    `json:"number" validate:"required,max=20"`
+	Position           *int      `json:"position,omitempty" validate:"omitempty,min=0"`
+	Prompt             string    `json:"prompt" validate:"omitempty,max=5000"`
+	ExpectedAnswer     string    `json:"expected_answer" validate:"omitempty,max=5000"`
+	AlternativeAnswers []string  `json:"alternative_answers" validate:"omitempty,dive,max=5000"`
+	Marks              int       `json:"marks" validate:"min=0"`
+	MatchMode          MatchMode `json:"match_mode" validate:"omitempty,oneof=exact keywords numeric"`
+	Keywords           []string  `json:"keywords" validate:"omitempty,dive,max=200"`
+	Tolerance          float64   `json:"tolerance" validate:"min=0"`
+}
+
+type UpdateMemorandumQuestion struct {
+	Number             *string    `json:"number,omitempty" validate:"omitempty,max=20"`
+	Position           *int       `json:"position,omitempty" validate:"omitempty,min=0"`
+	Prompt             *string    `json:"prompt,omitempty" validate:"omitempty,max=5000"`
+	ExpectedAnswer     *string    `json:"expected_answer,omitempty" validate:"omitempty,max=5000"`
+	AlternativeAnswers *[]string  `json:"alternative_answers,omitempty" validate:"omitempty,dive,max=5000"`
+	Marks              *int       `json:"marks,omitempty" validate:"omitempty,min=0"`
+	MatchMode          *MatchMode `json:"match_mode,omitempty" validate:"omitempty,oneof=exact keywords numeric"`
+	Keywords           *[]string  `json:"keywords,omitempty" validate:"omitempty,dive,max=200"`
+	Tolerance          *float64   `json:"tolerance,omitempty" validate:"omitempty,min=0"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// The marks awarded to an answer script for a single memorandum question
+type QuestionMark struct {
+	BaseModel
+	AnswerScriptId string              `json:"answer_script_id" gorm:"type:varchar(25);not null;uniqueIndex:idx_question_mark_script_question" validate:"-"`
+	AnswerScript   *AnswerScript       `json:"-" gorm:"foreignKey:AnswerScriptId;references:Id;constraint:OnDelete:CASCADE" validate:"-"`
+	QuestionId     string              `json:"question_id" gorm:"type:varchar(25);not null;uniqueIndex:idx_question_mark_script_question;index" validate:"-"`
+	Question       *MemorandumQuestion `json:"-" gorm:"foreignKey:QuestionId;references:Id;constraint:OnDelete:CASCADE" validate:"-"`
+	Awarded        int                 `json:"awarded" gorm:"type:int;not null" validate:"min=0"`
+	MaxMarks       int                 `json:"max_marks" gorm:"type:int;not null" validate:"min=0"`      // Copied from the question when marked
+	MarkedBy       string              `json:"marked_by" gorm:"type:varchar(100);not null" validate:"-"` // Id of the user, or "auto:<grader>" for automated grading
+	Comment        string              `json:"comment" gorm:"type:text" validate:"omitempty,max=1000"`
+}
+
+type QuestionMarkInput struct {
+	QuestionId string `json:"question_id" validate:"required"`
+	Awarded    int    `json:"awarded" validate:"min=0"`
+	Comment    string `json:"comment" validate:"omitempty,max=1000"`
+}
+
+// A batch of marks recorded for one answer script
+type RecordQuestionMarks struct {
+	MarkedBy string              `json:"-" validate:"-"` // Set to the Id of the signed in user
+	Marks    []QuestionMarkInput `json:"marks" validate:"required,min=1,dive"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// A signed in user. Only a hash of the session token is stored, the token
+// itself is handed to the client once when signing in.
+type Session struct {
+	BaseModel
+	UserId    string    `json:"user_id" gorm:"type:varchar(25);not null;index" validate:"-"`
+	User      *User     `json:"-" gorm:"foreignKey:UserId;references:Id;constraint:OnDelete:CASCADE" validate:"-"`
+	TokenHash string    `json:"-" gorm:"type:varchar(64);uniqueIndex;not null" validate:"-"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"type:timestamp;not null;index" validate:"-"`
+}
@@ -9,8 +9,7 @@ type Student struct {
 }
 
 type UpdateStudent struct {
-	FirstName     *string   `json:"first_name,omitempty" validate:"omitempty,min=3,max=50"`
-	LastName      *string   `json:"last_name,omitempty" validate:"omitempty,min=3,max=50"`
-	ExamNumber    *string   `json:"exam_number,omitempty" validate:"omitempty,min=4,max=20"`
-	AnswerScripts *[]string `json:"answer_scripts,omitempty" validate:"omitempty"` // IDs of answer scripts to add to attach to the student
+	FirstName  *string `json:"first_name,omitempty" validate:"omitempty,min=3,max=50"`
+	LastName   *string `json:"last_name,omitempty" validate:"omitempty,min=3,max=50"`
+	ExamNumber *string `json:"exam_number,omitempty" validate:"omitempty,min=4,max=20"`
 }
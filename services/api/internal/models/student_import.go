@@ -0,0 +1,34 @@
+package models
+
+// What happened to a row of an imported class list
+type ImportRowStatus string
+
+const (
+	ImportRowCreated   ImportRowStatus = "created"   // A new student was created
+	ImportRowUpdated   ImportRowStatus = "updated"   // The student with the exam number was changed
+	ImportRowUnchanged ImportRowStatus = "unchanged" // The student with the exam number already matched the row
+	ImportRowRejected  ImportRowStatus = "rejected"  // The row was invalid and left out
+)
+
+// The outcome of a single row of an imported class list
+type StudentImportRow struct {
+	Row        int             `json:"row"` // Row number in the file, the header is row 1
+	ExamNumber string          `json:"exam_number"`
+	Status     ImportRowStatus `json:"status"`
+	StudentId  *string         `json:"student_id,omitempty"` // Not set for rejected rows and rows created in a dry run
+	Errors     []string        `json:"errors,omitempty"`
+}
+
+type StudentImportSummary struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Rejected  int `json:"rejected"`
+}
+
+// The report of a class list import
+type StudentImportResult struct {
+	DryRun  bool                 `json:"dry_run"` // Nothing was saved when set
+	Summary StudentImportSummary `json:"summary"`
+	Rows    []StudentImportRow   `json:"rows"`
+}
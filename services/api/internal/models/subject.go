@@ -9,8 +9,7 @@ type Subject struct {
 }
 
 type UpdateSubject struct {
-	Name          *string   `json:"name" validate:"omitempty,min=3,max=100"`
-	Code          *string   `json:"code" validate:"omitempty,min=2,max=10"`
-	Description   *string   `json:"description" validate:"omitempty,max=500"`
-	AnswerScripts *[]string `json:"answer_scripts,omitempty" validate:"omitempty"` // IDs of answer scripts to add to attach to the subject
+	Name        *string `json:"name" validate:"omitempty,min=3,max=100"`
+	Code        *string `json:"code" validate:"omitempty,min=2,max=10"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
 }
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+type UploadSessionStatus string
+
+const (
+	UploadSessionActive     UploadSessionStatus = "active"     // Parts are being uploaded
+	UploadSessionCompleting UploadSessionStatus = "completing" // The parts are being joined into an answer script
+	UploadSessionCompleted  UploadSessionStatus = "completed"  // The answer script has been created
+)
+
+// A resumable upload of a single large file. The file is sent in numbered
+// parts of PartSize bytes, only the last part may be smaller. Parts that
+// were stored are recorded, so an interrupted upload continues with the
+// parts that are still missing.
+type UploadSession struct {
+	BaseModel
+	UserId         string              `json:"user_id" gorm:"type:varchar(25);not null;index" validate:"-"`
+	User           *User               `json:"-" gorm:"foreignKey:UserId;references:Id;constraint:OnDelete:CASCADE" validate:"-"`
+	ExamId         *string             `json:"exam_id" gorm:"type:varchar(25);index" validate:"-"`
+	Exam           *Exam               `json:"-" gorm:"foreignKey:ExamId;references:Id;constraint:OnDelete:CASCADE" validate:"-"`
+	FileName       string              `json:"file_name" gorm:"type:varchar(255);not null" validate:"-"`
+	ContentType    string              `json:"content_type" gorm:"type:varchar(255);not null" validate:"-"`
+	Size           int64               `json:"size" gorm:"not null" validate:"-"`
+	PartSize       int64               `json:"part_size" gorm:"not null" validate:"-"`
+	Status         UploadSessionStatus `json:"status" gorm:"type:varchar(20);not null;default:active" validate:"-"`
+	AnswerScriptId *string             `json:"answer_script_id" gorm:"type:varchar(25)" validate:"-"` // Set once the upload is completed
+	AnswerScript   *AnswerScript       `json:"-" gorm:"foreignKey:AnswerScriptId;references:Id;constraint:OnDelete:SET NULL" validate:"-"`
+	ExpiresAt      time.Time           `json:"expires_at" gorm:"type:timestamp;not null;index" validate:"-"`
+	Parts          []UploadPart        `json:"parts" gorm:"foreignKey:SessionId;references:Id;constraint:OnDelete:CASCADE" validate:"-"`
+}
+
+// Returns how many parts the file is split into
+func (u *UploadSession) PartCount() int {
+	if u.PartSize <= 0 {
+		return 0
+	}
+	return int((u.Size + u.PartSize - 1) / u.PartSize)
+}
+
+// Returns the size the part with the given number must have
+func (u *UploadSession) PartLength(number int) int64 {
+	if number == u.PartCount() {
+		return u.Size - int64(number-1)*u.PartSize
+	}
+	return u.PartSize
+}
+
+// A part of an upload session that has been stored
+type UploadPart struct {
+	BaseModel
+	SessionId  string `json:"-" gorm:"type:varchar(25);not null;uniqueIndex:idx_upload_part_session_number" validate:"-"`
+	Number     int    `json:"number" gorm:"type:int;not null;uniqueIndex:idx_upload_part_session_number" validate:"-"`
+	Size       int64  `json:"size" gorm:"not null" validate:"-"`
+	Checksum   string `json:"checksum" gorm:"type:varchar(32);not null" validate:"-"`      // Hex encoded MD5 of the part
+	StorageKey string `json:"-" gorm:"type:varchar(512);not null;default:''" validate:"-"` // Unique to every time the part was sent, empty for parts stored before
+}
+
+type CreateUploadSession struct {
+	FileName    string  `json:"file_name" validate:"required,min=3,max=255"`
+	ContentType string  `json:"content_type" validate:"omitempty,max=255"`
+	Size        int64   `json:"size" validate:"required,min=1"`
+	ExamId      *string `json:"exam_id,omitempty" validate:"omitempty"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+type Role string
+
+const (
+	RoleAdmin     Role = "admin"     // Manages users and can do everything else
+	RoleExaminer  Role = "examiner"  // Sets up exams and memorandums, uploads and marks scripts
+	RoleModerator Role = "moderator" // Reviews matches and adjusts marks
+	RoleViewer    Role = "viewer"    // Can only read records and download files
+)
+
+type User struct {
+	BaseModel
+	Email        string     `json:"email" gorm:"type:varchar(255);uniqueIndex;not null" validate:"required,email,max=255"`
+	Name         string     `json:"name" gorm:"type:varchar(100);not null" validate:"required,min=2,max=100"`
+	PasswordHash string     `json:"-" gorm:"type:varchar(100);not null" validate:"-"`
+	Role         Role       `json:"role" gorm:"type:varchar(20);not null;default:viewer" validate:"required,oneof=admin examiner moderator viewer"`
+	Active       bool       `json:"active" gorm:"not null;default:true" validate:"-"`
+	LastLoginAt  *time.Time `json:"last_login_at" gorm:"type:timestamp" validate:"-"`
+}
+
+type CreateUser struct {
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Name     string `json:"name" validate:"required,min=2,max=100"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+	Role     Role   `json:"role" validate:"required,oneof=admin examiner moderator viewer"`
+}
+
+type UpdateUser struct {
+	Email    *string `json:"email,omitempty" validate:"omitempty,email,max=255"`
+	Name     *string `json:"name,omitempty" validate:"omitempty,min=2,max=100"`
+	Password *string `json:"password,omitempty" validate:"omitempty,min=8,max=72"`
+	Role     *Role   `json:"role,omitempty" validate:"omitempty,oneof=admin examiner moderator viewer"`
+	Active   *bool   `json:"active,omitempty" validate:"omitempty"`
+}
+
+type Login struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
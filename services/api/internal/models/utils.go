@@ -1,11 +0,0 @@
-package models
-
-func GetAllModels() []interface{} {
-	return []interface{}{
-		&Student{},
-		&Subject{},
-		&Exam{},
-		&AnswerScript{},
-		&Memorandum{},
-	}
-}
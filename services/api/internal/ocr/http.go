@@ -0,0 +1,76 @@
+package ocr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"time"
+)
+
+// Delegates recognition to an external OCR service. The file is sent as
+// the "file" field of a multipart form and the service is expected to
+// respond with a JSON encoded Result.
+type HttpRecognizer struct {
+	url    string
+	client *http.Client
+}
+
+// Creates a new instance of HttpRecognizer
+func NewHttpRecognizer(url string) *HttpRecognizer {
+	return &HttpRecognizer{
+		url:    url,
+		client: &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+func (h *HttpRecognizer) Recognize(ctx context.Context, r io.Reader, contentType string) (*Result, error) {
+	// Stream the file into the request body instead of buffering it
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="file"; filename="script"`)
+		header.Set("Content-Type", contentType)
+
+		part, err := form.CreatePart(header)
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusUnprocessableEntity {
+		return nil, ErrNoExamNumber
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ocr service responded with status %d", res.StatusCode)
+	}
+
+	var result Result
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid response from ocr service: %w", err)
+	}
+	if result.ExamNumber == "" {
+		return nil, ErrNoExamNumber
+	}
+	return &result, nil
+}
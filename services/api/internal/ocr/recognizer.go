@@ -0,0 +1,36 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/smartik/api/internal/config"
+)
+
+// Returned when no exam number could be found on a script
+var ErrNoExamNumber = errors.New("no exam number found")
+
+// The outcome of recognizing a single answer script
+type Result struct {
+	ExamNumber string  `json:"exam_number"`
+	Confidence float32 `json:"confidence"` // Between 0 and 1
+}
+
+// Extracts the exam number written on a scanned answer script
+type Recognizer interface {
+	Recognize(ctx context.Context, r io.Reader, contentType string) (*Result, error)
+}
+
+// Creates the recognizer selected by the configuration
+func New(cfg *config.Env) (Recognizer, error) {
+	switch cfg.OcrRecognizer {
+	case config.OcrRecognizerStub:
+		return NewStubRecognizer(), nil
+	case config.OcrRecognizerHttp:
+		return NewHttpRecognizer(cfg.OcrServiceUrl), nil
+	default:
+		return nil, fmt.Errorf("unknown ocr recognizer %q", cfg.OcrRecognizer)
+	}
+}
@@ -0,0 +1,191 @@
+package pdfsplit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+func init() {
+	// pdfcpu would otherwise create a configuration directory in the
+	// user's home on first use
+	api.DisableConfigDir()
+}
+
+// How a batch is divided into answer scripts
+type Mode string
+
+const (
+	// Every script has the same number of pages
+	ModePages Mode = "pages"
+	// Scripts are divided by separator pages, which are left out
+	ModeSeparator Mode = "separator"
+	// Every script starts with a cover page, which is kept
+	ModeCover Mode = "cover"
+)
+
+// Markers looked for when none is given
+const (
+	DefaultSeparatorMarker = "SMARTIK SEPARATOR"
+	DefaultCoverMarker     = "SMARTIK COVER"
+)
+
+var (
+	// Returned when the batch cannot be read as a PDF
+	ErrInvalidPdf = errors.New("the file is not a valid PDF")
+	// Returned when the split options are incomplete or unknown
+	ErrInvalidOptions = errors.New("invalid split options")
+	// Returned when splitting leaves no pages for any script
+	ErrNoScripts = errors.New("no answer scripts found in the batch")
+)
+
+// Describes how to split a batch
+type Options struct {
+	Mode           Mode
+	PagesPerScript int    // Used by ModePages
+	Marker         string // Text identifying separator or cover pages, the mode's default when empty
+}
+
+// A run of pages, numbered from 1 and inclusive, making up one script
+type Range struct {
+	First int `json:"first"`
+	Last  int `json:"last"`
+}
+
+func (r Range) String() string {
+	if r.First == r.Last {
+		return fmt.Sprint(r.First)
+	}
+	return fmt.Sprintf("%d-%d", r.First, r.Last)
+}
+
+// A batch PDF read into memory
+type Document struct {
+	ctx *model.Context
+}
+
+// Reads and validates a PDF
+func Open(rs io.ReadSeeker) (*Document, error) {
+	conf := model.NewDefaultConfiguration()
+	conf.Cmd = model.EXTRACTPAGES
+
+	ctx, err := api.ReadValidateAndOptimize(rs, conf)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPdf, err)
+	}
+	if ctx.PageCount == 0 {
+		return nil, fmt.Errorf("%w: it has no pages", ErrInvalidPdf)
+	}
+	return &Document{ctx}, nil
+}
+
+// Returns the number of pages of the document
+func (d *Document) PageCount() int {
+	return d.ctx.PageCount
+}
+
+// Works out the page ranges of the scripts in the document
+func (d *Document) Plan(opts Options) ([]Range, error) {
+	var ranges []Range
+	switch opts.Mode {
+	case ModePages:
+		if opts.PagesPerScript < 1 {
+			return nil, fmt.Errorf("%w: pages_per_script must be at least 1", ErrInvalidOptions)
+		}
+		for first := 1; first <= d.PageCount(); first += opts.PagesPerScript {
+			ranges = append(ranges, Range{first, min(first+opts.PagesPerScript-1, d.PageCount())})
+		}
+
+	case ModeSeparator:
+		marked, err := d.markedPages(opts.Marker, DefaultSeparatorMarker)
+		if err != nil {
+			return nil, err
+		}
+		first := 1
+		for page := 1; page <= d.PageCount()+1; page++ {
+			if page <= d.PageCount() && !marked[page] {
+				continue
+			}
+			if page > first {
+				ranges = append(ranges, Range{first, page - 1})
+			}
+			first = page + 1
+		}
+
+	case ModeCover:
+		marked, err := d.markedPages(opts.Marker, DefaultCoverMarker)
+		if err != nil {
+			return nil, err
+		}
+		// Pages before the first cover page belong to no script and are left out
+		for page := 1; page <= d.PageCount(); page++ {
+			if marked[page] {
+				ranges = append(ranges, Range{page, page})
+			} else if len(ranges) > 0 {
+				ranges[len(ranges)-1].Last = page
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: mode must be one of pages, separator or cover", ErrInvalidOptions)
+	}
+
+	if len(ranges) == 0 {
+		return nil, ErrNoScripts
+	}
+	return ranges, nil
+}
+
+// Writes the pages of a range as a PDF of their own
+func (d *Document) Extract(r Range) ([]byte, error) {
+	pages := make([]int, 0, r.Last-r.First+1)
+	for page := r.First; page <= r.Last; page++ {
+		pages = append(pages, page)
+	}
+
+	ctx, err := pdfcpu.ExtractPages(d.ctx, pages, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := api.WriteContext(ctx, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Finds the pages whose text contains the marker. Case and white space
+// are ignored, since text layers of scans often split words oddly.
+func (d *Document) markedPages(marker, fallback string) (map[int]bool, error) {
+	if strings.TrimSpace(marker) == "" {
+		marker = fallback
+	}
+	marker = normalizeText(marker)
+
+	marked := map[int]bool{}
+	for page := 1; page <= d.PageCount(); page++ {
+		content, err := pdfcpu.ExtractPageContent(d.ctx, page)
+		if err != nil {
+			return nil, fmt.Errorf("%w: reading page %d: %v", ErrInvalidPdf, page, err)
+		}
+		data, err := io.ReadAll(content)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(normalizeText(contentText(data)), marker) {
+			marked[page] = true
+		}
+	}
+	return marked, nil
+}
+
+func normalizeText(text string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(text), ""))
+}
@@ -0,0 +1,134 @@
+package pdfsplit
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+)
+
+// Collects the strings of a page content stream. This is enough to find
+// markers written in simple fonts, such as printed separator sheets and
+// the text layer scanners add to searchable PDFs. Text drawn with
+// embedded two byte fonts is not decoded.
+func contentText(content []byte) string {
+	var text strings.Builder
+	for i := 0; i < len(content); i++ {
+		switch content[i] {
+		case '%':
+			// Comments run to the end of the line
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case '(':
+			var s []byte
+			s, i = literalString(content, i+1)
+			text.Write(s)
+		case '<':
+			if i+1 < len(content) && content[i+1] == '<' {
+				i++ // dictionary
+				continue
+			}
+			end := bytes.IndexByte(content[i:], '>')
+			if end < 0 {
+				return text.String()
+			}
+			text.Write(hexString(content[i+1 : i+end]))
+			i += end
+		case 'I':
+			// Skip the binary data of inline images, it may contain anything
+			if isOperator(content, i, "ID") {
+				end := bytes.Index(content[i+2:], []byte("EI"))
+				if end < 0 {
+					return text.String()
+				}
+				i += end + 3
+			}
+		}
+	}
+	return text.String()
+}
+
+// Reads a literal string starting after its opening parenthesis and
+// returns its content and the index of the closing parenthesis
+func literalString(content []byte, i int) ([]byte, int) {
+	var s []byte
+	depth := 0
+	for ; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\\' && i+1 < len(content):
+			i++
+			switch e := content[i]; e {
+			case 'n':
+				s = append(s, '\n')
+			case 'r':
+				s = append(s, '\r')
+			case 't':
+				s = append(s, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					value := 0
+					for n := 0; n < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7'; n++ {
+						value = value*8 + int(content[i]-'0')
+						i++
+					}
+					i--
+					s = append(s, byte(value))
+				} else {
+					s = append(s, e)
+				}
+			}
+		case c == '(':
+			depth++
+			s = append(s, c)
+		case c == ')':
+			if depth == 0 {
+				return s, i
+			}
+			depth--
+			s = append(s, c)
+		default:
+			s = append(s, c)
+		}
+	}
+	return s, i
+}
+
+// Decodes a hex string, ignoring white space and treating a missing last
+// digit as 0
+func hexString(data []byte) []byte {
+	digits := make([]byte, 0, len(data)+1)
+	for _, c := range data {
+		if !isSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	decoded := make([]byte, hex.DecodedLen(len(digits)))
+	if _, err := hex.Decode(decoded, digits); err != nil {
+		return nil
+	}
+	return decoded
+}
+
+// Reports whether the operator op starts at i and stands on its own
+func isOperator(content []byte, i int, op string) bool {
+	if !bytes.HasPrefix(content[i:], []byte(op)) {
+		return false
+	}
+	if i > 0 && !isSpace(content[i-1]) {
+		return false
+	}
+	end := i + len(op)
+	return end == len(content) || isSpace(content[end])
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
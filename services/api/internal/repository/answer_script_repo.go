@@ -1,33 +1,140 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/smartik/api/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AnswerScriptRepository struct {
 	db *gorm.DB
 }
 
+// Filters and sort orders available when listing answer scripts
+var answerScriptListSpec = ListSpec{
+	Filters: map[string]string{
+		"status":       "status",
+		"match_status": "match_status",
+		"exam_id":      "exam_id",
+		"subject_id":   "subject_id",
+		"student_id":   "student_id",
+	},
+	Ranges: map[string]string{
+		"created": "created_at",
+		"matched": "matched_at",
+	},
+	Sorts: map[string]string{
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+		"file_name":  "file_name",
+	},
+	DefaultSort: "created_at",
+}
+
 // Creates a new instance of AnswerScriptRepository
 func NewAnswerScriptRepository(db *gorm.DB) *AnswerScriptRepository {
 	return &AnswerScriptRepository{db}
 }
 
+// Returns a copy of the repository that runs its queries in the transaction tx
+func (r *AnswerScriptRepository) WithTx(tx *gorm.DB) *AnswerScriptRepository {
+	return &AnswerScriptRepository{tx}
+}
+
 // Creates a new answer script record in the database
 func (r *AnswerScriptRepository) Create(answerScript *models.AnswerScript) error {
 	return r.db.Create(answerScript).Error
 }
 
-// Retrieves all answer scripts from the database
-func (r *AnswerScriptRepository) GetAll() (*[]models.AnswerScript, error) {
+// Retrieves a page of answer scripts
+func (r *AnswerScriptRepository) GetAll(params ListParams) (*Page[models.AnswerScript], error) {
+	return Paginate[models.AnswerScript](r.db, answerScriptListSpec, params)
+}
+
+// Retrieves all answer scripts whose files are still stored under their original file name
+func (r *AnswerScriptRepository) GetAllWithoutStorageKey() (*[]models.AnswerScript, error) {
+	var answerScripts []models.AnswerScript
+	if err := r.db.Where("storage_key IS NULL OR storage_key = ''").Find(&answerScripts).Error; err != nil {
+		return nil, err
+	}
+	return &answerScripts, nil
+}
+
+// Retrieves all answer scripts of an exam
+func (r *AnswerScriptRepository) GetByExamId(examId string) (*[]models.AnswerScript, error) {
+	var answerScripts []models.AnswerScript
+	if err := r.db.Where("exam_id = ?", examId).Order("created_at").Find(&answerScripts).Error; err != nil {
+		return nil, err
+	}
+	return &answerScripts, nil
+}
+
+// Retrieves all answer scripts linked to a student
+func (r *AnswerScriptRepository) GetByStudentId(studentId string) (*[]models.AnswerScript, error) {
+	var answerScripts []models.AnswerScript
+	if err := r.db.Where("student_id = ?", studentId).Order("created_at").Find(&answerScripts).Error; err != nil {
+		return nil, err
+	}
+	return &answerScripts, nil
+}
+
+// Retrieves all answer scripts of a subject
+func (r *AnswerScriptRepository) GetBySubjectId(subjectId string) (*[]models.AnswerScript, error) {
+	var answerScripts []models.AnswerScript
+	if err := r.db.Where("subject_id = ?", subjectId).Order("created_at").Find(&answerScripts).Error; err != nil {
+		return nil, err
+	}
+	return &answerScripts, nil
+}
+
+// Retrieves one batch of the answer scripts matching the conditions with
+// their students, ordered by exam number. A nil condition value matches NULL.
+func (r *AnswerScriptRepository) GetMarkSheetBatch(conditions map[string]interface{}, offset, limit int) (*[]models.AnswerScript, error) {
 	var answerScripts []models.AnswerScript
-	if err := r.db.Find(&answerScripts).Error; err != nil {
+	if err := r.db.Joins("Student").
+		Where(conditions).
+		Order(`COALESCE("Student"."exam_number", answer_scripts.scanned_exam_number), answer_scripts.id`).
+		Offset(offset).
+		Limit(limit).
+		Find(&answerScripts).Error; err != nil {
 		return nil, err
 	}
 	return &answerScripts, nil
 }
 
+// Counts the answer scripts of an exam that belong to a subject other than the given one
+func (r *AnswerScriptRepository) CountOtherSubjects(examId, subjectId string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.AnswerScript{}).
+		Where("exam_id = ? AND subject_id IS NOT NULL AND subject_id <> ?", examId, subjectId).
+		Count(&count).Error
+	return count, err
+}
+
+// Counts the answer scripts of an exam with the given processing status
+func (r *AnswerScriptRepository) CountByExamAndStatus(examId string, status models.ProcessingStatus) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.AnswerScript{}).
+		Where("exam_id = ? AND status = ?", examId, status).
+		Count(&count).Error
+	return count, err
+}
+
+// Retrieves the distinct exams the answer scripts of a subject belong to.
+// A nil entry stands for scripts without an exam.
+func (r *AnswerScriptRepository) GetExamIdsBySubjectId(subjectId string) ([]*string, error) {
+	var examIds []*string
+	if err := r.db.Model(&models.AnswerScript{}).
+		Where("subject_id = ?", subjectId).
+		Distinct().
+		Pluck("exam_id", &examIds).Error; err != nil {
+		return nil, err
+	}
+	return examIds, nil
+}
+
 // Retrieves a specific answer script by its ID
 func (r *AnswerScriptRepository) GetById(id string) (*models.AnswerScript, error) {
 	var answerScript models.AnswerScript
@@ -37,8 +144,17 @@ func (r *AnswerScriptRepository) GetById(id string) (*models.AnswerScript, error
 	return &answerScript, nil
 }
 
+// Retrieves the answer scripts with any of the given IDs
+func (r *AnswerScriptRepository) GetByIds(ids []string) (*[]models.AnswerScript, error) {
+	var answerScripts []models.AnswerScript
+	if err := r.db.Where("id IN ?", ids).Order("created_at").Find(&answerScripts).Error; err != nil {
+		return nil, err
+	}
+	return &answerScripts, nil
+}
+
 // Updates an existing answer script record
-func (r *AnswerScriptRepository) Update(id string, data *models.AnswerScript) (*models.AnswerScript, error) {
+func (r *AnswerScriptRepository) Update(id string, data *models.UpdateAnswerScript) (*models.AnswerScript, error) {
 	answerScript, err := r.GetById(id)
 	if err != nil {
 		return nil, err
@@ -58,3 +174,119 @@ func (r *AnswerScriptRepository) Delete(id string) error {
 	}
 	return r.db.Delete(answerScript).Error
 }
+
+// Sets the storage key of a single answer script
+func (r *AnswerScriptRepository) SetStorageKey(id, key string) error {
+	return r.db.Model(&models.AnswerScript{}).Where("id = ?", id).Update("storage_key", key).Error
+}
+
+// Deletes the question marks and extracted answers of answer scripts and
+// clears their totals, e.g. once they no longer belong to the exam the
+// memorandum was marked against. Returns what was deleted.
+func (r *AnswerScriptRepository) ClearMarking(ids ...string) (marks []models.QuestionMark, answers []models.ExtractedAnswer, err error) {
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("answer_script_id IN ?", ids).Find(&marks).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("answer_script_id IN ?", ids).Find(&answers).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("answer_script_id IN ?", ids).Delete(&models.QuestionMark{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("answer_script_id IN ?", ids).Delete(&models.ExtractedAnswer{}).Error; err != nil {
+			return err
+		}
+		return recalculateTotals(tx, ids...)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return marks, answers, nil
+}
+
+// Claims up to limit scripts awaiting processing so that no other worker
+// picks them up. Claims older than staleAfter are considered abandoned,
+// e.g. by a worker that crashed, and are handed out again.
+func (r *AnswerScriptRepository) ClaimForProcessing(limit int, staleAfter time.Duration) ([]models.AnswerScript, error) {
+	var answerScripts []models.AnswerScript
+	now := time.Now()
+
+	err := r.db.Raw(`
+		UPDATE answer_scripts SET processing_claimed_at = ?
+		WHERE id IN (
+			SELECT id FROM answer_scripts
+			WHERE status = ? AND (processing_claimed_at IS NULL OR processing_claimed_at < ?)
+			ORDER BY created_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now, models.StatusProcessing, now.Add(-staleAfter), limit,
+	).Scan(&answerScripts).Error
+	if err != nil {
+		return nil, err
+	}
+	return answerScripts, nil
+}
+
+// Updates the given columns of a single answer script
+func (r *AnswerScriptRepository) UpdateFields(id string, fields map[string]interface{}) error {
+	return r.db.Model(&models.AnswerScript{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// Updates the given columns of several answer scripts in a single
+// transaction. The scripts are locked and checked to exist and match the
+// conditions first. When any does not, nothing is changed and their IDs
+// are returned.
+func (r *AnswerScriptRepository) UpdateFieldsOfMany(ids []string, conditions, fields map[string]interface{}) ([]string, error) {
+	var missing []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&models.AnswerScript{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", ids)
+		if len(conditions) > 0 {
+			query = query.Where(conditions)
+		}
+
+		var found []string
+		if err := query.Pluck("id", &found).Error; err != nil {
+			return err
+		}
+
+		if missing = missingIds(ids, found); len(missing) > 0 {
+			return nil
+		}
+		return tx.Model(&models.AnswerScript{}).Where("id IN ?", ids).Updates(fields).Error
+	})
+	return missing, err
+}
+
+// Retrieves processed answer scripts that are not linked to a student, or
+// whose match score is below the threshold, and were not reviewed yet
+func (r *AnswerScriptRepository) GetReviewQueue(threshold float32, params ListParams) (*Page[models.AnswerScript], error) {
+	query := r.db.
+		Where("status <> ?", models.StatusProcessing).
+		Where("match_status NOT IN ?", []models.MatchStatus{models.MatchStatusConfirmed, models.MatchStatusRejected}).
+		Where("student_id IS NULL OR match_score IS NULL OR match_score < ?", threshold)
+	return Paginate[models.AnswerScript](query, answerScriptListSpec, params)
+}
+
+// Returns the IDs that are not among the found ones, in their original order
+func missingIds(ids, found []string) []string {
+	seen := make(map[string]bool, len(found))
+	for _, id := range found {
+		seen[id] = true
+	}
+
+	var missing []string
+	for _, id := range ids {
+		if !seen[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
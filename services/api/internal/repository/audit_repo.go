@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"github.com/smartik/api/internal/models"
+	"gorm.io/gorm"
+)
+
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+// Filters and sort orders available when searching the audit log
+var auditListSpec = ListSpec{
+	Filters: map[string]string{
+		"entity_type": "entity_type",
+		"entity_id":   "entity_id",
+		"actor_id":    "actor_id",
+		"action":      "action",
+	},
+	Ranges:      map[string]string{"created": "created_at"},
+	Sorts:       map[string]string{"created_at": "created_at"},
+	DefaultSort: "-created_at",
+}
+
+// Creates a new instance of AuditRepository
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db}
+}
+
+// Returns a copy of the repository that runs its queries in the transaction tx
+func (r *AuditRepository) WithTx(tx *gorm.DB) *AuditRepository {
+	return &AuditRepository{tx}
+}
+
+// Runs fn in a transaction, committed when fn returns nil
+func (r *AuditRepository) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(fn)
+}
+
+// Appends an entry to the audit log
+func (r *AuditRepository) Create(entry *models.AuditEntry) error {
+	return r.db.Create(entry).Error
+}
+
+// Retrieves a page of audit entries
+func (r *AuditRepository) GetAll(params ListParams) (*Page[models.AuditEntry], error) {
+	return Paginate[models.AuditEntry](r.db, auditListSpec, params)
+}
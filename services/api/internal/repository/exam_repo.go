@@ -9,23 +9,48 @@ type ExamRepository struct {
 	db *gorm.DB
 }
 
+// Filters and sort orders available when listing exams
+var examListSpec = ListSpec{
+	Filters: map[string]string{
+		"subject_id":    "subject_id",
+		"status":        "status",
+		"academic_year": "academic_year",
+		"paper_number":  "paper_number",
+		"total_marks":   "total_marks",
+	},
+	Ranges: map[string]string{
+		"created": "created_at",
+		"date":    "date",
+	},
+	Sorts: map[string]string{
+		"created_at":    "created_at",
+		"updated_at":    "updated_at",
+		"name":          "name",
+		"date":          "date",
+		"academic_year": "academic_year",
+		"total_marks":   "total_marks",
+	},
+	DefaultSort: "created_at",
+}
+
 // Creates a new instance of ExamRepository
 func NewExamRepository(db *gorm.DB) *ExamRepository {
 	return &ExamRepository{db}
 }
 
+// Returns a copy of the repository that runs its queries in the transaction tx
+func (r *ExamRepository) WithTx(tx *gorm.DB) *ExamRepository {
+	return &ExamRepository{tx}
+}
+
 // Creates a new exam record in the database
 func (r *ExamRepository) Create(exam *models.Exam) error {
 	return r.db.Create(exam).Error
 }
 
-// Retrieves all exams from the database
-func (r *ExamRepository) GetAll() (*[]models.Exam, error) {
-	var exams []models.Exam
-	if err := r.db.Find(&exams).Error; err != nil {
-		return nil, err
-	}
-	return &exams, nil
+// Retrieves a page of exams
+func (r *ExamRepository) GetAll(params ListParams) (*Page[models.Exam], error) {
+	return Paginate[models.Exam](r.db, examListSpec, params)
 }
 
 // Retrieves a specific exam by its ID
@@ -38,20 +63,47 @@ func (r *ExamRepository) GetById(id string) (*models.Exam, error) {
 	return &exam, nil
 }
 
-// Updates an existing exam record
+// Updates an existing exam record. When the subject is set, answer scripts
+// of the exam without a subject are given the same one.
 func (r *ExamRepository) Update(id string, data *models.UpdateExam) (*models.Exam, error) {
 	exam, err := r.GetById(id)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := r.db.Model(&exam).Updates(data).Error; err != nil {
+	if err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&exam).Updates(data).Error; err != nil {
+			return err
+		}
+		if data.SubjectId == nil {
+			return nil
+		}
+		return tx.Model(&models.AnswerScript{}).
+			Where("exam_id = ? AND subject_id IS NULL", id).
+			Update("subject_id", *data.SubjectId).Error
+	}); err != nil {
 		return nil, err
 	}
 
 	return exam, nil
 }
 
+// Moves an exam from one of the given statuses to another. Reports false
+// when the exam was no longer in one of them, e.g. after a concurrent change.
+func (r *ExamRepository) UpdateStatus(id string, from []models.ExamStatus, to models.ExamStatus) (bool, error) {
+	result := r.db.Model(&models.Exam{}).
+		Where("id = ? AND status IN ?", id, from).
+		Update("status", to)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Counts the exams of a subject
+func (r *ExamRepository) CountBySubjectId(subjectId string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Exam{}).Where("subject_id = ?", subjectId).Count(&count).Error
+	return count, err
+}
+
 // Deletes an exam from the database
 func (r *ExamRepository) Delete(id string) error {
 	exam, err := r.GetById(id)
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"github.com/smartik/api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ExtractedAnswerRepository struct {
+	db *gorm.DB
+}
+
+// Creates a new instance of ExtractedAnswerRepository
+func NewExtractedAnswerRepository(db *gorm.DB) *ExtractedAnswerRepository {
+	return &ExtractedAnswerRepository{db}
+}
+
+// Returns a copy of the repository that runs its queries in the transaction tx
+func (r *ExtractedAnswerRepository) WithTx(tx *gorm.DB) *ExtractedAnswerRepository {
+	return &ExtractedAnswerRepository{tx}
+}
+
+// Retrieves the answers extracted from an answer script
+func (r *ExtractedAnswerRepository) GetByAnswerScriptId(answerScriptId string) (*[]models.ExtractedAnswer, error) {
+	var answers []models.ExtractedAnswer
+	if err := r.db.Where("answer_script_id = ?", answerScriptId).
+		Order("created_at").
+		Find(&answers).Error; err != nil {
+		return nil, err
+	}
+	return &answers, nil
+}
+
+// Inserts or replaces answers extracted from an answer script
+func (r *ExtractedAnswerRepository) Save(answers []models.ExtractedAnswer) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "answer_script_id"}, {Name: "question_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"text", "updated_at"}),
+	}).Create(&answers).Error
+}
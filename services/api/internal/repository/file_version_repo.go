@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"github.com/smartik/api/internal/models"
+	"gorm.io/gorm"
+)
+
+type FileVersionRepository struct {
+	db *gorm.DB
+}
+
+// Creates a new instance of FileVersionRepository
+func NewFileVersionRepository(db *gorm.DB) *FileVersionRepository {
+	return &FileVersionRepository{db}
+}
+
+// Returns a copy of the repository that runs its queries in the transaction tx
+func (r *FileVersionRepository) WithTx(tx *gorm.DB) *FileVersionRepository {
+	return &FileVersionRepository{tx}
+}
+
+// Retrieves the recorded file versions of a record, newest first
+func (r *FileVersionRepository) GetByRecord(recordType models.FileRecordType, recordId string) (*[]models.FileVersion, error) {
+	var versions []models.FileVersion
+	if err := r.db.Where("record_type = ? AND record_id = ?", recordType, recordId).
+		Order("version DESC").
+		Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return &versions, nil
+}
+
+// Retrieves a single file version of a record
+func (r *FileVersionRepository) GetVersion(recordType models.FileRecordType, recordId string, version int) (*models.FileVersion, error) {
+	var fileVersion models.FileVersion
+	if err := r.db.Where("record_type = ? AND record_id = ? AND version = ?", recordType, recordId, version).
+		First(&fileVersion).Error; err != nil {
+		return nil, err
+	}
+	return &fileVersion, nil
+}
+
+// Records new file versions and moves the record to the newest of them in
+// a single transaction. model is an empty value of the record's model.
+// Reports false, and changes nothing, when the record is no longer at the
+// expected version, e.g. after a concurrent replacement.
+func (r *FileVersionRepository) Replace(model interface{}, recordId string, from int, fields map[string]interface{}, versions []models.FileVersion) (bool, error) {
+	replaced := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(model).
+			Where("id = ? AND version = ?", recordId, from).
+			Updates(fields)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		if err := tx.Create(&versions).Error; err != nil {
+			return err
+		}
+		replaced = true
+		return nil
+	})
+	return replaced, err
+}
+
+// Deletes the recorded file versions of a record
+func (r *FileVersionRepository) DeleteByRecord(recordType models.FileRecordType, recordId string) error {
+	return r.db.Where("record_type = ? AND record_id = ?", recordType, recordId).
+		Delete(&models.FileVersion{}).Error
+}
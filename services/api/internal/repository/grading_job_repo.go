@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/smartik/api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GradingJobRepository struct {
+	db *gorm.DB
+}
+
+// Creates a new instance of GradingJobRepository
+func NewGradingJobRepository(db *gorm.DB) *GradingJobRepository {
+	return &GradingJobRepository{db}
+}
+
+// Creates a new grading job record in the database unless the exam already
+// has a job that has not finished. Reports false when it does, as enforced
+// by the idx_grading_jobs_active_exam index.
+func (r *GradingJobRepository) CreateIfIdle(job *models.GradingJob) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(job)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Retrieves a specific grading job by its ID
+func (r *GradingJobRepository) GetById(id string) (*models.GradingJob, error) {
+	var job models.GradingJob
+	if err := r.db.Where("id = ?", id).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Retrieves the grading jobs of an exam, newest first
+func (r *GradingJobRepository) GetByExamId(examId string) (*[]models.GradingJob, error) {
+	var jobs []models.GradingJob
+	if err := r.db.Where("exam_id = ?", examId).Order("created_at DESC").Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return &jobs, nil
+}
+
+// Reports whether the exam has a grading job that has not finished yet
+func (r *GradingJobRepository) HasActiveJob(examId string) (bool, error) {
+	var count int64
+	if err := r.db.Model(&models.GradingJob{}).
+		Where("exam_id = ? AND status IN ?", examId, []models.GradingJobStatus{models.GradingJobQueued, models.GradingJobRunning}).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Updates the given columns of a grading job while it has the given
+// status. Reports false when it no longer has, e.g. because the job was
+// failed as abandoned in the meantime.
+func (r *GradingJobRepository) UpdateFieldsIfStatus(id string, status models.GradingJobStatus, fields map[string]interface{}) (bool, error) {
+	result := r.db.Model(&models.GradingJob{}).Where("id = ? AND status = ?", id, status).Updates(fields)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Fails the grading jobs that are still queued or running but whose
+// heartbeat, or start for jobs without one, is older than staleBefore
+func (r *GradingJobRepository) FailStale(staleBefore time.Time, reason string) (int64, error) {
+	result := r.db.Model(&models.GradingJob{}).
+		Where("status IN ?", []models.GradingJobStatus{models.GradingJobQueued, models.GradingJobRunning}).
+		Where("COALESCE(heartbeat_at, started_at, created_at) < ?", staleBefore).
+		Updates(map[string]interface{}{
+			"status":      models.GradingJobFailed,
+			"error":       reason,
+			"finished_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
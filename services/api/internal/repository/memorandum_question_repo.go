@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/smartik/api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MemorandumQuestionRepository struct {
+	db *gorm.DB
+}
+
+// Creates a new instance of MemorandumQuestionRepository
+func NewMemorandumQuestionRepository(db *gorm.DB) *MemorandumQuestionRepository {
+	return &MemorandumQuestionRepository{db}
+}
+
+// Returns a copy of the repository that runs its queries in the transaction tx
+func (r *MemorandumQuestionRepository) WithTx(tx *gorm.DB) *MemorandumQuestionRepository {
+	return &MemorandumQuestionRepository{tx}
+}
+
+// Creates a new memorandum question record in the database
+func (r *MemorandumQuestionRepository) Create(question *models.MemorandumQuestion) error {
+	return r.db.Create(question).Error
+}
+
+// Retrieves all questions and sub-questions of a memorandum as a flat list,
+// ordered by their position
+func (r *MemorandumQuestionRepository) GetByMemorandumId(memorandumId string) (*[]models.MemorandumQuestion, error) {
+	var questions []models.MemorandumQuestion
+	if err := r.db.Where("memorandum_id = ?", memorandumId).
+		Order("position, number").
+		Find(&questions).Error; err != nil {
+		return nil, err
+	}
+	return &questions, nil
+}
+
+// Retrieves all questions of every memorandum of an exam as a flat list
+func (r *MemorandumQuestionRepository) GetByExamId(examId string) (*[]models.MemorandumQuestion, error) {
+	var questions []models.MemorandumQuestion
+	if err := r.db.Where("exam_id = ?", examId).
+		Order("position, number").
+		Find(&questions).Error; err != nil {
+		return nil, err
+	}
+	return &questions, nil
+}
+
+// Retrieves a specific question of a memorandum by its ID
+func (r *MemorandumQuestionRepository) GetById(memorandumId, id string) (*models.MemorandumQuestion, error) {
+	var question models.MemorandumQuestion
+	if err := r.db.Where("memorandum_id = ? AND id = ?", memorandumId, id).First(&question).Error; err != nil {
+		return nil, err
+	}
+	return &question, nil
+}
+
+// Saves every column of an existing memorandum question record. A change
+// of its marks is carried over to the marks already awarded for the
+// question and the totals of their answer scripts in the same transaction.
+// Fails with ErrAwardedAboveMaximum, changing nothing, when more marks were
+// awarded for the question than it is now worth.
+func (r *MemorandumQuestionRepository) Save(question *models.MemorandumQuestion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Marks recorded meanwhile wait for the question to be saved
+		var current models.MemorandumQuestion
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", question.Id).
+			First(&current).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("SubQuestions").Save(question).Error; err != nil {
+			return err
+		}
+		if current.Marks == question.Marks {
+			return nil
+		}
+
+		var above int64
+		if err := tx.Model(&models.QuestionMark{}).
+			Where("question_id = ? AND awarded > ?", question.Id, question.Marks).
+			Count(&above).Error; err != nil {
+			return err
+		}
+		if above > 0 {
+			return fmt.Errorf("%w: %d answer scripts were awarded more than %d marks for question %s",
+				ErrAwardedAboveMaximum, above, question.Marks, question.Number)
+		}
+
+		var answerScriptIds []string
+		if err := tx.Model(&models.QuestionMark{}).
+			Where("question_id = ?", question.Id).
+			Pluck("answer_script_id", &answerScriptIds).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.QuestionMark{}).
+			Where("question_id = ?", question.Id).
+			Update("max_marks", question.Marks).Error; err != nil {
+			return err
+		}
+		return recalculateTotals(tx, answerScriptIds...)
+	})
+}
+
+// Deletes a memorandum question, together with its sub-questions and the
+// marks awarded for them, and recalculates the totals of the answer scripts
+// that lost marks in the same transaction
+func (r *MemorandumQuestionRepository) Delete(question *models.MemorandumQuestion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		answerScriptIds, err := scriptsMarkedForMemorandum(tx, question.MemorandumId)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(question).Error; err != nil {
+			return err
+		}
+		return recalculateTotals(tx, answerScriptIds...)
+	})
+}
+
+// Replaces every question of a memorandum with the given question trees
+// in a single transaction. Marks awarded for the replaced questions are
+// deleted with them and the totals of their answer scripts recalculated.
+func (r *MemorandumQuestionRepository) ReplaceAll(memorandumId string, questions []models.MemorandumQuestion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		answerScriptIds, err := scriptsMarkedForMemorandum(tx, memorandumId)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("memorandum_id = ?", memorandumId).Delete(&models.MemorandumQuestion{}).Error; err != nil {
+			return err
+		}
+		if len(questions) > 0 {
+			// Sub-questions are created through the SubQuestions association
+			if err := tx.Create(&questions).Error; err != nil {
+				return err
+			}
+		}
+		return recalculateTotals(tx, answerScriptIds...)
+	})
+}
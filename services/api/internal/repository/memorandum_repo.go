@@ -9,20 +9,44 @@ type MemorandumRepository struct {
 	db *gorm.DB
 }
 
+// Filters and sort orders available when listing memorandums
+var memorandumListSpec = ListSpec{
+	Filters: map[string]string{
+		"exam_id": "exam_id",
+	},
+	Ranges: map[string]string{"created": "created_at"},
+	Sorts: map[string]string{
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+		"file_name":  "file_name",
+	},
+	DefaultSort: "created_at",
+}
+
 // Creates a new instance of MemorandumRepository
 func NewMemorandumRepository(db *gorm.DB) *MemorandumRepository {
 	return &MemorandumRepository{db}
 }
 
+// Returns a copy of the repository that runs its queries in the transaction tx
+func (r *MemorandumRepository) WithTx(tx *gorm.DB) *MemorandumRepository {
+	return &MemorandumRepository{tx}
+}
+
 // Creates a new memorandum record in the database
 func (r *MemorandumRepository) Create(memorandum *models.Memorandum) error {
 	return r.db.Create(memorandum).Error
 }
 
-// Retrieves all memorandums from the database
-func (r *MemorandumRepository) GetAll() (*[]models.Memorandum, error) {
+// Retrieves a page of memorandums
+func (r *MemorandumRepository) GetAll(params ListParams) (*Page[models.Memorandum], error) {
+	return Paginate[models.Memorandum](r.db, memorandumListSpec, params)
+}
+
+// Retrieves all memorandums whose files are still stored under their original file name
+func (r *MemorandumRepository) GetAllWithoutStorageKey() (*[]models.Memorandum, error) {
 	var memorandums []models.Memorandum
-	if err := r.db.Find(&memorandums).Error; err != nil {
+	if err := r.db.Where("storage_key IS NULL OR storage_key = ''").Find(&memorandums).Error; err != nil {
 		return nil, err
 	}
 	return &memorandums, nil
@@ -50,11 +74,27 @@ func (r *MemorandumRepository) Update(id string, data *models.Memorandum) (*mode
 	return memorandum, nil
 }
 
-// Deletes a memorandum from the database
+// Deletes a memorandum from the database together with its questions and
+// their marks, and recalculates the totals of the answer scripts that lost
+// marks in the same transaction
 func (r *MemorandumRepository) Delete(id string) error {
 	memorandum, err := r.GetById(id)
 	if err != nil {
 		return err
 	}
-	return r.db.Delete(memorandum).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		answerScriptIds, err := scriptsMarkedForMemorandum(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(memorandum).Error; err != nil {
+			return err
+		}
+		return recalculateTotals(tx, answerScriptIds...)
+	})
+}
+
+// Sets the storage key of a single memorandum
+func (r *MemorandumRepository) SetStorageKey(id, key string) error {
+	return r.db.Model(&models.Memorandum{}).Where("id = ?", id).Update("storage_key", key).Error
 }
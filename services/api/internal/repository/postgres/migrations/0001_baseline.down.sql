@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "memorandums";
+DROP TABLE IF EXISTS "answer_scripts";
+DROP TABLE IF EXISTS "exams";
+DROP TABLE IF EXISTS "subjects";
+DROP TABLE IF EXISTS "students";
@@ -0,0 +1,66 @@
+-- The schema GORM's AutoMigrate created before migrations were introduced.
+-- Tables and indexes that already exist are kept, so databases set up by
+-- earlier releases can be adopted by running this migration. Everything
+-- added since has a migration of its own.
+
+CREATE TABLE IF NOT EXISTS "students" (
+    "id" varchar(25),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "first_name" varchar(50),
+    "last_name" varchar(50),
+    "exam_number" varchar(20) NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_students_exam_number" ON "students" ("exam_number");
+
+CREATE TABLE IF NOT EXISTS "subjects" (
+    "id" varchar(25),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "name" varchar(100) NOT NULL,
+    "code" varchar(10) NOT NULL,
+    "description" text,
+    PRIMARY KEY ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "exams" (
+    "id" varchar(25),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "date" timestamptz NOT NULL,
+    "total_marks" bigint NOT NULL DEFAULT 1,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_exam_date" ON "exams" ("date");
+
+CREATE TABLE IF NOT EXISTS "answer_scripts" (
+    "id" varchar(25),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "file_name" varchar(255) NOT NULL,
+    "file_url" text,
+    "student_id" varchar(25),
+    "subject_id" varchar(25),
+    "exam_id" varchar(25),
+    "total_marks" bigint DEFAULT NULL,
+    "max_marks" bigint DEFAULT NULL,
+    "scanned_exam_number" varchar(20),
+    "status" varchar(20) DEFAULT 'processing',
+    "matched_at" timestamp DEFAULT NULL,
+    "matching_confidence" decimal,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_exams_answer_scripts" FOREIGN KEY ("exam_id") REFERENCES "exams"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_students_answer_scripts" FOREIGN KEY ("student_id") REFERENCES "students"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_subjects_answer_scripts" FOREIGN KEY ("subject_id") REFERENCES "subjects"("id") ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS "memorandums" (
+    "id" varchar(25),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "file_name" varchar(255) NOT NULL,
+    "exam_id" varchar(25) NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_memorandums_exam" FOREIGN KEY ("exam_id") REFERENCES "exams"("id") ON DELETE CASCADE
+);
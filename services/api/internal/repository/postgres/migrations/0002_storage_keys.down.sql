@@ -0,0 +1,2 @@
+ALTER TABLE "memorandums" DROP COLUMN IF EXISTS "storage_key";
+ALTER TABLE "answer_scripts" DROP COLUMN IF EXISTS "storage_key";
@@ -0,0 +1,7 @@
+-- Files are stored under keys derived from the record Id. Records uploaded
+-- before have no key, their file is stored under the file name.
+ALTER TABLE "answer_scripts" ADD COLUMN IF NOT EXISTS "storage_key" varchar(512);
+CREATE INDEX IF NOT EXISTS "idx_answer_scripts_storage_key" ON "answer_scripts" ("storage_key");
+
+ALTER TABLE "memorandums" ADD COLUMN IF NOT EXISTS "storage_key" varchar(512);
+CREATE INDEX IF NOT EXISTS "idx_memorandums_storage_key" ON "memorandums" ("storage_key");
@@ -0,0 +1,2 @@
+ALTER TABLE "answer_scripts" DROP COLUMN IF EXISTS "processing_claimed_at";
+ALTER TABLE "answer_scripts" DROP COLUMN IF EXISTS "processing_error";
@@ -0,0 +1,5 @@
+-- Workers claim uploaded scripts to read their exam number and record why
+-- processing failed
+ALTER TABLE "answer_scripts" ADD COLUMN IF NOT EXISTS "processing_error" text;
+ALTER TABLE "answer_scripts" ADD COLUMN IF NOT EXISTS "processing_claimed_at" timestamp;
+CREATE INDEX IF NOT EXISTS "idx_answer_scripts_processing_claimed_at" ON "answer_scripts" ("processing_claimed_at");
@@ -0,0 +1,3 @@
+ALTER TABLE "answer_scripts" DROP COLUMN IF EXISTS "reviewed_at";
+ALTER TABLE "answer_scripts" DROP COLUMN IF EXISTS "reviewed_by";
+ALTER TABLE "answer_scripts" DROP COLUMN IF EXISTS "match_status";
@@ -0,0 +1,9 @@
+-- How a script was linked to its student, and who reviewed the match
+ALTER TABLE "answer_scripts" ADD COLUMN IF NOT EXISTS "match_status" varchar(20) NOT NULL DEFAULT 'unmatched';
+ALTER TABLE "answer_scripts" ADD COLUMN IF NOT EXISTS "reviewed_by" varchar(100);
+ALTER TABLE "answer_scripts" ADD COLUMN IF NOT EXISTS "reviewed_at" timestamp;
+CREATE INDEX IF NOT EXISTS "idx_answer_scripts_match_status" ON "answer_scripts" ("match_status");
+
+-- Scripts were only linked to students by hand before matching existed
+UPDATE "answer_scripts" SET "match_status" = 'confirmed'
+WHERE "student_id" IS NOT NULL AND "match_status" = 'unmatched';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "grading_jobs";
+DROP TABLE IF EXISTS "extracted_answers";
+DROP TABLE IF EXISTS "question_marks";
+DROP TABLE IF EXISTS "memorandum_questions";
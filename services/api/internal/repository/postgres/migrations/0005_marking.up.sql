@@ -0,0 +1,79 @@
+-- The questions of memoranda, the marks awarded for them and the grading
+-- of exams against them
+
+CREATE TABLE IF NOT EXISTS "memorandum_questions" (
+    "id" varchar(25),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "memorandum_id" varchar(25) NOT NULL,
+    "exam_id" varchar(25) NOT NULL,
+    "parent_id" varchar(25),
+    "number" varchar(20) NOT NULL,
+    "position" bigint NOT NULL DEFAULT 0,
+    "prompt" text,
+    "expected_answer" text,
+    "alternative_answers" jsonb,
+    "marks" bigint NOT NULL,
+    "match_mode" varchar(20) NOT NULL DEFAULT 'exact',
+    "keywords" jsonb,
+    "tolerance" double precision NOT NULL DEFAULT 0,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_memorandum_questions_memorandum" FOREIGN KEY ("memorandum_id") REFERENCES "memorandums"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_memorandum_questions_exam" FOREIGN KEY ("exam_id") REFERENCES "exams"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_memorandum_questions_sub_questions" FOREIGN KEY ("parent_id") REFERENCES "memorandum_questions"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_memorandum_questions_parent_id" ON "memorandum_questions" ("parent_id");
+CREATE INDEX IF NOT EXISTS "idx_memorandum_questions_exam_id" ON "memorandum_questions" ("exam_id");
+CREATE INDEX IF NOT EXISTS "idx_memorandum_questions_memorandum_id" ON "memorandum_questions" ("memorandum_id");
+
+CREATE TABLE IF NOT EXISTS "question_marks" (
+    "id" varchar(25),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "answer_script_id" varchar(25) NOT NULL,
+    "question_id" varchar(25) NOT NULL,
+    "awarded" bigint NOT NULL,
+    "max_marks" bigint NOT NULL,
+    "marked_by" varchar(100) NOT NULL,
+    "comment" text,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_question_marks_answer_script" FOREIGN KEY ("answer_script_id") REFERENCES "answer_scripts"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_question_marks_question" FOREIGN KEY ("question_id") REFERENCES "memorandum_questions"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_question_marks_question_id" ON "question_marks" ("question_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_question_mark_script_question" ON "question_marks" ("answer_script_id", "question_id");
+
+CREATE TABLE IF NOT EXISTS "extracted_answers" (
+    "id" varchar(25),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "answer_script_id" varchar(25) NOT NULL,
+    "question_id" varchar(25) NOT NULL,
+    "text" text,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_extracted_answers_answer_script" FOREIGN KEY ("answer_script_id") REFERENCES "answer_scripts"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_extracted_answers_question" FOREIGN KEY ("question_id") REFERENCES "memorandum_questions"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_extracted_answers_question_id" ON "extracted_answers" ("question_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_extracted_answer_script_question" ON "extracted_answers" ("answer_script_id", "question_id");
+
+CREATE TABLE IF NOT EXISTS "grading_jobs" (
+    "id" varchar(25),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "exam_id" varchar(25) NOT NULL,
+    "grader" varchar(50) NOT NULL,
+    "overwrite" boolean NOT NULL DEFAULT false,
+    "status" varchar(20) NOT NULL DEFAULT 'queued',
+    "total_scripts" bigint NOT NULL DEFAULT 0,
+    "graded_scripts" bigint NOT NULL DEFAULT 0,
+    "skipped_scripts" bigint NOT NULL DEFAULT 0,
+    "failed_scripts" bigint NOT NULL DEFAULT 0,
+    "error" text,
+    "started_at" timestamp,
+    "finished_at" timestamp,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_grading_jobs_exam" FOREIGN KEY ("exam_id") REFERENCES "exams"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_grading_jobs_status" ON "grading_jobs" ("status");
+CREATE INDEX IF NOT EXISTS "idx_grading_jobs_exam_id" ON "grading_jobs" ("exam_id");
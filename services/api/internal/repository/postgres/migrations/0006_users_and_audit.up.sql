@@ -0,0 +1,47 @@
+-- Signed in users, their sessions and the audit log of their changes
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" varchar(25),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "email" varchar(255) NOT NULL,
+    "name" varchar(100) NOT NULL,
+    "password_hash" varchar(100) NOT NULL,
+    "role" varchar(20) NOT NULL DEFAULT 'viewer',
+    "active" boolean NOT NULL DEFAULT true,
+    "last_login_at" timestamp,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_email" ON "users" ("email");
+
+CREATE TABLE IF NOT EXISTS "sessions" (
+    "id" varchar(25),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "user_id" varchar(25) NOT NULL,
+    "token_hash" varchar(64) NOT NULL,
+    "expires_at" timestamp NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_sessions_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_sessions_expires_at" ON "sessions" ("expires_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_sessions_token_hash" ON "sessions" ("token_hash");
+CREATE INDEX IF NOT EXISTS "idx_sessions_user_id" ON "sessions" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "audit_entries" (
+    "id" varchar(25),
+    "created_at" timestamptz,
+    "actor_id" varchar(25),
+    "actor" varchar(255) NOT NULL,
+    "action" varchar(30) NOT NULL,
+    "entity_type" varchar(50) NOT NULL,
+    "entity_id" varchar(25) NOT NULL,
+    "before" jsonb,
+    "after" jsonb,
+    "changes" jsonb,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_audit_entity" ON "audit_entries" ("entity_type", "entity_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_entries_action" ON "audit_entries" ("action");
+CREATE INDEX IF NOT EXISTS "idx_audit_entries_actor_id" ON "audit_entries" ("actor_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_entries_created_at" ON "audit_entries" ("created_at");
@@ -0,0 +1,6 @@
+ALTER TABLE "exams" DROP COLUMN IF EXISTS "status";
+ALTER TABLE "exams" DROP COLUMN IF EXISTS "duration_minutes";
+ALTER TABLE "exams" DROP COLUMN IF EXISTS "academic_year";
+ALTER TABLE "exams" DROP COLUMN IF EXISTS "paper_number";
+ALTER TABLE "exams" DROP COLUMN IF EXISTS "name";
+ALTER TABLE "exams" DROP COLUMN IF EXISTS "subject_id";
@@ -0,0 +1,14 @@
+-- Exams belong to a subject and describe the paper. Exams created before
+-- are left without a subject and count their academic year from the date.
+ALTER TABLE "exams" ADD COLUMN IF NOT EXISTS "subject_id" varchar(25)
+    CONSTRAINT "fk_exams_subject" REFERENCES "subjects"("id") ON DELETE CASCADE;
+ALTER TABLE "exams" ADD COLUMN IF NOT EXISTS "name" varchar(150) NOT NULL DEFAULT '';
+ALTER TABLE "exams" ADD COLUMN IF NOT EXISTS "paper_number" bigint NOT NULL DEFAULT 1;
+ALTER TABLE "exams" ADD COLUMN IF NOT EXISTS "academic_year" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "exams" ADD COLUMN IF NOT EXISTS "duration_minutes" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "exams" ADD COLUMN IF NOT EXISTS "status" varchar(20) NOT NULL DEFAULT 'scheduled';
+CREATE INDEX IF NOT EXISTS "idx_exams_subject_id" ON "exams" ("subject_id");
+CREATE INDEX IF NOT EXISTS "idx_exams_academic_year" ON "exams" ("academic_year");
+CREATE INDEX IF NOT EXISTS "idx_exams_status" ON "exams" ("status");
+
+UPDATE "exams" SET "academic_year" = EXTRACT(YEAR FROM "date") WHERE "academic_year" = 0;
@@ -0,0 +1,3 @@
+-- Renamed statuses cannot be told apart from exams that were created
+-- with them, so only the default is restored
+ALTER TABLE "exams" ALTER COLUMN "status" SET DEFAULT 'scheduled';
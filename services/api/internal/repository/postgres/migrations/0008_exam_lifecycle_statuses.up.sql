@@ -0,0 +1,7 @@
+-- Exams created while the lifecycle used the statuses scheduled, written
+-- and moderated are moved to their closest equivalent
+ALTER TABLE "exams" ALTER COLUMN "status" SET DEFAULT 'draft';
+
+UPDATE "exams" SET "status" = 'draft' WHERE "status" = 'scheduled';
+UPDATE "exams" SET "status" = 'open' WHERE "status" = 'written';
+UPDATE "exams" SET "status" = 'moderation' WHERE "status" = 'moderated';
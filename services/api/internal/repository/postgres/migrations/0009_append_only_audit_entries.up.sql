@@ -0,0 +1,15 @@
+-- The audit log is append-only. The API refuses to change entries, this
+-- also stops anyone with direct access to the database.
+CREATE OR REPLACE FUNCTION "reject_audit_entry_change"() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit entries cannot be changed or deleted';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "audit_entries_append_only"
+    BEFORE UPDATE OR DELETE ON "audit_entries"
+    FOR EACH ROW EXECUTE FUNCTION "reject_audit_entry_change"();
+
+CREATE TRIGGER "audit_entries_no_truncate"
+    BEFORE TRUNCATE ON "audit_entries"
+    FOR EACH STATEMENT EXECUTE FUNCTION "reject_audit_entry_change"();
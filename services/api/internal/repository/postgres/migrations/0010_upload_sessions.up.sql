@@ -0,0 +1,35 @@
+-- Resumable uploads of large files, sent in numbered parts
+CREATE TABLE "upload_sessions" (
+    "id" varchar(25),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "user_id" varchar(25) NOT NULL,
+    "exam_id" varchar(25),
+    "file_name" varchar(255) NOT NULL,
+    "content_type" varchar(255) NOT NULL,
+    "size" bigint NOT NULL,
+    "part_size" bigint NOT NULL,
+    "status" varchar(20) NOT NULL DEFAULT 'active',
+    "answer_script_id" varchar(25),
+    "expires_at" timestamp NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_upload_sessions_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_upload_sessions_exam" FOREIGN KEY ("exam_id") REFERENCES "exams"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_upload_sessions_answer_script" FOREIGN KEY ("answer_script_id") REFERENCES "answer_scripts"("id") ON DELETE SET NULL
+);
+CREATE INDEX "idx_upload_sessions_expires_at" ON "upload_sessions" ("expires_at");
+CREATE INDEX "idx_upload_sessions_exam_id" ON "upload_sessions" ("exam_id");
+CREATE INDEX "idx_upload_sessions_user_id" ON "upload_sessions" ("user_id");
+
+CREATE TABLE "upload_parts" (
+    "id" varchar(25),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "session_id" varchar(25) NOT NULL,
+    "number" bigint NOT NULL,
+    "size" bigint NOT NULL,
+    "checksum" varchar(32) NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_upload_sessions_parts" FOREIGN KEY ("session_id") REFERENCES "upload_sessions"("id") ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX "idx_upload_part_session_number" ON "upload_parts" ("session_id", "number");
@@ -0,0 +1,4 @@
+-- Files of earlier versions are left in storage
+ALTER TABLE "memorandums" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "answer_scripts" DROP COLUMN IF EXISTS "version";
+DROP TABLE IF EXISTS "file_versions";
@@ -0,0 +1,20 @@
+-- Files that are or were behind answer scripts and memoranda. Replacing a
+-- file keeps the previous one, the records point at their current version.
+CREATE TABLE "file_versions" (
+    "id" varchar(25),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "record_type" varchar(20) NOT NULL,
+    "record_id" varchar(25) NOT NULL,
+    "version" bigint NOT NULL,
+    "file_name" varchar(255) NOT NULL,
+    "storage_key" varchar(512) NOT NULL,
+    "content_type" varchar(255) NOT NULL,
+    "size" bigint NOT NULL,
+    "uploaded_by" varchar(25),
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_file_version_record" ON "file_versions" ("record_type", "record_id", "version");
+
+ALTER TABLE "answer_scripts" ADD COLUMN "version" bigint NOT NULL DEFAULT 1;
+ALTER TABLE "memorandums" ADD COLUMN "version" bigint NOT NULL DEFAULT 1;
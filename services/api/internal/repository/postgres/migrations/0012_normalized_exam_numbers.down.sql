@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "idx_students_normalized_exam_number";
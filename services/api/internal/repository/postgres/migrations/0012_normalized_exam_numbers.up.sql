@@ -0,0 +1,4 @@
+-- Matching compares exam numbers without case or separators, e.g. a
+-- scanned JOH5196 matches a student registered as joh-5196
+CREATE INDEX IF NOT EXISTS "idx_students_normalized_exam_number"
+    ON "students" ((upper(regexp_replace("exam_number", '[^[:alnum:]]', '', 'g'))));
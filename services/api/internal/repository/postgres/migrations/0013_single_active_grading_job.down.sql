@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "idx_grading_jobs_active_exam";
@@ -0,0 +1,16 @@
+-- Only the newest of several unfinished jobs of an exam is kept
+UPDATE "grading_jobs" SET
+    "status" = 'failed',
+    "error" = 'another grading job of the exam was started',
+    "finished_at" = now()
+WHERE "status" IN ('queued', 'running')
+  AND EXISTS (
+    SELECT 1 FROM "grading_jobs" AS "newer"
+    WHERE "newer"."exam_id" = "grading_jobs"."exam_id"
+      AND "newer"."status" IN ('queued', 'running')
+      AND ("newer"."created_at", "newer"."id") > ("grading_jobs"."created_at", "grading_jobs"."id")
+  );
+
+-- An exam is graded by one job at a time
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_grading_jobs_active_exam"
+    ON "grading_jobs" ("exam_id") WHERE "status" IN ('queued', 'running');
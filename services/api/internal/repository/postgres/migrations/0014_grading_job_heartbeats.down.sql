@@ -0,0 +1 @@
+ALTER TABLE "grading_jobs" DROP COLUMN IF EXISTS "heartbeat_at";
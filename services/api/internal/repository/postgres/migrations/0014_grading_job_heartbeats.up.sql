@@ -0,0 +1,3 @@
+-- Running jobs refresh their heartbeat, jobs whose heartbeat stopped belong
+-- to a process that is gone
+ALTER TABLE "grading_jobs" ADD COLUMN IF NOT EXISTS "heartbeat_at" timestamp;
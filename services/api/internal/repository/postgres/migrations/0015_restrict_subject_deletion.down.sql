@@ -0,0 +1,3 @@
+ALTER TABLE "exams" DROP CONSTRAINT IF EXISTS "fk_exams_subject";
+ALTER TABLE "exams" ADD CONSTRAINT "fk_exams_subject"
+    FOREIGN KEY ("subject_id") REFERENCES "subjects"("id") ON DELETE CASCADE;
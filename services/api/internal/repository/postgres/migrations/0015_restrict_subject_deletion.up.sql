@@ -0,0 +1,5 @@
+-- Deleting a subject no longer takes its exams, and their scripts and
+-- marks, along. Its exams have to be deleted first.
+ALTER TABLE "exams" DROP CONSTRAINT IF EXISTS "fk_exams_subject";
+ALTER TABLE "exams" ADD CONSTRAINT "fk_exams_subject"
+    FOREIGN KEY ("subject_id") REFERENCES "subjects"("id") ON DELETE RESTRICT;
@@ -0,0 +1 @@
+ALTER TABLE "upload_parts" DROP COLUMN IF EXISTS "storage_key";
@@ -0,0 +1,4 @@
+-- Every time a part is sent it is stored under a key of its own, so a part
+-- sent again never overwrites content that is being joined. Parts stored
+-- before are kept under a key derived from their number.
+ALTER TABLE "upload_parts" ADD COLUMN IF NOT EXISTS "storage_key" varchar(512) NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE "answer_scripts" DROP COLUMN IF EXISTS "match_score";
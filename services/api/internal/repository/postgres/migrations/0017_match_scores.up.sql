@@ -0,0 +1,7 @@
+-- matching_confidence keeps the confidence of the OCR, how well the scanned
+-- exam number matched a student is stored on its own
+ALTER TABLE "answer_scripts" ADD COLUMN IF NOT EXISTS "match_score" float;
+
+-- Matching used to overwrite matching_confidence with the match score
+UPDATE "answer_scripts" SET "match_score" = "matching_confidence"
+WHERE "match_score" IS NULL AND "match_status" IN ('auto_matched', 'needs_review');
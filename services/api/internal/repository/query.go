@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+)
+
+// Returned when list parameters name unknown fields or are inconsistent
+var ErrInvalidListParams = errors.New("invalid list parameters")
+
+// Describes how a list endpoint can be filtered and sorted. Only the
+// columns listed here are ever put into a query.
+type ListSpec struct {
+	Filters     map[string]string // Query parameter to column
+	Ranges      map[string]string // Prefix of the <prefix>_from and <prefix>_to parameters to a time column
+	Sorts       map[string]string // Sort key to column, the columns must not be nullable
+	DefaultSort string            // Sort key, prefixed with "-" for descending order
+}
+
+// A request for one page of a list. Either Offset or Cursor can be used,
+// cursors are returned with each page and stay stable while rows are added.
+type ListParams struct {
+	Limit   int
+	Offset  int
+	Cursor  string
+	Sort    string            // Sort key, prefixed with "-" for descending order
+	Filters map[string]string // Filter and range parameters. Comma separated filter values match any of them.
+}
+
+// One page of a list
+type Page[T any] struct {
+	Items      []T        `json:"items"`
+	Pagination Pagination `json:"pagination"`
+}
+
+type Pagination struct {
+	Total      int64  `json:"total"` // Number of rows matching the filters
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Sort       string `json:"sort"`
+	NextCursor string `json:"next_cursor,omitempty"` // Not set on the last page
+}
+
+// Position of the last row of a page, encoded into an opaque cursor
+type cursor struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v"`
+	Id    string      `json:"id"`
+}
+
+// Retrieves one page of rows of T, applying the filters and sort order
+// allowed by spec. query may carry preloads or conditions of its own.
+func Paginate[T any](query *gorm.DB, spec ListSpec, params ListParams) (*Page[T], error) {
+	sortKey := params.Sort
+	if sortKey == "" {
+		sortKey = spec.DefaultSort
+	}
+	descending := strings.HasPrefix(sortKey, "-")
+	column, ok := spec.Sorts[strings.TrimPrefix(sortKey, "-")]
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot sort by %q", ErrInvalidListParams, strings.TrimPrefix(sortKey, "-"))
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		return nil, fmt.Errorf("%w: limit cannot be more than %d", ErrInvalidListParams, MaxPageLimit)
+	}
+	if params.Offset < 0 {
+		return nil, fmt.Errorf("%w: offset cannot be negative", ErrInvalidListParams)
+	}
+	if params.Cursor != "" && params.Offset > 0 {
+		return nil, fmt.Errorf("%w: use either offset or cursor", ErrInvalidListParams)
+	}
+
+	var model T
+	query = query.Model(&model)
+	for name, value := range params.Filters {
+		condition, err := filterCondition(spec, name, value)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(condition)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	direction, comparison := "ASC", ">"
+	if descending {
+		direction, comparison = "DESC", "<"
+	}
+	if params.Cursor != "" {
+		after, err := decodeCursor(params.Cursor)
+		if err != nil || after.Sort != sortKey {
+			return nil, fmt.Errorf("%w: the cursor is invalid or was created for another sort order", ErrInvalidListParams)
+		}
+		query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", quoteColumn(column), comparison), after.Value, after.Id)
+	}
+
+	// Ordering by id as well keeps pages stable when sort values repeat
+	items := []T{}
+	if err := query.
+		Order(fmt.Sprintf("%s %s, id %s", quoteColumn(column), direction, direction)).
+		Offset(params.Offset).
+		Limit(limit + 1).
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	page := &Page[T]{
+		Items: items,
+		Pagination: Pagination{
+			Total:  total,
+			Limit:  limit,
+			Offset: params.Offset,
+			Sort:   sortKey,
+		},
+	}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		next, err := encodeCursor(query, sortKey, column, &page.Items[limit-1])
+		if err != nil {
+			return nil, err
+		}
+		page.Pagination.NextCursor = next
+	}
+	return page, nil
+}
+
+// Builds the condition of a single filter or range parameter
+func filterCondition(spec ListSpec, name, value string) (clause.Expression, error) {
+	if column, ok := spec.Filters[name]; ok {
+		if values := strings.Split(value, ","); len(values) > 1 {
+			return clause.IN{Column: clause.Column{Name: column}, Values: toInterfaces(values)}, nil
+		}
+		return clause.Eq{Column: clause.Column{Name: column}, Value: value}, nil
+	}
+
+	for prefix, column := range spec.Ranges {
+		switch name {
+		case prefix + "_from":
+			from, _, err := parseRangeTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s must be a date or an RFC 3339 timestamp", ErrInvalidListParams, name)
+			}
+			return clause.Gte{Column: clause.Column{Name: column}, Value: from}, nil
+		case prefix + "_to":
+			to, dateOnly, err := parseRangeTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s must be a date or an RFC 3339 timestamp", ErrInvalidListParams, name)
+			}
+			// A date includes the whole day
+			if dateOnly {
+				to = to.AddDate(0, 0, 1)
+			}
+			return clause.Lt{Column: clause.Column{Name: column}, Value: to}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: cannot filter by %q", ErrInvalidListParams, name)
+}
+
+// Parses a timestamp or a date, reporting which of the two it was
+func parseRangeTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	return t, true, err
+}
+
+func encodeCursor(db *gorm.DB, sortKey, column string, last interface{}) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(last); err != nil {
+		return "", err
+	}
+	value := reflect.ValueOf(last)
+	sortField := stmt.Schema.LookUpField(column)
+	idField := stmt.Schema.LookUpField("id")
+	if sortField == nil || idField == nil {
+		return "", fmt.Errorf("cannot build a cursor on %s", column)
+	}
+
+	sortValue, _ := sortField.ValueOf(db.Statement.Context, value)
+	id, _ := idField.ValueOf(db.Statement.Context, value)
+	data, err := json.Marshal(cursor{Sort: sortKey, Value: sortValue, Id: fmt.Sprint(id)})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(encoded string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	// Numbers are kept as written so that integers are not turned into floats
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var c cursor
+	if err := decoder.Decode(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func quoteColumn(column string) string {
+	return `"` + column + `"`
+}
+
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/smartik/api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Returned when more marks are awarded for a question than it is worth
+var ErrAwardedAboveMaximum = errors.New("more marks awarded than the question is worth")
+
+type QuestionMarkRepository struct {
+	db *gorm.DB
+}
+
+// Creates a new instance of QuestionMarkRepository
+func NewQuestionMarkRepository(db *gorm.DB) *QuestionMarkRepository {
+	return &QuestionMarkRepository{db}
+}
+
+// Returns a copy of the repository that runs its queries in the transaction tx
+func (r *QuestionMarkRepository) WithTx(tx *gorm.DB) *QuestionMarkRepository {
+	return &QuestionMarkRepository{tx}
+}
+
+// Retrieves the marks recorded for an answer script
+func (r *QuestionMarkRepository) GetByAnswerScriptId(answerScriptId string) (*[]models.QuestionMark, error) {
+	var marks []models.QuestionMark
+	if err := r.db.Where("answer_script_id = ?", answerScriptId).
+		Order("created_at").
+		Find(&marks).Error; err != nil {
+		return nil, err
+	}
+	return &marks, nil
+}
+
+// Retrieves the marks recorded for several answer scripts
+func (r *QuestionMarkRepository) GetByAnswerScriptIds(answerScriptIds []string) (*[]models.QuestionMark, error) {
+	var marks []models.QuestionMark
+	if err := r.db.Where("answer_script_id IN ?", answerScriptIds).Find(&marks).Error; err != nil {
+		return nil, err
+	}
+	return &marks, nil
+}
+
+// Retrieves the marks recorded for the questions of a memorandum
+func (r *QuestionMarkRepository) GetByMemorandumId(memorandumId string) (*[]models.QuestionMark, error) {
+	var marks []models.QuestionMark
+	if err := r.db.Where("question_id IN (?)", r.db.Model(&models.MemorandumQuestion{}).Select("id").Where("memorandum_id = ?", memorandumId)).
+		Order("created_at").
+		Find(&marks).Error; err != nil {
+		return nil, err
+	}
+	return &marks, nil
+}
+
+// Inserts or replaces the marks of an answer script and recalculates its
+// totals, all in a single transaction. The maximum of every mark is taken
+// from its question, a mark above it fails with ErrAwardedAboveMaximum.
+func (r *QuestionMarkRepository) Save(answerScriptId string, marks []models.QuestionMark) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		questionIds := make([]string, len(marks))
+		for i, mark := range marks {
+			questionIds[i] = mark.QuestionId
+		}
+		// Keeps the marks of the questions from changing until the marks are saved
+		var questions []models.MemorandumQuestion
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+			Select("id", "number", "marks").
+			Where("id IN ?", questionIds).
+			Find(&questions).Error; err != nil {
+			return err
+		}
+		byId := make(map[string]models.MemorandumQuestion, len(questions))
+		for _, question := range questions {
+			byId[question.Id] = question
+		}
+		for i := range marks {
+			question, ok := byId[marks[i].QuestionId]
+			if !ok {
+				return gorm.ErrRecordNotFound
+			}
+			if marks[i].Awarded > question.Marks {
+				return fmt.Errorf("%w: question %s is worth %d marks but %d were awarded",
+					ErrAwardedAboveMaximum, question.Number, question.Marks, marks[i].Awarded)
+			}
+			marks[i].MaxMarks = question.Marks
+		}
+
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "answer_script_id"}, {Name: "question_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"awarded", "max_marks", "marked_by", "comment", "updated_at"}),
+		}).Create(&marks).Error; err != nil {
+			return err
+		}
+		return recalculateTotals(tx, answerScriptId)
+	})
+}
+
+// Deletes the mark of a single question and recalculates the totals of the answer script
+func (r *QuestionMarkRepository) Delete(answerScriptId, questionId string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("answer_script_id = ? AND question_id = ?", answerScriptId, questionId).
+			Delete(&models.QuestionMark{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return recalculateTotals(tx, answerScriptId)
+	})
+}
+
+// Sets the total and maximum marks of answer scripts to the sums of their
+// question marks, or clears them when no question has been marked
+func recalculateTotals(tx *gorm.DB, answerScriptIds ...string) error {
+	if len(answerScriptIds) == 0 {
+		return nil
+	}
+	return tx.Exec(`
+		UPDATE answer_scripts SET
+			total_marks = (SELECT SUM(awarded) FROM question_marks WHERE answer_script_id = answer_scripts.id),
+			max_marks = (SELECT SUM(max_marks) FROM question_marks WHERE answer_script_id = answer_scripts.id)
+		WHERE id IN @ids`,
+		map[string]interface{}{"ids": answerScriptIds},
+	).Error
+}
+
+// Returns the answer scripts that have marks for any question of a memorandum
+func scriptsMarkedForMemorandum(tx *gorm.DB, memorandumId string) ([]string, error) {
+	var answerScriptIds []string
+	err := tx.Model(&models.QuestionMark{}).
+		Where("question_id IN (?)", tx.Model(&models.MemorandumQuestion{}).Select("id").Where("memorandum_id = ?", memorandumId)).
+		Distinct().
+		Pluck("answer_script_id", &answerScriptIds).Error
+	return answerScriptIds, err
+}
@@ -37,16 +37,24 @@ func SeedDatabase(db *gorm.DB) error {
 		{Name: "Chemistry", Code: "CHEM101"},
 	}
 
-	for _, subject := range subjects {
-		if err := db.Create(&subject).Error; err != nil {
+	for i := range subjects {
+		if err := db.Create(&subjects[i]).Error; err != nil {
 			return err
 		}
 	}
 
+	// Every exam is the first paper of one of the subjects
 	exams := []models.Exam{
-		{Date: time.Now().Add(24 * 30 * time.Hour), TotalMarks: 150}, // 30 days from now
-		{Date: time.Now().Add(24 * 60 * time.Hour), TotalMarks: 255}, // 60 days from now
-		{Date: time.Now().Add(24 * 120 * time.Hour), TotalMarks: 90}, // 120 days from now
+		{Date: time.Now().Add(24 * 30 * time.Hour), TotalMarks: 150, DurationMinutes: 180}, // 30 days from now
+		{Date: time.Now().Add(24 * 60 * time.Hour), TotalMarks: 255, DurationMinutes: 180}, // 60 days from now
+		{Date: time.Now().Add(24 * 120 * time.Hour), TotalMarks: 90, DurationMinutes: 120}, // 120 days from now
+	}
+	for i := range exams {
+		exams[i].SubjectId = &subjects[i].Id
+		exams[i].Name = subjects[i].Name + " Paper 1"
+		exams[i].PaperNumber = 1
+		exams[i].AcademicYear = exams[i].Date.Year()
+		exams[i].Status = models.ExamStatusDraft
 	}
 
 	for _, exam := range exams {
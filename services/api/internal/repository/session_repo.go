@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/smartik/api/internal/models"
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+// Creates a new instance of SessionRepository
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db}
+}
+
+// Creates a new session record in the database
+func (r *SessionRepository) Create(session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+// Retrieves an unexpired session, with its user, by the hash of its token
+func (r *SessionRepository) GetByTokenHash(tokenHash string) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.Preload("User").
+		Where("token_hash = ? AND expires_at > ?", tokenHash, time.Now()).
+		First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Deletes the session with the given token hash
+func (r *SessionRepository) DeleteByTokenHash(tokenHash string) error {
+	return r.db.Where("token_hash = ?", tokenHash).Delete(&models.Session{}).Error
+}
+
+// Deletes every session of a user, signing them out everywhere
+func (r *SessionRepository) DeleteByUserId(userId string) error {
+	return r.db.Where("user_id = ?", userId).Delete(&models.Session{}).Error
+}
+
+// Deletes sessions that have expired
+func (r *SessionRepository) DeleteExpired() error {
+	return r.db.Where("expires_at <= ?", time.Now()).Delete(&models.Session{}).Error
+}
@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Directory (relative to the storage root) holding object metadata
+const localMetaDir = ".meta"
+
+// Stores objects as plain files below a root directory.
+// Intended for offline installations where MinIO cannot be hosted.
+type LocalStorage struct {
+	root string
+}
+
+// Metadata persisted next to every stored file
+type localMeta struct {
+	ContentType string `json:"content_type"`
+	ETag        string `json:"etag"`
+}
+
+// Creates a new instance of LocalStorage, creating the root directory if needed
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	if err := os.MkdirAll(filepath.Join(root, localMetaDir), 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{root}, nil
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*ObjectInfo, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	// Write to a temporary file first so readers never see partial objects
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := md5.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if size >= 0 && written != size {
+		return nil, fmt.Errorf("expected %d bytes but received %d", size, written)
+	}
+
+	meta := localMeta{ContentType: contentType, ETag: hex.EncodeToString(hash.Sum(nil))}
+	if err := s.writeMeta(key, meta); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, err
+	}
+
+	return s.Stat(ctx, key)
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (Object, *ObjectInfo, error) {
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	path, _ := s.path(key)
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, translateFsError(err)
+	}
+	return file, info, nil
+}
+
+func (s *LocalStorage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return nil, translateFsError(err)
+	}
+	if fileInfo.IsDir() {
+		return nil, ErrObjectNotFound
+	}
+
+	meta := s.readMeta(key, fileInfo)
+	return &ObjectInfo{
+		Key:          key,
+		Size:         fileInfo.Size(),
+		ContentType:  meta.ContentType,
+		ETag:         meta.ETag,
+		LastModified: fileInfo.ModTime(),
+	}, nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(s.metaPath(path)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objects := []ObjectInfo{}
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == localMetaDir && filepath.Dir(path) == filepath.Clean(s.root) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := s.Stat(ctx, key)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, *info)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+func (s *LocalStorage) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return "", ErrPresignNotSupported
+}
+
+// Resolves a key to a path below the storage root, rejecting keys
+// that would escape it
+func (s *LocalStorage) path(key string) (string, error) {
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("invalid object key %q", key)
+		}
+	}
+
+	cleaned := filepath.Clean("/" + filepath.FromSlash(key))
+	if cleaned == string(filepath.Separator) || strings.HasPrefix(cleaned, string(filepath.Separator)+localMetaDir) {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return filepath.Join(s.root, cleaned), nil
+}
+
+// Maps an object path to the path of its metadata file
+func (s *LocalStorage) metaPath(path string) string {
+	rel, _ := filepath.Rel(s.root, path)
+	return filepath.Join(s.root, localMetaDir, rel+".json")
+}
+
+func (s *LocalStorage) writeMeta(key string, meta localMeta) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	metaPath := s.metaPath(path)
+	if err := os.MkdirAll(filepath.Dir(metaPath), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(metaPath, data, 0o644)
+}
+
+// Reads the metadata of an object, falling back to values derived from
+// the file itself for objects that were placed on disk by hand
+func (s *LocalStorage) readMeta(key string, fileInfo fs.FileInfo) localMeta {
+	meta := localMeta{}
+
+	path, _ := s.path(key)
+	if data, err := os.ReadFile(s.metaPath(path)); err == nil {
+		_ = json.Unmarshal(data, &meta)
+	}
+
+	if meta.ContentType == "" {
+		meta.ContentType = "application/octet-stream"
+	}
+	if meta.ETag == "" {
+		meta.ETag = fmt.Sprintf("%x-%x", fileInfo.ModTime().UnixNano(), fileInfo.Size())
+	}
+	return meta
+}
+
+func translateFsError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrObjectNotFound
+	}
+	return err
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	minio "github.com/minio/minio-go/v7"
+)
+
+// Stores objects in a MinIO (or any S3 compatible) bucket
+type MinioStorage struct {
+	client *minio.Client
+	bucket string
+}
+
+// Creates a new instance of MinioStorage
+func NewMinioStorage(client *minio.Client, bucket string) *MinioStorage {
+	return &MinioStorage{client, bucket}
+}
+
+func (s *MinioStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*ObjectInfo, error) {
+	info, err := s.client.PutObject(ctx, s.bucket, key, r, size,
+		minio.PutObjectOptions{ContentType: contentType},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ObjectInfo{
+		Key:          key,
+		Size:         info.Size,
+		ContentType:  contentType,
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+	}, nil
+}
+
+func (s *MinioStorage) Get(ctx context.Context, key string) (Object, *ObjectInfo, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, translateMinioError(err)
+	}
+
+	// GetObject is lazy, so errors such as a missing key only surface here
+	info, err := object.Stat()
+	if err != nil {
+		object.Close()
+		return nil, nil, translateMinioError(err)
+	}
+
+	return object, toObjectInfo(info), nil
+}
+
+func (s *MinioStorage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, translateMinioError(err)
+	}
+	return toObjectInfo(info), nil
+}
+
+func (s *MinioStorage) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *MinioStorage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objects := []ObjectInfo{}
+	for info := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	}) {
+		if info.Err != nil {
+			return nil, info.Err
+		}
+		objects = append(objects, *toObjectInfo(info))
+	}
+	return objects, nil
+}
+
+func (s *MinioStorage) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, key, expiry, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func toObjectInfo(info minio.ObjectInfo) *ObjectInfo {
+	return &ObjectInfo{
+		Key:          info.Key,
+		Size:         info.Size,
+		ContentType:  info.ContentType,
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+	}
+}
+
+// Maps MinIO "not found" responses onto ErrObjectNotFound
+func translateMinioError(err error) error {
+	if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+		return ErrObjectNotFound
+	}
+	return err
+}
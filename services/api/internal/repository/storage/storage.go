@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/smartik/api/internal/config"
+	"github.com/smartik/api/internal/repository/minio"
+)
+
+var (
+	// Returned when the requested object does not exist in the backend
+	ErrObjectNotFound = errors.New("object not found")
+	// Returned by backends that cannot issue presigned URLs
+	ErrPresignNotSupported = errors.New("presigned urls are not supported by this storage backend")
+)
+
+// Metadata describing a stored object
+type ObjectInfo struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"content_type"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// An opened object. Seeking is supported so that callers can serve
+// partial content without reading the whole object.
+type Object interface {
+	io.ReadSeekCloser
+}
+
+// Abstracts the object store holding answer scripts and memoranda
+type Storage interface {
+	// Stores the content of r under key, replacing any existing object.
+	// A negative size means the size is unknown.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*ObjectInfo, error)
+	// Opens the object stored under key for reading
+	Get(ctx context.Context, key string) (Object, *ObjectInfo, error)
+	// Retrieves the metadata of the object stored under key
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// Removes the object stored under key. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	// Lists the objects whose keys start with prefix
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// Issues a URL that allows the object to be downloaded directly until expiry
+	PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+// Creates the storage backend selected by the configuration
+func New(cfg *config.Env) (Storage, error) {
+	switch cfg.StorageDriver {
+	case config.StorageDriverMinio:
+		client, err := minio.NewMinioClient(cfg.MinioEndpointUrl,
+			cfg.MinioAccessId, cfg.MinioSecretKey, cfg,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return NewMinioStorage(client, cfg.MinioStorageBucket), nil
+	case config.StorageDriverLocal:
+		return NewLocalStorage(cfg.LocalStoragePath)
+	case config.StorageDriverMemory:
+		return NewMemoryStorage(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.StorageDriver)
+	}
+}
@@ -0,0 +1,290 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The backends that run without external services
+func testBackends(t *testing.T) map[string]Storage {
+	t.Helper()
+
+	local, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	return map[string]Storage{
+		"memory": NewMemoryStorage(),
+		"local":  local,
+	}
+}
+
+func TestPutAndGet(t *testing.T) {
+	tests := []struct {
+		name        string
+		key         string
+		content     string
+		size        int64
+		contentType string
+		wantErr     bool
+	}{
+		{name: "known size", key: "scripts/a.pdf", content: "%PDF-1.7", size: 8, contentType: "application/pdf"},
+		{name: "unknown size", key: "scripts/b.pdf", content: "%PDF-1.4", size: -1, contentType: "application/pdf"},
+		{name: "nested key", key: "exams/e1/scripts/c.png", content: "png", size: 3, contentType: "image/png"},
+		{name: "empty object", key: "empty.txt", content: "", size: 0, contentType: "text/plain"},
+		{name: "size mismatch", key: "short.pdf", content: "%PDF", size: 10, contentType: "application/pdf", wantErr: true},
+	}
+
+	for backend, store := range testBackends(t) {
+		for _, tt := range tests {
+			t.Run(backend+"/"+tt.name, func(t *testing.T) {
+				ctx := context.Background()
+				info, err := store.Put(ctx, tt.key, strings.NewReader(tt.content), tt.size, tt.contentType)
+				if tt.wantErr {
+					if err == nil {
+						t.Fatal("Put succeeded, want an error")
+					}
+					if _, err := store.Stat(ctx, tt.key); !errors.Is(err, ErrObjectNotFound) {
+						t.Errorf("Stat after failed Put = %v, want ErrObjectNotFound", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Put: %v", err)
+				}
+				if info.Size != int64(len(tt.content)) || info.ContentType != tt.contentType || info.ETag == "" {
+					t.Errorf("Put info = %+v", info)
+				}
+
+				object, got, err := store.Get(ctx, tt.key)
+				if err != nil {
+					t.Fatalf("Get: %v", err)
+				}
+				defer object.Close()
+				data, err := io.ReadAll(object)
+				if err != nil {
+					t.Fatalf("reading object: %v", err)
+				}
+				if string(data) != tt.content {
+					t.Errorf("content = %q, want %q", data, tt.content)
+				}
+				if got.Key != tt.key || got.Size != info.Size || got.ETag != info.ETag || got.ContentType != tt.contentType {
+					t.Errorf("Get info = %+v, want %+v", got, info)
+				}
+			})
+		}
+	}
+}
+
+func TestGetSeeks(t *testing.T) {
+	for backend, store := range testBackends(t) {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+			if _, err := store.Put(ctx, "a.txt", strings.NewReader("0123456789"), 10, "text/plain"); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+
+			object, _, err := store.Get(ctx, "a.txt")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			defer object.Close()
+			if _, err := object.Seek(4, io.SeekStart); err != nil {
+				t.Fatalf("Seek: %v", err)
+			}
+			part := make([]byte, 3)
+			if _, err := io.ReadFull(object, part); err != nil {
+				t.Fatalf("reading object: %v", err)
+			}
+			if string(part) != "456" {
+				t.Errorf("read %q after seeking, want %q", part, "456")
+			}
+		})
+	}
+}
+
+func TestPutReplaces(t *testing.T) {
+	for backend, store := range testBackends(t) {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+			first, err := store.Put(ctx, "a.txt", strings.NewReader("first"), 5, "text/plain")
+			if err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			second, err := store.Put(ctx, "a.txt", strings.NewReader("second"), 6, "text/csv")
+			if err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			if first.ETag == second.ETag {
+				t.Error("ETag did not change with the content")
+			}
+
+			info, err := store.Stat(ctx, "a.txt")
+			if err != nil {
+				t.Fatalf("Stat: %v", err)
+			}
+			if info.Size != 6 || info.ContentType != "text/csv" {
+				t.Errorf("Stat = %+v, want the second object", info)
+			}
+		})
+	}
+}
+
+func TestMissingObjects(t *testing.T) {
+	for backend, store := range testBackends(t) {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+			if _, _, err := store.Get(ctx, "missing.pdf"); !errors.Is(err, ErrObjectNotFound) {
+				t.Errorf("Get = %v, want ErrObjectNotFound", err)
+			}
+			if _, err := store.Stat(ctx, "missing.pdf"); !errors.Is(err, ErrObjectNotFound) {
+				t.Errorf("Stat = %v, want ErrObjectNotFound", err)
+			}
+			if err := store.Delete(ctx, "missing.pdf"); err != nil {
+				t.Errorf("Delete = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	for backend, store := range testBackends(t) {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+			if _, err := store.Put(ctx, "a/b.pdf", strings.NewReader("pdf"), 3, "application/pdf"); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			if err := store.Delete(ctx, "a/b.pdf"); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if _, err := store.Stat(ctx, "a/b.pdf"); !errors.Is(err, ErrObjectNotFound) {
+				t.Errorf("Stat after Delete = %v, want ErrObjectNotFound", err)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	keys := []string{"exams/e1/a.pdf", "exams/e1/b.pdf", "exams/e2/c.pdf", "memorandums/m.pdf"}
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "", want: keys},
+		{prefix: "exams/", want: keys[:3]},
+		{prefix: "exams/e1/", want: keys[:2]},
+		{prefix: "memorandums/", want: keys[3:]},
+		{prefix: "missing/", want: nil},
+	}
+
+	for backend, store := range testBackends(t) {
+		ctx := context.Background()
+		for _, key := range keys {
+			if _, err := store.Put(ctx, key, strings.NewReader("pdf"), 3, "application/pdf"); err != nil {
+				t.Fatalf("%s: Put: %v", backend, err)
+			}
+		}
+
+		for _, tt := range tests {
+			t.Run(backend+"/"+tt.prefix, func(t *testing.T) {
+				objects, err := store.List(ctx, tt.prefix)
+				if err != nil {
+					t.Fatalf("List: %v", err)
+				}
+				var got []string
+				for _, object := range objects {
+					got = append(got, object.Key)
+				}
+				if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+					t.Errorf("List(%q) = %v, want %v", tt.prefix, got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestPresignNotSupported(t *testing.T) {
+	for backend, store := range testBackends(t) {
+		t.Run(backend, func(t *testing.T) {
+			if _, err := store.PresignGet(context.Background(), "a.pdf", time.Minute); !errors.Is(err, ErrPresignNotSupported) {
+				t.Errorf("PresignGet = %v, want ErrPresignNotSupported", err)
+			}
+		})
+	}
+}
+
+func TestLocalStoragePath(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewLocalStorage(root)
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{key: "a.pdf", want: "a.pdf"},
+		{key: "exams/e1/a.pdf", want: "exams/e1/a.pdf"},
+		{key: "/exams/a.pdf", want: "exams/a.pdf"},
+		{key: "exams/./a.pdf", want: "exams/a.pdf"},
+		{key: "a..b.pdf", want: "a..b.pdf"},
+		{key: "exams/../a.pdf", wantErr: true},
+		{key: "../a.pdf", wantErr: true},
+		{key: "../../etc/passwd", wantErr: true},
+		{key: "exams/../../../a.pdf", wantErr: true},
+		{key: "exams/..", wantErr: true},
+		{key: "", wantErr: true},
+		{key: "/", wantErr: true},
+		{key: "..", wantErr: true},
+		{key: ".meta/a.pdf.json", wantErr: true},
+		{key: "../.meta/a.pdf.json", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			path, err := store.path(tt.key)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("path(%q) = %q, want an error", tt.key, path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("path(%q): %v", tt.key, err)
+			}
+			if want := root + "/" + tt.want; path != want {
+				t.Errorf("path(%q) = %q, want %q", tt.key, path, want)
+			}
+		})
+	}
+}
+
+func TestLocalStorageRejectsEscapingKeys(t *testing.T) {
+	parent := t.TempDir()
+	store, err := NewLocalStorage(parent + "/root")
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := store.Put(ctx, "../outside.txt", strings.NewReader("x"), 1, "text/plain"); err == nil {
+		t.Fatal("Put with a ../ key succeeded")
+	}
+	if _, err := os.Stat(parent + "/outside.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("a key with ../ was written outside the root: %v", err)
+	}
+	if _, err := store.Stat(ctx, "../root/a.txt"); err == nil || errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Stat with a ../ key = %v, want an invalid key error", err)
+	}
+	if err := store.Delete(ctx, "../outside.txt"); err == nil {
+		t.Error("Delete with a ../ key succeeded")
+	}
+}
@@ -3,29 +3,54 @@ package repository
 import (
 	"github.com/smartik/api/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// An exam number upper cased and stripped of separators such as spaces and
+// dashes, the way matching compares them. It is indexed, see the
+// 0006_normalized_exam_numbers migration.
+const normalizedExamNumber = `upper(regexp_replace(exam_number, '[^[:alnum:]]', '', 'g'))`
+
 type StudentRepository struct {
 	db *gorm.DB
 }
 
+// Filters and sort orders available when listing students
+var studentListSpec = ListSpec{
+	Filters: map[string]string{
+		"exam_number": "exam_number",
+		"first_name":  "first_name",
+		"last_name":   "last_name",
+	},
+	Ranges: map[string]string{"created": "created_at"},
+	Sorts: map[string]string{
+		"created_at":  "created_at",
+		"updated_at":  "updated_at",
+		"exam_number": "exam_number",
+		"first_name":  "first_name",
+		"last_name":   "last_name",
+	},
+	DefaultSort: "created_at",
+}
+
 // Creates a new instance of StudentRepository
 func NewStudentRepository(db *gorm.DB) *StudentRepository {
 	return &StudentRepository{db}
 }
 
+// Returns a copy of the repository that runs its queries in the transaction tx
+func (r *StudentRepository) WithTx(tx *gorm.DB) *StudentRepository {
+	return &StudentRepository{tx}
+}
+
 // Creates a new student record in the database
 func (r *StudentRepository) Create(student *models.Student) error {
 	return r.db.Create(student).Error
 }
 
-// Retrieves all students from the database
-func (r *StudentRepository) GetAll() (*[]models.Student, error) {
-	var students []models.Student
-	if err := r.db.Find(&students).Error; err != nil {
-		return nil, err
-	}
-	return &students, nil
+// Retrieves a page of students
+func (r *StudentRepository) GetAll(params ListParams) (*Page[models.Student], error) {
+	return Paginate[models.Student](r.db, studentListSpec, params)
 }
 
 // Retrieves a specific student by their ID
@@ -37,6 +62,39 @@ func (r *StudentRepository) GetById(id string) (*models.Student, error) {
 	return &student, nil
 }
 
+// Retrieves up to two students whose exam number is the given one once
+// normalized, so that callers can tell an exact match from a clash
+func (r *StudentRepository) GetByNormalizedExamNumber(examNumber string) (*[]models.Student, error) {
+	var students []models.Student
+	if err := r.db.Where(normalizedExamNumber+" = ?", examNumber).
+		Limit(2).
+		Find(&students).Error; err != nil {
+		return nil, err
+	}
+	return &students, nil
+}
+
+// Retrieves the students with any of the given exam numbers
+func (r *StudentRepository) GetByExamNumbers(examNumbers []string) (*[]models.Student, error) {
+	var students []models.Student
+	if err := r.db.Where("exam_number IN ?", examNumbers).Find(&students).Error; err != nil {
+		return nil, err
+	}
+	return &students, nil
+}
+
+// Retrieves the Id and exam number of all students whose normalized exam
+// number length lies within the given bounds
+func (r *StudentRepository) GetExamNumbersByLength(min, max int) (*[]models.Student, error) {
+	var students []models.Student
+	if err := r.db.Select("id", "exam_number").
+		Where("char_length("+normalizedExamNumber+") BETWEEN ? AND ?", min, max).
+		Find(&students).Error; err != nil {
+		return nil, err
+	}
+	return &students, nil
+}
+
 // Updates an existing student record
 func (r *StudentRepository) Update(id string, data *models.UpdateStudent) (*models.Student, error) {
 	student, err := r.GetById(id)
@@ -51,6 +109,51 @@ func (r *StudentRepository) Update(id string, data *models.UpdateStudent) (*mode
 	return student, nil
 }
 
+// Creates and updates the students of an import in a single transaction.
+// Only the names of updated students are written. Students whose exam
+// number was registered by someone else in the meantime are not created,
+// their exam numbers are returned instead.
+func (r *StudentRepository) Import(created, updated []models.Student) ([]string, error) {
+	var taken []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if len(created) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "exam_number"}},
+				DoNothing: true,
+			}).Create(&created).Error; err != nil {
+				return err
+			}
+
+			ids := make([]string, len(created))
+			for i := range created {
+				ids[i] = created[i].Id
+			}
+			var inserted []string
+			if err := tx.Model(&models.Student{}).Where("id IN ?", ids).Pluck("id", &inserted).Error; err != nil {
+				return err
+			}
+			if len(inserted) < len(created) {
+				insertedIds := make(map[string]bool, len(inserted))
+				for _, id := range inserted {
+					insertedIds[id] = true
+				}
+				for _, student := range created {
+					if !insertedIds[student.Id] {
+						taken = append(taken, student.ExamNumber)
+					}
+				}
+			}
+		}
+		for i := range updated {
+			if err := tx.Model(&updated[i]).Select("first_name", "last_name").Updates(&updated[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return taken, err
+}
+
 // Deletes a student from the database
 func (r *StudentRepository) Delete(id string) error {
 	student, err := r.GetById(id)
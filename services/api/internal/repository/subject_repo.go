@@ -9,24 +9,40 @@ type SubjectRepository struct {
 	db *gorm.DB
 }
 
+// Filters and sort orders available when listing subjects
+var subjectListSpec = ListSpec{
+	Filters: map[string]string{
+		"code": "code",
+		"name": "name",
+	},
+	Ranges: map[string]string{"created": "created_at"},
+	Sorts: map[string]string{
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+		"code":       "code",
+		"name":       "name",
+	},
+	DefaultSort: "created_at",
+}
+
 // Creates a new instance of SubjectRepository
 func NewSubjectRepository(db *gorm.DB) *SubjectRepository {
 	return &SubjectRepository{db}
 }
 
+// Returns a copy of the repository that runs its queries in the transaction tx
+func (r *SubjectRepository) WithTx(tx *gorm.DB) *SubjectRepository {
+	return &SubjectRepository{tx}
+}
+
 // Creates a new subject record in the database
 func (r *SubjectRepository) Create(subject *models.Subject) error {
 	return r.db.Create(subject).Error
 }
 
-// Retrieves all subjects from the database
-func (r *SubjectRepository) GetAll() (*[]models.Subject, error) {
-	var subjects []models.Subject
-	if err := r.db.Find(&subjects).Error; err != nil {
-		return nil, err
-	}
-
-	return &subjects, nil
+// Retrieves a page of subjects
+func (r *SubjectRepository) GetAll(params ListParams) (*Page[models.Subject], error) {
+	return Paginate[models.Subject](r.db, subjectListSpec, params)
 }
 
 // Retrieves a specific subject by its ID
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/smartik/api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UploadSessionRepository struct {
+	db *gorm.DB
+}
+
+// Creates a new instance of UploadSessionRepository
+func NewUploadSessionRepository(db *gorm.DB) *UploadSessionRepository {
+	return &UploadSessionRepository{db}
+}
+
+// Creates a new upload session record in the database
+func (r *UploadSessionRepository) Create(session *models.UploadSession) error {
+	return r.db.Create(session).Error
+}
+
+// Retrieves an upload session, with its stored parts in order, by its ID
+func (r *UploadSessionRepository) GetById(id string) (*models.UploadSession, error) {
+	var session models.UploadSession
+	if err := r.db.Preload("Parts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("number")
+	}).Where("id = ?", id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Retrieves the upload sessions that have expired, with their stored parts
+func (r *UploadSessionRepository) GetExpired() (*[]models.UploadSession, error) {
+	var sessions []models.UploadSession
+	if err := r.db.Preload("Parts").
+		Where("expires_at <= ?", time.Now()).
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return &sessions, nil
+}
+
+// Records a stored part, replacing an earlier upload of the same part,
+// while its session is active. Reports false, and records nothing, once
+// the session is no longer active. replaced is the part that was replaced,
+// nil when the part was sent for the first time.
+func (r *UploadSessionRepository) SavePart(part *models.UploadPart) (saved bool, replaced *models.UploadPart, err error) {
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		// The lock keeps the session from being completed until the part is recorded
+		var active []string
+		if err := tx.Model(&models.UploadSession{}).
+			Clauses(clause.Locking{Strength: "SHARE"}).
+			Where("id = ? AND status = ?", part.SessionId, models.UploadSessionActive).
+			Pluck("id", &active).Error; err != nil {
+			return err
+		}
+		if len(active) == 0 {
+			return nil
+		}
+
+		var previous models.UploadPart
+		if err := tx.Where("session_id = ? AND number = ?", part.SessionId, part.Number).
+			Take(&previous).Error; err == nil {
+			replaced = &previous
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "session_id"}, {Name: "number"}},
+			DoUpdates: clause.AssignmentColumns([]string{"size", "checksum", "storage_key", "updated_at"}),
+		}).Create(part).Error; err != nil {
+			return err
+		}
+		saved = true
+		return nil
+	})
+	if err != nil || !saved {
+		return false, nil, err
+	}
+	return saved, replaced, nil
+}
+
+// Updates only the given columns of an upload session
+func (r *UploadSessionRepository) UpdateFields(id string, fields map[string]interface{}) error {
+	return r.db.Model(&models.UploadSession{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// Moves an upload session from one status to another. Reports false when
+// the session was no longer in the expected status, e.g. because the
+// upload was completed by a concurrent request.
+func (r *UploadSessionRepository) UpdateStatus(id string, from, to models.UploadSessionStatus) (bool, error) {
+	result := r.db.Model(&models.UploadSession{}).
+		Where("id = ? AND status = ?", id, from).
+		Update("status", to)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Deletes an upload session and the records of its parts
+func (r *UploadSessionRepository) Delete(id string) error {
+	return r.db.Where("id = ?", id).Delete(&models.UploadSession{}).Error
+}
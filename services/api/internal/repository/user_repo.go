@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"github.com/smartik/api/internal/models"
+	"gorm.io/gorm"
+)
+
+type UserRepository struct {
+	db *gorm.DB
+}
+
+// Filters and sort orders available when listing users
+var userListSpec = ListSpec{
+	Filters: map[string]string{
+		"email":  "email",
+		"role":   "role",
+		"active": "active",
+	},
+	Ranges: map[string]string{
+		"created":    "created_at",
+		"last_login": "last_login_at",
+	},
+	Sorts: map[string]string{
+		"created_at": "created_at",
+		"email":      "email",
+		"name":       "name",
+	},
+	DefaultSort: "email",
+}
+
+// Creates a new instance of UserRepository
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{db}
+}
+
+// Returns a copy of the repository that runs its queries in the transaction tx
+func (r *UserRepository) WithTx(tx *gorm.DB) *UserRepository {
+	return &UserRepository{tx}
+}
+
+// Creates a new user record in the database
+func (r *UserRepository) Create(user *models.User) error {
+	return r.db.Create(user).Error
+}
+
+// Retrieves a page of users
+func (r *UserRepository) GetAll(params ListParams) (*Page[models.User], error) {
+	return Paginate[models.User](r.db, userListSpec, params)
+}
+
+// Retrieves a specific user by their ID
+func (r *UserRepository) GetById(id string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where("id = ?", id).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Retrieves a specific user by their email address
+func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where("lower(email) = lower(?)", email).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Counts the users in the database
+func (r *UserRepository) Count() (int64, error) {
+	var count int64
+	if err := r.db.Model(&models.User{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Updates only the given columns of a user
+func (r *UserRepository) UpdateFields(id string, fields map[string]interface{}) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// Deletes a user from the database
+func (r *UserRepository) Delete(id string) error {
+	user, err := r.GetById(id)
+	if err != nil {
+		return err
+	}
+	return r.db.Delete(user).Error
+}
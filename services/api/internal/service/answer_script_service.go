@@ -1,23 +1,33 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"mime/multipart"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/labstack/gommon/log"
-	minio "github.com/minio/minio-go/v7"
 	"github.com/smartik/api/internal/config"
 	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/pdfsplit"
 	"github.com/smartik/api/internal/repository"
+	"github.com/smartik/api/internal/repository/storage"
+	"gorm.io/gorm"
 )
 
 // Handles business logic for answer script operations
 type AnswerScriptService struct {
-	repo        *repository.AnswerScriptRepository
-	minioClient *minio.Client
-	cfg         *config.Env
+	repo     *repository.AnswerScriptRepository
+	examRepo *repository.ExamRepository
+	storage  storage.Storage
+	versions *fileVersions
+	links    *DownloadLinkService
+	cfg      *config.Env
+	audit    *AuditService
 }
 
 type AnswerScriptUploadResult struct {
@@ -28,19 +38,39 @@ type AnswerScriptUploadResult struct {
 // Creates a new instance of AnswerScriptService
 func NewAnswerScriptService(
 	repo *repository.AnswerScriptRepository,
-	minioClient *minio.Client,
+	examRepo *repository.ExamRepository,
+	versionRepo *repository.FileVersionRepository,
+	storage storage.Storage,
+	links *DownloadLinkService,
 	cfg *config.Env,
+	audit *AuditService,
 ) *AnswerScriptService {
 	return &AnswerScriptService{
-		repo:        repo,
-		minioClient: minioClient,
-		cfg:         cfg,
+		repo:     repo,
+		examRepo: examRepo,
+		storage:  storage,
+		versions: &fileVersions{versionRepo, storage, audit},
+		links:    links,
+		cfg:      cfg,
+		audit:    audit,
 	}
 }
 
 // Handles the upload of multiple answer script files
-// Processes each file individually and returns a summary of successes and failures
-func (s *AnswerScriptService) UploadFiles(files []*multipart.FileHeader) (*AnswerScriptUploadResult, error) {
+// Processes each file individually and returns a summary of successes and failures.
+// When examId is given the uploaded scripts are linked to that exam and its subject,
+// which has to be open for uploads.
+func (s *AnswerScriptService) UploadFiles(ctx context.Context, files []*multipart.FileHeader, examId *string) (*AnswerScriptUploadResult, error) {
+	subjectId, err := scriptSubject(s.examRepo, examId, nil)
+	if err != nil {
+		return nil, err
+	}
+	if examId != nil {
+		if err := requireUploadableExam(s.examRepo, *examId); err != nil {
+			return nil, err
+		}
+	}
+
 	result := &AnswerScriptUploadResult{
 		SuccessfulUploads: []models.AnswerScript{},
 		UploadResult: UploadResult{
@@ -50,7 +80,7 @@ func (s *AnswerScriptService) UploadFiles(files []*multipart.FileHeader) (*Answe
 
 	// Process each file individually
 	for _, file := range files {
-		if err := s.uploadSingleFile(file, result); err != nil {
+		if err := s.uploadSingleFile(ctx, file, examId, subjectId, result); err != nil {
 			continue // error handled in `uploadSingleFile`
 		}
 	}
@@ -58,34 +88,108 @@ func (s *AnswerScriptService) UploadFiles(files []*multipart.FileHeader) (*Answe
 	return result, nil
 }
 
-// Processes a single file upload with proper error handling and rollback
-func (s *AnswerScriptService) uploadSingleFile(file *multipart.FileHeader, result *AnswerScriptUploadResult) error {
+// Splits a batch PDF holding the scripts of many learners into one answer
+// script per learner. Every script is stored as its own object and waits
+// for processing like an uploaded one. The exam rules of UploadFiles apply.
+func (s *AnswerScriptService) UploadBatch(ctx context.Context, file *multipart.FileHeader, examId *string, opts pdfsplit.Options) (*AnswerScriptUploadResult, error) {
+	subjectId, err := scriptSubject(s.examRepo, examId, nil)
+	if err != nil {
+		return nil, err
+	}
+	if examId != nil {
+		if err := requireUploadableExam(s.examRepo, *examId); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkUploadSize(file.Size, s.cfg.UploadMaxBatchSize); err != nil {
+		return nil, err
+	}
+
 	src, err := file.Open()
 	if err != nil {
-		s.addUploadError(result, file.Filename, "Failed to open file: "+err.Error())
-		return err
+		return nil, err
 	}
 	defer src.Close()
 
-	// Upload file to MinIO storage
-	if err := s.uploadToStorage(file.Filename, src, file.Size, file.Header.Get("Content-Type")); err != nil {
-		s.addUploadError(result, file.Filename, "Failed to upload to storage: "+err.Error())
-		return err
+	// Only PDFs can be split
+	contentType, err := sniffContentType(src)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "application/pdf" {
+		return nil, &FileRejectedError{UploadErrorUnsupported, "batches must be PDF files"}
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, err
 	}
 
-	// Create database record for the uploaded file
-	answerScript := &models.AnswerScript{
-		FileName: file.Filename,
-		Status:   models.StatusUploaded,
+	doc, err := pdfsplit.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	ranges, err := doc.Plan(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AnswerScriptUploadResult{
+		SuccessfulUploads: []models.AnswerScript{},
+		UploadResult: UploadResult{
+			FailedUploads: []FileUploadError{},
+		},
+	}
+
+	// Scripts are named after the batch and the pages they came from
+	base := strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
+	if runes := []rune(base); len(runes) > 200 {
+		base = string(runes[:200])
 	}
+	for _, pages := range ranges {
+		fileName := fmt.Sprintf("%s_pages_%s.pdf", base, pages)
+
+		if count := pages.Last - pages.First + 1; s.cfg.UploadMaxPages > 0 && count > s.cfg.UploadMaxPages {
+			s.addUploadError(result, fileName, UploadErrorTooManyPages,
+				fmt.Sprintf("the script has %d pages, at most %d are accepted", count, s.cfg.UploadMaxPages))
+			continue
+		}
+
+		data, err := doc.Extract(pages)
+		if err != nil {
+			s.addUploadError(result, fileName, UploadErrorCorrupt, "Failed to extract pages: "+err.Error())
+			continue
+		}
 
-	if err := s.repo.Create(answerScript); err != nil {
-		// Deletes file from MinIO if database save fails
-		if deleteErr := s.deleteFromStorage(file.Filename); deleteErr != nil {
-			log.Errorf("Failed to rollback file deletion for %s: %v", file.Filename, deleteErr)
+		answerScript, err := s.storeScript(ctx, fileName, bytes.NewReader(data), int64(len(data)), contentType, examId, subjectId)
+		if err != nil {
+			s.addUploadError(result, fileName, UploadErrorFailed, err.Error())
+			continue
 		}
+		result.SuccessfulUploads = append(result.SuccessfulUploads, *answerScript)
+	}
+
+	return result, nil
+}
 
-		s.addUploadError(result, file.Filename, "Failed to save to database: "+err.Error())
+// Processes a single file upload with proper error handling and rollback
+func (s *AnswerScriptService) uploadSingleFile(ctx context.Context, file *multipart.FileHeader, examId, subjectId *string, result *AnswerScriptUploadResult) error {
+	src, err := file.Open()
+	if err != nil {
+		s.addUploadError(result, file.Filename, UploadErrorFailed, "Failed to open file: "+err.Error())
+		return err
+	}
+	defer src.Close()
+
+	// The content type the client claims is not trusted
+	contentType, err := inspectUpload(src, file.Size, s.uploadLimits())
+	if err != nil {
+		s.addUploadError(result, file.Filename, uploadErrorCode(err), err.Error())
+		return err
+	}
+
+	answerScript, err := s.storeScript(ctx, file.Filename, src, file.Size, contentType, examId, subjectId)
+	if err != nil {
+		s.addUploadError(result, file.Filename, UploadErrorFailed, err.Error())
 		return err
 	}
 
@@ -94,40 +198,60 @@ func (s *AnswerScriptService) uploadSingleFile(file *multipart.FileHeader, resul
 	return nil
 }
 
-// Handles the actual file upload to MinIO
-func (s *AnswerScriptService) uploadToStorage(filename string, src io.Reader, size int64, contentType string) error {
-	_, err := s.minioClient.PutObject(
-		context.Background(),
-		s.cfg.MinioStorageBucket,
-		filename,
-		src,
-		size,
-		minio.PutObjectOptions{ContentType: contentType},
-	)
-	return err
-}
+// Stores the content of a file and creates the answer script record for it.
+// The caller checks that the exam accepts uploads.
+func (s *AnswerScriptService) storeScript(ctx context.Context, fileName string, r io.Reader, size int64, contentType string, examId, subjectId *string) (*models.AnswerScript, error) {
+	// The Id is generated up front so the storage key can be derived from it.
+	// New scripts wait in 'processing' until a worker has read their exam number.
+	answerScript := &models.AnswerScript{
+		FileName:  fileName,
+		ExamId:    examId,
+		SubjectId: subjectId,
+		Status:    models.StatusProcessing,
+	}
+	if err := models.SetId(&answerScript.Id); err != nil {
+		return nil, fmt.Errorf("Failed to generate id: %w", err)
+	}
+	answerScript.StorageKey = answerScriptKey(examId, answerScript.Id, fileName)
+
+	// Upload file to storage
+	if _, err := s.storage.Put(ctx, answerScript.StorageKey, r, size, contentType); err != nil {
+		return nil, fmt.Errorf("Failed to upload to storage: %w", err)
+	}
 
-// Removes a file from MinIO storage
-func (s *AnswerScriptService) deleteFromStorage(filename string) error {
-	return s.minioClient.RemoveObject(
-		context.Background(),
-		s.cfg.MinioStorageBucket,
-		filename,
-		minio.RemoveObjectOptions{},
-	)
+	// Create database record for the uploaded file
+	if err := s.audit.Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.WithTx(tx).Create(answerScript); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionCreate, AuditEntityAnswerScript, answerScript.Id, nil, answerScript)
+	}); err != nil {
+		// Deletes file from storage if database save fails
+		if deleteErr := s.storage.Delete(context.Background(), answerScript.StorageKey); deleteErr != nil {
+			log.Errorf("Failed to rollback file deletion for %s: %v", fileName, deleteErr)
+		}
+		return nil, fmt.Errorf("Failed to save to database: %w", err)
+	}
+	return answerScript, nil
 }
 
 // Helper method to add upload errors to the result
-func (s *AnswerScriptService) addUploadError(result *AnswerScriptUploadResult, filename, errorMsg string) {
+func (s *AnswerScriptService) addUploadError(result *AnswerScriptUploadResult, filename string, code UploadErrorCode, errorMsg string) {
 	result.FailedUploads = append(result.FailedUploads, FileUploadError{
 		Filename: filename,
+		Code:     code,
 		Error:    errorMsg,
 	})
 }
 
-// Retrieves all answer scripts from the database
-func (s *AnswerScriptService) GetAll() (*[]models.AnswerScript, error) {
-	return s.repo.GetAll()
+// Limits applied to a single uploaded answer script
+func (s *AnswerScriptService) uploadLimits() uploadLimits {
+	return uploadLimits{maxSize: s.cfg.UploadMaxFileSize, maxPages: s.cfg.UploadMaxPages}
+}
+
+// Retrieves a page of answer scripts
+func (s *AnswerScriptService) GetAll(params repository.ListParams) (*repository.Page[models.AnswerScript], error) {
+	return s.repo.GetAll(params)
 }
 
 // Retrieves a specific answer script by its ID
@@ -135,25 +259,282 @@ func (s *AnswerScriptService) GetById(id string) (*models.AnswerScript, error) {
 	return s.repo.GetById(id)
 }
 
-// Modifies an existing answer script record
-func (s *AnswerScriptService) Update(id string, data *models.AnswerScript) (*models.AnswerScript, error) {
-	return s.repo.Update(id, data)
+// Modifies the fields of an answer script users may change
+func (s *AnswerScriptService) Update(ctx context.Context, id string, data *models.UpdateAnswerScript) (*models.AnswerScript, error) {
+	before, err := s.repo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Scripts only move out of exams with unlocked marks and into exams open for uploads
+	if data.ExamId != nil && (before.ExamId == nil || *data.ExamId != *before.ExamId) {
+		if before.ExamId != nil {
+			if err := requireUnlockedExam(s.examRepo, *before.ExamId, "its answer scripts cannot be moved"); err != nil {
+				return nil, err
+			}
+		}
+		if err := requireUploadableExam(s.examRepo, *data.ExamId); err != nil {
+			return nil, err
+		}
+	}
+
+	// Keep the subject in line with the exam, whichever of the two changes
+	if data.ExamId != nil || data.SubjectId != nil {
+		examId := data.ExamId
+		if examId == nil {
+			examId = before.ExamId
+		}
+		if data.SubjectId, err = scriptSubject(s.examRepo, examId, data.SubjectId); err != nil {
+			return nil, err
+		}
+	}
+
+	var answerScript *models.AnswerScript
+	err = s.audit.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		// Files stored under their original name must not lose their key when renamed
+		if data.FileName != nil && before.StorageKey == "" {
+			if err := repo.SetStorageKey(id, before.ObjectKey()); err != nil {
+				return err
+			}
+		}
+
+		// Marks were given against the memorandum of the exam the script leaves
+		if data.ExamId != nil && before.ExamId != nil && *data.ExamId != *before.ExamId {
+			if err := clearMarking(ctx, tx, s.audit, repo, id); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		answerScript, err = repo.Update(id, data)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionUpdate, AuditEntityAnswerScript, id, before, answerScript)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return answerScript, nil
+}
+
+// Overrides the processing status of an answer script, e.g. to mark a
+// script the worker cannot read as failed. Setting it to processing
+// queues the script again.
+func (s *AnswerScriptService) UpdateStatus(ctx context.Context, id string, data *models.UpdateAnswerScriptStatus) (*models.AnswerScript, error) {
+	before, err := s.repo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if before.ExamId != nil {
+		if err := requireUnlockedExam(s.examRepo, *before.ExamId, "the status of its answer scripts cannot be changed"); err != nil {
+			return nil, err
+		}
+	}
+
+	fields := map[string]interface{}{
+		"status":                data.Status,
+		"processing_error":      nil,
+		"processing_claimed_at": nil,
+	}
+	if data.Status == models.StatusFailed {
+		fields["processing_error"] = data.ProcessingError
+	}
+	return s.updateFields(ctx, models.AuditActionUpdate, before, fields)
+}
+
+// Overrides what matching found for an answer script
+func (s *AnswerScriptService) UpdateMatching(ctx context.Context, id string, data *models.UpdateAnswerScriptMatching) (*models.AnswerScript, error) {
+	before, err := s.repo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if before.ExamId != nil {
+		if err := requireUnlockedExam(s.examRepo, *before.ExamId, "the matching of its answer scripts cannot be changed"); err != nil {
+			return nil, err
+		}
+	}
+
+	fields := map[string]interface{}{}
+	if data.ScannedExamNumber != nil {
+		fields["scanned_exam_number"] = *data.ScannedExamNumber
+	}
+	if data.MatchingConfidence != nil {
+		fields["matching_confidence"] = *data.MatchingConfidence
+	}
+	if data.MatchScore != nil {
+		fields["match_score"] = *data.MatchScore
+	}
+	if data.MatchStatus != nil {
+		fields["match_status"] = *data.MatchStatus
+	}
+	if len(fields) == 0 {
+		return before, nil
+	}
+	return s.updateFields(ctx, models.AuditActionUpdate, before, fields)
+}
+
+// Writes the given columns, then reloads the answer script and records the change
+func (s *AnswerScriptService) updateFields(ctx context.Context, action models.AuditAction, before *models.AnswerScript, fields map[string]interface{}) (*models.AnswerScript, error) {
+	var answerScript *models.AnswerScript
+	err := s.audit.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		if err := repo.UpdateFields(before.Id, fields); err != nil {
+			return err
+		}
+
+		var err error
+		answerScript, err = repo.GetById(before.Id)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, action, AuditEntityAnswerScript, before.Id, before, answerScript)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return answerScript, nil
+}
+
+// Queues an answer script to be processed again, e.g. after it failed
+func (s *AnswerScriptService) Reprocess(ctx context.Context, id string) (*models.AnswerScript, error) {
+	before, err := s.repo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if before.ExamId != nil {
+		if err := requireUnlockedExam(s.examRepo, *before.ExamId, "its answer scripts cannot be processed again"); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.updateFields(ctx, models.AuditActionReprocess, before, map[string]interface{}{
+		"status":                models.StatusProcessing,
+		"processing_error":      nil,
+		"processing_claimed_at": nil,
+	})
+}
+
+// Claims up to limit scripts awaiting processing for the worker. Claims
+// older than staleAfter were abandoned and are handed out again.
+func (s *AnswerScriptService) ClaimForProcessing(limit int, staleAfter time.Duration) ([]models.AnswerScript, error) {
+	return s.repo.ClaimForProcessing(limit, staleAfter)
+}
+
+// Records the exam number the worker read from an answer script and how
+// confident the recognizer was about it
+func (s *AnswerScriptService) RecordRecognized(ctx context.Context, id, examNumber string, confidence float32) error {
+	return s.recordProcessing(ctx, id, map[string]interface{}{
+		"status":                models.StatusUploaded,
+		"scanned_exam_number":   examNumber,
+		"matching_confidence":   confidence,
+		"processing_error":      nil,
+		"processing_claimed_at": nil,
+	})
+}
+
+// Records why the worker could not process an answer script
+func (s *AnswerScriptService) RecordProcessingFailure(ctx context.Context, id, reason string) error {
+	return s.recordProcessing(ctx, id, map[string]interface{}{
+		"status":                models.StatusFailed,
+		"processing_error":      reason,
+		"processing_claimed_at": nil,
+	})
+}
+
+func (s *AnswerScriptService) recordProcessing(ctx context.Context, id string, fields map[string]interface{}) error {
+	before, err := s.repo.GetById(id)
+	if err != nil {
+		return err
+	}
+	_, err = s.updateFields(ctx, models.AuditActionUpdate, before, fields)
+	return err
+}
+
+// Replaces the file of an answer script with a new upload, e.g. a better
+// scan. The record keeps its Id, the previous file is kept as an earlier
+// version and the script is processed again.
+func (s *AnswerScriptService) ReplaceFile(ctx context.Context, id string, file *multipart.FileHeader) (*models.AnswerScript, error) {
+	before, err := s.repo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if before.ExamId != nil {
+		if err := requireUnlockedExam(s.examRepo, *before.ExamId, "its answer scripts cannot be replaced"); err != nil {
+			return nil, err
+		}
+	}
+
+	var answerScript *models.AnswerScript
+	key, err := versionKey(answerScriptKey(before.ExamId, id, file.Filename), before.Version+1)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.versions.replace(ctx, answerScriptFile(before), &models.AnswerScript{}, file, s.uploadLimits(), key, map[string]interface{}{
+		"status":                models.StatusProcessing,
+		"processing_error":      nil,
+		"processing_claimed_at": nil,
+	}, func(tx *gorm.DB) error {
+		var err error
+		answerScript, err = s.repo.WithTx(tx).GetById(id)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionReplaceFile, AuditEntityAnswerScript, id, before, answerScript)
+	}); err != nil {
+		return nil, err
+	}
+	return answerScript, nil
+}
+
+// Lists every file an answer script has had, newest first
+func (s *AnswerScriptService) GetVersions(ctx context.Context, id string) ([]models.FileVersion, error) {
+	answerScript, err := s.repo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.versions.list(ctx, answerScriptFile(answerScript))
+}
+
+// Retrieves a file stream of a version of an answer script's file
+func (s *AnswerScriptService) GetVersionFileStream(id string, version int) (*FileStreamResult, error) {
+	answerScript, err := s.repo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.versions.open(answerScriptFile(answerScript), version)
 }
 
 // Removes an answer script from both database and storage
-func (s *AnswerScriptService) Delete(id string) error {
+func (s *AnswerScriptService) Delete(ctx context.Context, id string) error {
 	answerScript, err := s.repo.GetById(id)
 	if err != nil {
 		return err
 	}
+	if answerScript.ExamId != nil {
+		if err := requireUnlockedExam(s.examRepo, *answerScript.ExamId, "its answer scripts cannot be deleted"); err != nil {
+			return err
+		}
+	}
 
-	// Delete from storage (Minio)
-	if err := s.deleteFromStorage(answerScript.FileName); err != nil {
+	// Delete from database, then from storage including the files it replaced
+	var replaced []string
+	if err := s.audit.Transaction(func(tx *gorm.DB) error {
+		var err error
+		replaced, err = s.versions.deleteVersions(tx, answerScriptFile(answerScript))
+		if err != nil {
+			return err
+		}
+		if err := s.repo.WithTx(tx).Delete(id); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionDelete, AuditEntityAnswerScript, id, answerScript, nil)
+	}); err != nil {
 		return err
 	}
-
-	// Delete from database
-	return s.repo.Delete(id)
+	s.versions.deleteFiles(ctx, append(replaced, answerScript.ObjectKey())...)
+	return nil
 }
 
 // Retrieves a file stream from storage for serving files
@@ -163,28 +544,48 @@ func (s *AnswerScriptService) GetFileStream(id string) (*FileStreamResult, error
 		return nil, err
 	}
 
-	// Get file object from MinIO
-	object, err := s.minioClient.GetObject(
-		context.Background(),
-		s.cfg.MinioStorageBucket,
-		answerScript.FileName,
-		minio.GetObjectOptions{},
-	)
+	return openFileStream(s.storage, answerScript.ObjectKey(), answerScript.FileName)
+}
+
+// Issues a short lived link the signed in user can download the file of
+// an answer script from without going through the API
+func (s *AnswerScriptService) GetDownloadLink(ctx context.Context, id string) (*DownloadLink, error) {
+	answerScript, err := s.repo.GetById(id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get file from storage: %w", err)
+		return nil, err
 	}
 
-	// Get file metadata
-	objectInfo, err := object.Stat()
+	return s.links.issue(ctx, answerScript.ObjectKey(), answerScript.FileName)
+}
+
+// Moves the files of answer scripts stored under their original file
+// name to keys derived from their record Id
+func (s *AnswerScriptService) MigrateStorageKeys(ctx context.Context) (*StorageKeyMigrationResult, error) {
+	answerScripts, err := s.repo.GetAllWithoutStorageKey()
 	if err != nil {
-		object.Close()
-		return nil, fmt.Errorf("failed to get file info: %w", err)
+		return nil, err
+	}
+
+	targets := make([]rekeyTarget, 0, len(*answerScripts))
+	for _, answerScript := range *answerScripts {
+		targets = append(targets, rekeyTarget{
+			id:     answerScript.Id,
+			oldKey: answerScript.FileName,
+			newKey: answerScriptKey(answerScript.ExamId, answerScript.Id, answerScript.FileName),
+		})
 	}
 
-	return &FileStreamResult{
-		Content:     object,
-		ContentType: objectInfo.ContentType,
-		Filename:    answerScript.FileName,
-		Size:        objectInfo.Size,
-	}, nil
+	return rekeyObjects(ctx, s.storage, targets, s.repo.SetStorageKey), nil
+}
+
+// Describes the file an answer script currently points at
+func answerScriptFile(answerScript *models.AnswerScript) currentFile {
+	return currentFile{
+		recordType: models.FileRecordAnswerScript,
+		recordId:   answerScript.Id,
+		fileName:   answerScript.FileName,
+		key:        answerScript.ObjectKey(),
+		version:    answerScript.Version,
+		createdAt:  answerScript.CreatedAt,
+	}
 }
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/auth"
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Entity types recorded in the audit log
+const (
+	AuditEntityStudent            = "student"
+	AuditEntitySubject            = "subject"
+	AuditEntityExam               = "exam"
+	AuditEntityAnswerScript       = "answer_script"
+	AuditEntityMemorandum         = "memorandum"
+	AuditEntityMemorandumQuestion = "memorandum_question"
+	AuditEntityQuestionMark       = "question_mark"
+	AuditEntityExtractedAnswer    = "extracted_answer"
+	AuditEntityUser               = "user"
+)
+
+// Fields left out when comparing the before and after state of an entity
+var ignoredAuditFields = map[string]bool{
+	"updated_at": true,
+}
+
+// Records changes to entities in the append-only audit log
+type AuditService struct {
+	repo *repository.AuditRepository
+}
+
+// Creates a new instance of AuditService
+func NewAuditService(repo *repository.AuditRepository) *AuditService {
+	return &AuditService{repo}
+}
+
+// Runs fn in a single database transaction, so that a change is only
+// committed together with the audit entries recording it. fn makes the
+// change through repositories bound to tx with WithTx and records it with
+// Record in the same tx.
+func (s *AuditService) Transaction(fn func(tx *gorm.DB) error) error {
+	return s.repo.Transaction(fn)
+}
+
+// Records a change to an entity made by the actor carried by ctx in the
+// transaction tx the change is made in. before is nil for created entities
+// and after is nil for deleted ones. Failing to record the change fails
+// the transaction, the change is not made without its entry.
+func (s *AuditService) Record(ctx context.Context, tx *gorm.DB, action models.AuditAction, entityType, entityId string, before, after interface{}) error {
+	entry := &models.AuditEntry{
+		Action:     action,
+		EntityType: entityType,
+		EntityId:   entityId,
+		Before:     auditSnapshot(before),
+		After:      auditSnapshot(after),
+	}
+	entry.Changes = auditChanges(entry.Before, entry.After)
+
+	if user := auth.UserFromContext(ctx); user != nil {
+		entry.ActorId = &user.Id
+		entry.Actor = user.Email
+	} else {
+		entry.Actor = auth.SystemActorFromContext(ctx)
+	}
+
+	if action == models.AuditActionUpdate && len(entry.Changes) == 0 {
+		return nil
+	}
+	if err := s.repo.WithTx(tx).Create(entry); err != nil {
+		return fmt.Errorf("failed to record %s of %s %s in the audit log: %w", action, entityType, entityId, err)
+	}
+	return nil
+}
+
+// Retrieves a page of audit entries
+func (s *AuditService) GetAll(params repository.ListParams) (*repository.Page[models.AuditEntry], error) {
+	return s.repo.GetAll(params)
+}
+
+// Converts an entity to the JSON object it is shown as in the API
+func auditSnapshot(entity interface{}) map[string]interface{} {
+	if entity == nil || reflect.ValueOf(entity).Kind() == reflect.Ptr && reflect.ValueOf(entity).IsNil() {
+		return nil
+	}
+
+	data, err := json.Marshal(entity)
+	if err != nil {
+		log.Warnf("Failed to snapshot %T for the audit log: %v", entity, err)
+		return nil
+	}
+	var snapshot map[string]interface{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		log.Warnf("Failed to snapshot %T for the audit log: %v", entity, err)
+		return nil
+	}
+	return snapshot
+}
+
+// Lists the fields that differ between two snapshots
+func auditChanges(before, after map[string]interface{}) map[string]models.AuditChange {
+	changes := map[string]models.AuditChange{}
+	for field, from := range before {
+		if to := after[field]; !ignoredAuditFields[field] && !reflect.DeepEqual(from, to) {
+			changes[field] = models.AuditChange{From: from, To: to}
+		}
+	}
+	for field, to := range after {
+		if _, ok := before[field]; !ok && !ignoredAuditFields[field] {
+			changes[field] = models.AuditChange{From: nil, To: to}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/auth"
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	// Returned when the email or password is wrong, or the user is inactive
+	ErrInvalidCredentials = errors.New("invalid email or password")
+
+	// Returned when a session token is unknown, expired or belongs to an inactive user
+	ErrInvalidSession = errors.New("invalid or expired session")
+)
+
+// Handles signing users in and out and resolving session tokens
+type AuthService struct {
+	userRepo    *repository.UserRepository
+	sessionRepo *repository.SessionRepository
+	sessionTTL  time.Duration
+}
+
+// Creates a new instance of AuthService
+func NewAuthService(userRepo *repository.UserRepository, sessionRepo *repository.SessionRepository, sessionTTL time.Duration) *AuthService {
+	return &AuthService{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		sessionTTL:  sessionTTL,
+	}
+}
+
+// Signs a user in and returns the token of the new session
+func (s *AuthService) Login(data *models.Login) (string, *models.Session, *models.User, error) {
+	user, err := s.userRepo.GetByEmail(data.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			auth.DummyCheck(data.Password)
+			return "", nil, nil, ErrInvalidCredentials
+		}
+		return "", nil, nil, err
+	}
+	if !auth.CheckPassword(user.PasswordHash, data.Password) || !user.Active {
+		return "", nil, nil, ErrInvalidCredentials
+	}
+
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	session := &models.Session{
+		UserId:    user.Id,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(s.sessionTTL),
+	}
+	if err := s.sessionRepo.Create(session); err != nil {
+		return "", nil, nil, err
+	}
+
+	now := time.Now()
+	user.LastLoginAt = &now
+	if err := s.userRepo.UpdateFields(user.Id, map[string]interface{}{"last_login_at": now}); err != nil {
+		log.Warnf("Failed to record login of user %s: %v", user.Id, err)
+	}
+	if err := s.sessionRepo.DeleteExpired(); err != nil {
+		log.Warnf("Failed to delete expired sessions: %v", err)
+	}
+
+	return token, session, user, nil
+}
+
+// Resolves a session token to the signed in user
+func (s *AuthService) Authenticate(token string) (*models.User, error) {
+	session, err := s.sessionRepo.GetByTokenHash(auth.HashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidSession
+		}
+		return nil, err
+	}
+	if session.User == nil || !session.User.Active {
+		return nil, ErrInvalidSession
+	}
+	return session.User, nil
+}
+
+// Ends the session of a token
+func (s *AuthService) Logout(token string) error {
+	return s.sessionRepo.DeleteByTokenHash(auth.HashToken(token))
+}
+
+// Creates the first admin account when there are no users yet, so that a
+// fresh installation can be signed in to
+func (s *AuthService) EnsureAdmin(email, password string) error {
+	count, err := s.userRepo.Count()
+	if err != nil || count > 0 {
+		return err
+	}
+	if email == "" || password == "" {
+		log.Warn("No users exist, set ADMIN_EMAIL and ADMIN_PASSWORD to create the first admin")
+		return nil
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	log.Infof("Creating admin user %s", email)
+	return s.userRepo.Create(&models.User{
+		Email:        email,
+		Name:         "Administrator",
+		PasswordHash: hash,
+		Role:         models.RoleAdmin,
+		Active:       true,
+	})
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/smartik/api/internal/auth"
+	"github.com/smartik/api/internal/config"
+	"github.com/smartik/api/internal/repository"
+	"github.com/smartik/api/internal/repository/storage"
+	"gorm.io/gorm"
+)
+
+var (
+	// Returned when a download link was altered, has expired or its user
+	// can no longer sign in
+	ErrInvalidDownloadLink = errors.New("invalid or expired download link")
+	// Returned when a download link is requested without a signed in user
+	ErrDownloadLinkUser = errors.New("download links can only be issued to signed in users")
+)
+
+// A short lived URL a file can be downloaded from without signing in
+type DownloadLink struct {
+	Url       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Where a download link leads, either a presigned storage URL to redirect
+// to or, for backends that cannot presign, the file itself
+type DownloadTarget struct {
+	RedirectUrl string
+	File        *FileStreamResult
+}
+
+// What a download link grants, kept short as it is part of the URL
+type downloadClaims struct {
+	Key       string `json:"k"`
+	FileName  string `json:"f"`
+	UserId    string `json:"u"`
+	ExpiresAt int64  `json:"e"`
+}
+
+// Issues and resolves download links. A link is scoped to a single stored
+// object and the user it was issued to, it stops working when it expires
+// or the user is deactivated.
+type DownloadLinkService struct {
+	userRepo *repository.UserRepository
+	storage  storage.Storage
+	signer   *auth.Signer
+	baseUrl  string
+	ttl      time.Duration
+}
+
+// Creates a new instance of DownloadLinkService
+func NewDownloadLinkService(userRepo *repository.UserRepository, storage storage.Storage, signer *auth.Signer, cfg *config.Env) *DownloadLinkService {
+	return &DownloadLinkService{
+		userRepo: userRepo,
+		storage:  storage,
+		signer:   signer,
+		baseUrl:  strings.TrimSuffix(cfg.ServerUrl, "/") + "/api/v1/downloads/",
+		ttl:      cfg.DownloadLinkTTL,
+	}
+}
+
+// Issues a link to the object stored under key for the signed in user
+func (s *DownloadLinkService) issue(ctx context.Context, key, fileName string) (*DownloadLink, error) {
+	userId := currentUserId(ctx)
+	if userId == nil {
+		return nil, ErrDownloadLinkUser
+	}
+
+	expiresAt := time.Now().Add(s.ttl).Truncate(time.Second)
+	payload, err := json.Marshal(downloadClaims{
+		Key:       key,
+		FileName:  fileName,
+		UserId:    *userId,
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DownloadLink{
+		Url:       s.baseUrl + s.signer.Sign(payload),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Resolves the token of a download link. Storage that can presign URLs
+// serves the file itself, for a presigned URL that expires with the link.
+func (s *DownloadLinkService) Resolve(ctx context.Context, token string) (*DownloadTarget, error) {
+	payload, err := s.signer.Verify(token)
+	if err != nil {
+		return nil, ErrInvalidDownloadLink
+	}
+	var claims downloadClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidDownloadLink
+	}
+
+	remaining := time.Until(time.Unix(claims.ExpiresAt, 0))
+	if remaining <= 0 {
+		return nil, ErrInvalidDownloadLink
+	}
+
+	user, err := s.userRepo.GetById(claims.UserId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidDownloadLink
+		}
+		return nil, err
+	}
+	if !user.Active {
+		return nil, ErrInvalidDownloadLink
+	}
+
+	// Presigned URLs are valid for whole seconds only
+	url, err := s.storage.PresignGet(ctx, claims.Key, remaining.Truncate(time.Second)+time.Second)
+	if err == nil {
+		return &DownloadTarget{RedirectUrl: url}, nil
+	}
+	if !errors.Is(err, storage.ErrPresignNotSupported) {
+		return nil, err
+	}
+
+	file, err := openFileStream(s.storage, claims.Key, claims.FileName)
+	if err != nil {
+		return nil, err
+	}
+	return &DownloadTarget{File: file}, nil
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository"
+	"gorm.io/gorm"
+)
+
+// A step in the lifecycle of an exam
+type ExamTransition string
+
+const (
+	ExamTransitionOpen             ExamTransition = "open"
+	ExamTransitionStartMarking     ExamTransition = "start-marking"
+	ExamTransitionSubmitModeration ExamTransition = "submit-moderation"
+	ExamTransitionReturnToMarking  ExamTransition = "return-to-marking"
+	ExamTransitionFinalize         ExamTransition = "finalize"
+	ExamTransitionPublish          ExamTransition = "publish"
+)
+
+// Returned when an exam is not in a status that allows an operation, or
+// the preconditions of a transition are not met
+type ExamStatusError struct {
+	Status models.ExamStatus
+	Reason string
+}
+
+func (e *ExamStatusError) Error() string {
+	return fmt.Sprintf("exam is %s: %s", e.Status, e.Reason)
+}
+
+type examTransition struct {
+	from []models.ExamStatus
+	to   models.ExamStatus
+	// Returns why the exam cannot take the transition, empty when it can
+	check func(s *ExamService, exam *models.Exam) (string, error)
+}
+
+var examTransitions = map[ExamTransition]examTransition{
+	ExamTransitionOpen: {
+		from:  []models.ExamStatus{models.ExamStatusDraft},
+		to:    models.ExamStatusOpen,
+		check: (*ExamService).checkOpen,
+	},
+	ExamTransitionStartMarking: {
+		from:  []models.ExamStatus{models.ExamStatusOpen},
+		to:    models.ExamStatusMarking,
+		check: (*ExamService).checkMemorandum,
+	},
+	ExamTransitionSubmitModeration: {
+		from:  []models.ExamStatus{models.ExamStatusMarking},
+		to:    models.ExamStatusModeration,
+		check: (*ExamService).checkMarkingDone,
+	},
+	ExamTransitionReturnToMarking: {
+		from: []models.ExamStatus{models.ExamStatusModeration},
+		to:   models.ExamStatusMarking,
+	},
+	ExamTransitionFinalize: {
+		from:  []models.ExamStatus{models.ExamStatusModeration},
+		to:    models.ExamStatusFinalized,
+		check: (*ExamService).checkMarkingDone,
+	},
+	ExamTransitionPublish: {
+		from: []models.ExamStatus{models.ExamStatusFinalized},
+		to:   models.ExamStatusPublished,
+	},
+}
+
+// Moves an exam to the next status of its lifecycle. Fails with an
+// ExamStatusError when the exam is in the wrong status or not ready.
+func (s *ExamService) Transition(ctx context.Context, id string, name ExamTransition) (*models.Exam, error) {
+	transition, ok := examTransitions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown exam transition %q", name)
+	}
+
+	before, err := s.repo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if !containsStatus(transition.from, before.Status) {
+		return nil, &ExamStatusError{before.Status, fmt.Sprintf("%s is only possible from %s", name, joinStatuses(transition.from))}
+	}
+	if transition.check != nil {
+		reason, err := transition.check(s, before)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			return nil, &ExamStatusError{before.Status, reason}
+		}
+	}
+
+	var exam *models.Exam
+	err = s.audit.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		// The status is only changed if nobody else changed it in the meantime
+		moved, err := repo.UpdateStatus(id, transition.from, transition.to)
+		if err != nil {
+			return err
+		}
+		if !moved {
+			return &ExamStatusError{before.Status, "the exam was changed by someone else, reload it and try again"}
+		}
+
+		exam, err = repo.GetById(id)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionUpdate, AuditEntityExam, id, before, exam)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return exam, nil
+}
+
+// Scripts are filed by subject and marked out of the total marks
+func (s *ExamService) checkOpen(exam *models.Exam) (string, error) {
+	if exam.SubjectId == nil {
+		return "the exam has no subject", nil
+	}
+	if exam.TotalMarks <= 0 {
+		return "the exam has no total marks", nil
+	}
+	return "", nil
+}
+
+// Marking needs a memorandum whose questions add up to the exam's total marks
+func (s *ExamService) checkMemorandum(exam *models.Exam) (string, error) {
+	questions, err := s.questionRepo.GetByExamId(exam.Id)
+	if err != nil {
+		return "", err
+	}
+	if len(*questions) == 0 {
+		return "the exam has no memorandum with questions", nil
+	}
+	if report := checkAllocation(buildQuestionTree(*questions), exam.TotalMarks); !report.Complete {
+		return "the memorandum is incomplete: " + strings.Join(report.Issues, "; "), nil
+	}
+	return "", nil
+}
+
+// Marks can only be handed on once no script or grading job is still in progress
+func (s *ExamService) checkMarkingDone(exam *models.Exam) (string, error) {
+	active, err := s.jobRepo.HasActiveJob(exam.Id)
+	if err != nil {
+		return "", err
+	}
+	if active {
+		return "the exam is still being graded", nil
+	}
+
+	processing, err := s.scriptRepo.CountByExamAndStatus(exam.Id, models.StatusProcessing)
+	if err != nil {
+		return "", err
+	}
+	if processing > 0 {
+		return fmt.Sprintf("%d answer scripts are still being processed", processing), nil
+	}
+	return "", nil
+}
+
+// Fails with an ExamStatusError unless the exam is in one of the allowed
+// statuses. action describes what is refused, e.g. "marks cannot be changed".
+func requireExamStatus(examRepo *repository.ExamRepository, examId, action string, allowed ...models.ExamStatus) error {
+	exam, err := examRepo.GetById(examId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUnknownExam
+		}
+		return err
+	}
+	if !containsStatus(allowed, exam.Status) {
+		return &ExamStatusError{exam.Status, action}
+	}
+	return nil
+}
+
+// Fails with an ExamStatusError when the marks of the exam are locked
+func requireUnlockedExam(examRepo *repository.ExamRepository, examId, action string) error {
+	return requireExamStatus(examRepo, examId, action,
+		models.ExamStatusDraft, models.ExamStatusOpen, models.ExamStatusMarking, models.ExamStatusModeration)
+}
+
+// Fails with an ExamStatusError when any of the answer scripts belongs to
+// an exam whose marks are locked
+func requireUnlockedScripts(examRepo *repository.ExamRepository, scripts []models.AnswerScript, action string) error {
+	checked := map[string]bool{}
+	for _, script := range scripts {
+		if script.ExamId == nil || checked[*script.ExamId] {
+			continue
+		}
+		checked[*script.ExamId] = true
+		if err := requireUnlockedExam(examRepo, *script.ExamId, action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Records the deletion of answer scripts that the database deleted along
+// with the record they were linked to
+func recordCascadedScripts(ctx context.Context, tx *gorm.DB, audit *AuditService, scripts []models.AnswerScript) error {
+	for i := range scripts {
+		if err := audit.Record(ctx, tx, models.AuditActionDelete, AuditEntityAnswerScript, scripts[i].Id, &scripts[i], nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Fails with an ExamStatusError unless answer scripts can be added to the
+// exam. Late scripts are still accepted while marking.
+func requireUploadableExam(examRepo *repository.ExamRepository, examId string) error {
+	return requireExamStatus(examRepo, examId, "answer scripts cannot be added",
+		models.ExamStatusOpen, models.ExamStatusMarking)
+}
+
+func containsStatus(statuses []models.ExamStatus, status models.ExamStatus) bool {
+	for _, candidate := range statuses {
+		if candidate == status {
+			return true
+		}
+	}
+	return false
+}
+
+func joinStatuses(statuses []models.ExamStatus) string {
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = string(status)
+	}
+	return strings.Join(names, " or ")
+}
@@ -1,30 +1,73 @@
 package service
 
 import (
+	"context"
+	"errors"
+
 	"github.com/smartik/api/internal/models"
 	"github.com/smartik/api/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownSubject  = errors.New("the subject does not exist")
+	ErrUnknownExam     = errors.New("the exam does not exist")
+	ErrSubjectMismatch = errors.New("answer scripts must belong to the same subject as their exam")
 )
 
 // Handles business logic for exam operations
 type ExamService struct {
-	repo *repository.ExamRepository
+	repo         *repository.ExamRepository
+	subjectRepo  *repository.SubjectRepository
+	scriptRepo   *repository.AnswerScriptRepository
+	questionRepo *repository.MemorandumQuestionRepository
+	jobRepo      *repository.GradingJobRepository
+	audit        *AuditService
 }
 
 // Creates a new instance of ExamService
-func NewExamService(repo *repository.ExamRepository) *ExamService {
+func NewExamService(
+	repo *repository.ExamRepository,
+	subjectRepo *repository.SubjectRepository,
+	scriptRepo *repository.AnswerScriptRepository,
+	questionRepo *repository.MemorandumQuestionRepository,
+	jobRepo *repository.GradingJobRepository,
+	audit *AuditService,
+) *ExamService {
 	return &ExamService{
-		repo: repo,
+		repo:         repo,
+		subjectRepo:  subjectRepo,
+		scriptRepo:   scriptRepo,
+		questionRepo: questionRepo,
+		jobRepo:      jobRepo,
+		audit:        audit,
 	}
 }
 
-// Creates a new exam record in the database
-func (s *ExamService) Create(exam *models.Exam) error {
-	return s.repo.Create(exam)
+// Creates a new exam record in the database. New exams start as drafts.
+func (s *ExamService) Create(ctx context.Context, exam *models.Exam) error {
+	if err := s.checkSubject(exam.SubjectId); err != nil {
+		return err
+	}
+	if exam.PaperNumber == 0 {
+		exam.PaperNumber = 1
+	}
+	if exam.AcademicYear == 0 {
+		exam.AcademicYear = exam.Date.Year()
+	}
+	exam.Status = models.ExamStatusDraft
+
+	return s.audit.Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.WithTx(tx).Create(exam); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionCreate, AuditEntityExam, exam.Id, nil, exam)
+	})
 }
 
-// Retrieves all exams from the database
-func (s *ExamService) GetAll() (*[]models.Exam, error) {
-	return s.repo.GetAll()
+// Retrieves a page of exams
+func (s *ExamService) GetAll(params repository.ListParams) (*repository.Page[models.Exam], error) {
+	return s.repo.GetAll(params)
 }
 
 // Retrieves a specific exam by its ID
@@ -32,13 +75,107 @@ func (s *ExamService) GetById(id string) (*models.Exam, error) {
 	return s.repo.GetById(id)
 }
 
-// Modifies an existing exam record
-func (s *ExamService) Update(id string, updateData *models.UpdateExam) (*models.Exam, error) {
-	return s.repo.Update(id, updateData)
+// Modifies an existing exam record. The subject and total marks are fixed
+// once marking started, nothing changes after the exam was finalized.
+func (s *ExamService) Update(ctx context.Context, id string, updateData *models.UpdateExam) (*models.Exam, error) {
+	before, err := s.repo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch before.Status {
+	case models.ExamStatusFinalized, models.ExamStatusPublished:
+		return nil, &ExamStatusError{before.Status, "the exam can no longer be changed"}
+	case models.ExamStatusMarking, models.ExamStatusModeration:
+		if updateData.SubjectId != nil || updateData.TotalMarks != nil {
+			return nil, &ExamStatusError{before.Status, "the subject and total marks cannot change once marking started"}
+		}
+	}
+
+	// Scripts already linked to the exam have to stay consistent with it
+	if updateData.SubjectId != nil {
+		if err := s.checkSubject(updateData.SubjectId); err != nil {
+			return nil, err
+		}
+		conflicts, err := s.scriptRepo.CountOtherSubjects(id, *updateData.SubjectId)
+		if err != nil {
+			return nil, err
+		}
+		if conflicts > 0 {
+			return nil, ErrSubjectMismatch
+		}
+	}
+
+	var exam *models.Exam
+	err = s.audit.Transaction(func(tx *gorm.DB) error {
+		var err error
+		exam, err = s.repo.WithTx(tx).Update(id, updateData)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionUpdate, AuditEntityExam, id, before, exam)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return exam, nil
 }
 
-// Removes an exam from the database
-func (s *ExamService) Delete(id string) error {
-	return s.repo.Delete(id)
+// Removes an exam from the database, unless its marks are locked
+func (s *ExamService) Delete(ctx context.Context, id string) error {
+	before, err := s.repo.GetById(id)
+	if err != nil {
+		return err
+	}
+	if before.Status == models.ExamStatusFinalized || before.Status == models.ExamStatusPublished {
+		return &ExamStatusError{before.Status, "the exam and its marks cannot be deleted"}
+	}
+
+	return s.audit.Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.WithTx(tx).Delete(id); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionDelete, AuditEntityExam, id, before, nil)
+	})
+}
+
+func (s *ExamService) checkSubject(subjectId *string) error {
+	if subjectId == nil {
+		return ErrUnknownSubject
+	}
+	if _, err := s.subjectRepo.GetById(*subjectId); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUnknownSubject
+		}
+		return err
+	}
+	return nil
 }
 
+// Works out the subject of an answer script linked to an exam. A script
+// takes the subject of its exam when none is given, a different one is
+// refused. Exams without a subject accept any.
+func scriptSubject(examRepo *repository.ExamRepository, examId, subjectId *string) (*string, error) {
+	if examId == nil {
+		return subjectId, nil
+	}
+
+	exam, err := examRepo.GetById(*examId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUnknownExam
+		}
+		return nil, err
+	}
+
+	switch {
+	case exam.SubjectId == nil:
+		return subjectId, nil
+	case subjectId == nil:
+		return exam.SubjectId, nil
+	case *subjectId != *exam.SubjectId:
+		return nil, ErrSubjectMismatch
+	default:
+		return subjectId, nil
+	}
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository"
+)
+
+// The file format of an exported mark sheet
+type ExportFormat string
+
+const (
+	ExportFormatCSV  ExportFormat = "csv"
+	ExportFormatXLSX ExportFormat = "xlsx"
+	ExportFormatPDF  ExportFormat = "pdf"
+)
+
+// How many answer scripts are read from the database at a time while exporting
+const exportBatchSize = 500
+
+// Returned when a mark sheet is requested in a format that is not supported
+var ErrUnsupportedExportFormat = errors.New("unsupported export format, use csv, xlsx or pdf")
+
+// Parses the format query parameter of an export, defaulting to CSV
+func ParseExportFormat(value string) (ExportFormat, error) {
+	switch format := ExportFormat(strings.ToLower(value)); format {
+	case "":
+		return ExportFormatCSV, nil
+	case ExportFormatCSV, ExportFormatXLSX, ExportFormatPDF:
+		return format, nil
+	default:
+		return "", ErrUnsupportedExportFormat
+	}
+}
+
+// Returns the MIME type of files in the format
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportFormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case ExportFormatPDF:
+		return "application/pdf"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// Handles building mark sheets of exams and subjects
+type ExportService struct {
+	examRepo     *repository.ExamRepository
+	subjectRepo  *repository.SubjectRepository
+	scriptRepo   *repository.AnswerScriptRepository
+	questionRepo *repository.MemorandumQuestionRepository
+	markRepo     *repository.QuestionMarkRepository
+}
+
+// Creates a new instance of ExportService
+func NewExportService(
+	examRepo *repository.ExamRepository,
+	subjectRepo *repository.SubjectRepository,
+	scriptRepo *repository.AnswerScriptRepository,
+	questionRepo *repository.MemorandumQuestionRepository,
+	markRepo *repository.QuestionMarkRepository,
+) *ExportService {
+	return &ExportService{
+		examRepo:     examRepo,
+		subjectRepo:  subjectRepo,
+		scriptRepo:   scriptRepo,
+		questionRepo: questionRepo,
+		markRepo:     markRepo,
+	}
+}
+
+// The marks of the answer scripts of an exam or subject, ready to be written
+// out. Only the questions are loaded up front, the scripts and their marks
+// are read in batches while writing.
+type MarkSheet struct {
+	Name     string // Used for the file name
+	Title    string
+	sections []markSheetSection
+	service  *ExportService
+}
+
+// The scripts of one exam within a mark sheet
+type markSheetSection struct {
+	title      string
+	conditions map[string]interface{} // Selects the answer scripts of the section
+	questions  []models.MemorandumQuestion
+}
+
+// Prepares the mark sheet of all answer scripts of an exam
+func (s *ExportService) ExamMarkSheet(examId string) (*MarkSheet, error) {
+	exam, err := s.examRepo.GetById(examId)
+	if err != nil {
+		return nil, err
+	}
+
+	section, err := s.examSection(exam, map[string]interface{}{"exam_id": exam.Id})
+	if err != nil {
+		return nil, err
+	}
+	return &MarkSheet{
+		Name:     "exam-" + exam.Id,
+		Title:    "Mark sheet",
+		sections: []markSheetSection{*section},
+		service:  s,
+	}, nil
+}
+
+// Prepares the mark sheet of all answer scripts of a subject, with a section
+// for every exam they were written for
+func (s *ExportService) SubjectMarkSheet(subjectId string) (*MarkSheet, error) {
+	subject, err := s.subjectRepo.GetById(subjectId)
+	if err != nil {
+		return nil, err
+	}
+
+	examIds, err := s.scriptRepo.GetExamIdsBySubjectId(subject.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := &MarkSheet{
+		Name:    "subject-" + subject.Code,
+		Title:   fmt.Sprintf("Mark sheet, %s (%s)", subject.Name, subject.Code),
+		service: s,
+	}
+	var withoutExam bool
+	for _, examId := range examIds {
+		if examId == nil {
+			withoutExam = true
+			continue
+		}
+		exam, err := s.examRepo.GetById(*examId)
+		if err != nil {
+			return nil, err
+		}
+		section, err := s.examSection(exam, map[string]interface{}{"subject_id": subject.Id, "exam_id": exam.Id})
+		if err != nil {
+			return nil, err
+		}
+		sheet.sections = append(sheet.sections, *section)
+	}
+	if withoutExam {
+		sheet.sections = append(sheet.sections, markSheetSection{
+			title:      "No exam",
+			conditions: map[string]interface{}{"subject_id": subject.Id, "exam_id": nil},
+		})
+	}
+	return sheet, nil
+}
+
+func (s *ExportService) examSection(exam *models.Exam, conditions map[string]interface{}) (*markSheetSection, error) {
+	questions, err := s.questionRepo.GetByExamId(exam.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &markSheetSection{
+		title:      examTitle(exam),
+		conditions: conditions,
+		questions:  leafQuestions(buildQuestionTree(*questions)),
+	}, nil
+}
+
+// Names an exam in exported files, e.g. "Mathematics, paper 1 (2025-11-03)"
+func examTitle(exam *models.Exam) string {
+	name := exam.Name
+	if name == "" {
+		name = "Exam"
+	}
+	return fmt.Sprintf("%s, paper %d (%s)", name, exam.PaperNumber, exam.Date.Format("2006-01-02"))
+}
+
+// Returns the name of the file the mark sheet is downloaded as
+func (m *MarkSheet) FileName(format ExportFormat) string {
+	return fmt.Sprintf("%s-marks.%s", m.Name, format)
+}
+
+// Writes the mark sheet in the given format, reading the answer scripts in batches
+func (m *MarkSheet) Write(w io.Writer, format ExportFormat) error {
+	var writer markSheetWriter
+	switch format {
+	case ExportFormatCSV:
+		writer = newCSVMarkSheetWriter(w)
+	case ExportFormatXLSX:
+		writer = newXLSXMarkSheetWriter(w)
+	case ExportFormatPDF:
+		writer = newPDFMarkSheetWriter(w, m.Title)
+	default:
+		return ErrUnsupportedExportFormat
+	}
+
+	for _, section := range m.sections {
+		if err := writer.StartSection(section.title, section.header()); err != nil {
+			return err
+		}
+		if err := m.writeSection(writer, section); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+func (m *MarkSheet) writeSection(writer markSheetWriter, section markSheetSection) error {
+	for offset := 0; ; offset += exportBatchSize {
+		scripts, err := m.service.scriptRepo.GetMarkSheetBatch(section.conditions, offset, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(*scripts) == 0 {
+			return nil
+		}
+
+		ids := make([]string, len(*scripts))
+		for i, script := range *scripts {
+			ids[i] = script.Id
+		}
+		marks, err := m.service.markRepo.GetByAnswerScriptIds(ids)
+		if err != nil {
+			return err
+		}
+		awarded := map[string]map[string]int{}
+		for _, mark := range *marks {
+			if awarded[mark.AnswerScriptId] == nil {
+				awarded[mark.AnswerScriptId] = map[string]int{}
+			}
+			awarded[mark.AnswerScriptId][mark.QuestionId] = mark.Awarded
+		}
+
+		for _, script := range *scripts {
+			if err := writer.WriteRow(section.row(script, awarded[script.Id])); err != nil {
+				return err
+			}
+		}
+		if len(*scripts) < exportBatchSize {
+			return nil
+		}
+	}
+}
+
+func (s markSheetSection) header() []string {
+	header := []string{"Exam number", "First name", "Last name"}
+	for _, question := range s.questions {
+		header = append(header, fmt.Sprintf("Q%s (%d)", question.Number, question.Marks))
+	}
+	return append(header, "Total", "Out of", "%")
+}
+
+// Builds the row of an answer script. Unmarked questions are left blank.
+func (s markSheetSection) row(script models.AnswerScript, awarded map[string]int) []string {
+	row := make([]string, 0, len(s.questions)+6)
+	if script.Student != nil {
+		row = append(row, script.Student.ExamNumber, script.Student.FirstName, script.Student.LastName)
+	} else {
+		row = append(row, optionalString(script.ScannedExamNumber), "", "")
+	}
+
+	for _, question := range s.questions {
+		if marks, ok := awarded[question.Id]; ok {
+			row = append(row, strconv.Itoa(marks))
+		} else {
+			row = append(row, "")
+		}
+	}
+
+	row = append(row, optionalInt(script.TotalMarks), optionalInt(script.MaxMarks))
+	if script.TotalMarks != nil && script.MaxMarks != nil && *script.MaxMarks > 0 {
+		row = append(row, strconv.FormatFloat(float64(*script.TotalMarks)*100/float64(*script.MaxMarks), 'f', 1, 64))
+	} else {
+		row = append(row, "")
+	}
+	return row
+}
+
+func optionalString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func optionalInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
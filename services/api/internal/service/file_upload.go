@@ -1,26 +1,53 @@
 package service
 
-import "io"
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/smartik/api/internal/repository/storage"
+)
 
 type UploadResult struct {
 	FailedUploads []FileUploadError `json:"failed_uploads"`
 }
 
 type FileUploadError struct {
-	Filename string
-	Error    string
+	Filename string          `json:"filename"`
+	Code     UploadErrorCode `json:"code,omitempty"`
+	Error    string          `json:"error"`
 }
 
 type FileStreamResult struct {
-	Content     io.ReadCloser
-	ContentType string
-	Filename    string
-	Size        int64
+	Content      storage.Object
+	ContentType  string
+	Filename     string
+	Size         int64
+	ETag         string
+	LastModified time.Time
 }
 
-func (u *UploadResult) addUploadError(filename, errorMsg string) {
+func (u *UploadResult) addUploadError(filename string, code UploadErrorCode, errorMsg string) {
 	u.FailedUploads = append(u.FailedUploads, FileUploadError{
 		Filename: filename,
+		Code:     code,
 		Error:    errorMsg,
 	})
 }
+
+// Opens a stored object for serving it under the given filename
+func openFileStream(store storage.Storage, key, filename string) (*FileStreamResult, error) {
+	object, info, err := store.Get(context.Background(), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file from storage: %w", err)
+	}
+
+	return &FileStreamResult{
+		Content:      object,
+		ContentType:  info.ContentType,
+		Filename:     filename,
+		Size:         info.Size,
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+	}, nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/smartik/api/internal/pdfsplit"
+)
+
+// Why a file failed to upload, reported with every entry of FailedUploads
+type UploadErrorCode string
+
+const (
+	UploadErrorEmpty        UploadErrorCode = "empty_file"
+	UploadErrorTooLarge     UploadErrorCode = "file_too_large"
+	UploadErrorUnsupported  UploadErrorCode = "unsupported_type"
+	UploadErrorCorrupt      UploadErrorCode = "corrupt_file"
+	UploadErrorTooManyPages UploadErrorCode = "too_many_pages"
+	UploadErrorFailed       UploadErrorCode = "upload_failed" // The file was fine but could not be stored
+)
+
+// Content types of the scans and documents that are accepted
+var allowedUploadTypes = []string{"application/pdf", "image/png", "image/jpeg", "image/tiff"}
+
+// Returned when an uploaded file is refused because of its content
+type FileRejectedError struct {
+	Code   UploadErrorCode
+	Reason string
+}
+
+func (e *FileRejectedError) Error() string {
+	return e.Reason
+}
+
+// Limits applied to an uploaded file. Zero disables a limit.
+type uploadLimits struct {
+	maxSize  int64
+	maxPages int
+}
+
+// Checks the size and content of an uploaded file and returns its content
+// type as sniffed from the content, ignoring what the client claimed.
+// PDFs are also checked for corruption and their number of pages. r is
+// rewound to the start afterwards.
+func inspectUpload(r io.ReadSeeker, size int64, limits uploadLimits) (string, error) {
+	if err := checkUploadSize(size, limits.maxSize); err != nil {
+		return "", err
+	}
+
+	contentType, err := sniffContentType(r)
+	if err != nil {
+		return "", err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	if contentType == "application/pdf" {
+		doc, err := pdfsplit.Open(r)
+		if err != nil {
+			if errors.Is(err, pdfsplit.ErrInvalidPdf) {
+				return "", &FileRejectedError{UploadErrorCorrupt, err.Error()}
+			}
+			return "", err
+		}
+		if limits.maxPages > 0 && doc.PageCount() > limits.maxPages {
+			return "", &FileRejectedError{UploadErrorTooManyPages,
+				fmt.Sprintf("the PDF has %d pages, at most %d are accepted", doc.PageCount(), limits.maxPages)}
+		}
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+	}
+	return contentType, nil
+}
+
+// Refuses empty files and files above the size limit
+func checkUploadSize(size, maxSize int64) error {
+	if size == 0 {
+		return &FileRejectedError{UploadErrorEmpty, "the file is empty"}
+	}
+	if maxSize > 0 && size > maxSize {
+		return &FileRejectedError{UploadErrorTooLarge,
+			fmt.Sprintf("the file is %s, at most %s is accepted", formatBytes(size), formatBytes(maxSize))}
+	}
+	return nil
+}
+
+// Detects the content type from the first bytes read from r, refusing
+// anything but the allowed types
+func sniffContentType(r io.Reader) (string, error) {
+	detected, err := mimetype.DetectReader(r)
+	if err != nil {
+		return "", err
+	}
+	for _, allowed := range allowedUploadTypes {
+		if detected.Is(allowed) {
+			return allowed, nil
+		}
+	}
+	return "", &FileRejectedError{UploadErrorUnsupported,
+		fmt.Sprintf("files of type %s are not accepted, upload a PDF, PNG, JPEG or TIFF", detected.String())}
+}
+
+// Returns the code reported for a failed upload
+func uploadErrorCode(err error) UploadErrorCode {
+	var rejected *FileRejectedError
+	if errors.As(err, &rejected) {
+		return rejected.Code
+	}
+	return UploadErrorFailed
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, prefix := float64(size), ""
+	for _, p := range []string{"K", "M", "G", "T"} {
+		if value < unit {
+			break
+		}
+		value /= unit
+		prefix = p
+	}
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", value), ".0") + " " + prefix + "iB"
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository"
+	"github.com/smartik/api/internal/repository/storage"
+	"gorm.io/gorm"
+)
+
+var (
+	// Returned when the file of a record was replaced by someone else in the meantime
+	ErrFileChanged = errors.New("the file was replaced by someone else, reload it and try again")
+	// Returned when a record has no file with the requested version
+	ErrUnknownFileVersion = errors.New("the file version does not exist")
+)
+
+// The file a record currently points at
+type currentFile struct {
+	recordType models.FileRecordType
+	recordId   string
+	fileName   string
+	key        string
+	version    int
+	createdAt  time.Time // When the record, and so its first file, was created
+}
+
+// Keeps the files of answer scripts and memoranda together with the files
+// they replaced. Records created before versions were kept have no
+// version rows until their file is first replaced.
+type fileVersions struct {
+	repo    *repository.FileVersionRepository
+	storage storage.Storage
+	audit   *AuditService
+}
+
+// Stores an uploaded file under key as the next version of the record's
+// file and points the record at it, together with the given fields. key
+// has to be unique to this replacement, see versionKey. The previous file
+// stays in storage. record is called in the transaction that
+// replaces the file to record the change in the audit log.
+func (v *fileVersions) replace(ctx context.Context, current currentFile, model interface{}, file *multipart.FileHeader, limits uploadLimits, key string, fields map[string]interface{}, record func(tx *gorm.DB) error) error {
+	src, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("Failed to open file: %w", err)
+	}
+	defer src.Close()
+
+	// The content type the client claims is not trusted
+	contentType, err := inspectUpload(src, file.Size, limits)
+	if err != nil {
+		return err
+	}
+
+	var versions []models.FileVersion
+	if _, err := v.repo.GetVersion(current.recordType, current.recordId, current.version); err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		// The file being replaced was uploaded before versions were kept
+		first, err := v.describe(ctx, current)
+		if err != nil {
+			return err
+		}
+		versions = append(versions, *first)
+	}
+
+	if _, err := v.storage.Put(ctx, key, src, file.Size, contentType); err != nil {
+		return fmt.Errorf("Failed to upload to storage: %w", err)
+	}
+
+	next := current.version + 1
+	versions = append(versions, models.FileVersion{
+		RecordType:  current.recordType,
+		RecordId:    current.recordId,
+		Version:     next,
+		FileName:    file.Filename,
+		StorageKey:  key,
+		ContentType: contentType,
+		Size:        file.Size,
+		UploadedBy:  currentUserId(ctx),
+	})
+	fields["file_name"] = file.Filename
+	fields["storage_key"] = key
+	fields["version"] = next
+
+	err = v.audit.Transaction(func(tx *gorm.DB) error {
+		replaced, err := v.repo.WithTx(tx).Replace(model, current.recordId, current.version, fields, versions)
+		if err != nil {
+			return err
+		}
+		if !replaced {
+			return ErrFileChanged
+		}
+		return record(tx)
+	})
+	if err != nil {
+		// The key is unique to this replacement, no record points at it
+		if deleteErr := v.storage.Delete(context.Background(), key); deleteErr != nil {
+			log.Errorf("Failed to delete replacement file after it was refused: %v", deleteErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Lists every file the record has had, newest first
+func (v *fileVersions) list(ctx context.Context, current currentFile) ([]models.FileVersion, error) {
+	recorded, err := v.repo.GetByRecord(current.recordType, current.recordId)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := *recorded
+	if len(versions) == 0 || versions[0].Version != current.version {
+		first, err := v.describe(ctx, current)
+		if err != nil {
+			return nil, err
+		}
+		versions = append([]models.FileVersion{*first}, versions...)
+	}
+	for i := range versions {
+		versions[i].Current = versions[i].Version == current.version
+	}
+	return versions, nil
+}
+
+// Opens a file version of the record for serving
+func (v *fileVersions) open(current currentFile, version int) (*FileStreamResult, error) {
+	if version == current.version {
+		return openFileStream(v.storage, current.key, current.fileName)
+	}
+
+	fileVersion, err := v.repo.GetVersion(current.recordType, current.recordId, version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUnknownFileVersion
+		}
+		return nil, err
+	}
+	return openFileStream(v.storage, fileVersion.StorageKey, fileVersion.FileName)
+}
+
+// Removes the version rows of a record in the transaction tx and returns
+// the keys of the files it replaced, to be deleted with deleteFiles once
+// tx is committed. The current file is left to the caller.
+func (v *fileVersions) deleteVersions(tx *gorm.DB, current currentFile) ([]string, error) {
+	repo := v.repo.WithTx(tx)
+	versions, err := repo.GetByRecord(current.recordType, current.recordId)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, version := range *versions {
+		if version.StorageKey != current.key {
+			keys = append(keys, version.StorageKey)
+		}
+	}
+	return keys, repo.DeleteByRecord(current.recordType, current.recordId)
+}
+
+// Deletes files of a removed record from storage. The record is already
+// gone, so failures are logged and leave the file behind.
+func (v *fileVersions) deleteFiles(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := v.storage.Delete(ctx, key); err != nil {
+			log.Errorf("Failed to delete %s of a removed record from storage: %v", key, err)
+		}
+	}
+}
+
+// Describes the current file of a record that has no version rows yet
+func (v *fileVersions) describe(ctx context.Context, current currentFile) (*models.FileVersion, error) {
+	version := &models.FileVersion{
+		RecordType:  current.recordType,
+		RecordId:    current.recordId,
+		Version:     current.version,
+		FileName:    current.fileName,
+		StorageKey:  current.key,
+		ContentType: "application/octet-stream",
+	}
+	version.CreatedAt = current.createdAt
+
+	info, err := v.storage.Stat(ctx, current.key)
+	if err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+		return nil, err
+	}
+	if info != nil {
+		version.Size = info.Size
+		if info.ContentType != "" {
+			version.ContentType = info.ContentType
+		}
+	}
+	return version, nil
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/smartik/api/internal/models"
+)
+
+// The score a grader awarded for a single question
+type QuestionScore struct {
+	QuestionId string `json:"question_id"`
+	Awarded    int    `json:"awarded"`
+	MaxMarks   int    `json:"max_marks"`
+	Rationale  string `json:"rationale"` // Why the marks were awarded, shown to the people moderating the result
+}
+
+// Grades the answers extracted from an answer script against the memorandum
+type Grader interface {
+	// Name identifies the grader in the marks it awards
+	Name() string
+
+	// Grade scores the answers, keyed by question Id, for the given markable
+	// questions. Questions the grader cannot decide on, or that have no
+	// answer, are left out of the result so that they can be marked by hand.
+	// An answer that was extracted but is blank scores 0.
+	Grade(ctx context.Context, answers map[string]string, questions []models.MemorandumQuestion) ([]QuestionScore, error)
+}
+
+var numberPattern = regexp.MustCompile(`[-+]?\d+(?:[.,]\d+)?`)
+
+// Grades answers with simple rules configured on each memorandum question,
+// without calling out to any external service
+type RuleBasedGrader struct{}
+
+// Creates a new instance of RuleBasedGrader
+func NewRuleBasedGrader() *RuleBasedGrader {
+	return &RuleBasedGrader{}
+}
+
+func (g *RuleBasedGrader) Name() string {
+	return "rule-based"
+}
+
+func (g *RuleBasedGrader) Grade(ctx context.Context, answers map[string]string, questions []models.MemorandumQuestion) ([]QuestionScore, error) {
+	scores := make([]QuestionScore, 0, len(questions))
+	for _, question := range questions {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Without an extracted answer the question is left to be marked by hand
+		answer, ok := answers[question.Id]
+		if !ok {
+			continue
+		}
+
+		var score *QuestionScore
+		switch question.MatchMode {
+		case models.MatchModeKeywords:
+			score = gradeKeywords(question, answer)
+		case models.MatchModeNumeric:
+			score = gradeNumeric(question, answer)
+		default:
+			score = gradeExact(question, answer)
+		}
+		if score == nil {
+			continue
+		}
+		if strings.TrimSpace(answer) == "" {
+			score.Awarded = 0
+			score.Rationale = "No answer was given"
+		}
+		scores = append(scores, *score)
+	}
+	return scores, nil
+}
+
+// Awards full marks when the answer equals the expected answer or one of
+// the alternatives, ignoring case, punctuation and spacing
+func gradeExact(question models.MemorandumQuestion, answer string) *QuestionScore {
+	expected := expectedAnswers(question)
+	if len(expected) == 0 {
+		return nil
+	}
+
+	normalized := normalizeAnswer(answer)
+	for i, candidate := range expected {
+		if normalized == normalizeAnswer(candidate) {
+			rationale := "Matches the expected answer"
+			if i > 0 {
+				rationale = fmt.Sprintf("Matches the alternative answer %q", candidate)
+			}
+			return &QuestionScore{QuestionId: question.Id, Awarded: question.Marks, MaxMarks: question.Marks, Rationale: rationale}
+		}
+	}
+	return &QuestionScore{
+		QuestionId: question.Id,
+		MaxMarks:   question.Marks,
+		Rationale:  "Does not match the expected answer or any alternative",
+	}
+}
+
+// Awards marks in proportion to the keywords that appear in the answer.
+// Questions without keywords fall back to exact matching.
+func gradeKeywords(question models.MemorandumQuestion, answer string) *QuestionScore {
+	keywords := make([]string, 0, len(question.Keywords))
+	for _, keyword := range question.Keywords {
+		if normalizeAnswer(keyword) != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+	if len(keywords) == 0 {
+		return gradeExact(question, answer)
+	}
+
+	padded := " " + normalizeAnswer(answer) + " "
+	var found, missing []string
+	for _, keyword := range keywords {
+		if strings.Contains(padded, " "+normalizeAnswer(keyword)+" ") {
+			found = append(found, keyword)
+		} else {
+			missing = append(missing, keyword)
+		}
+	}
+
+	rationale := fmt.Sprintf("Found %d of %d keywords", len(found), len(keywords))
+	if len(found) > 0 {
+		rationale += ": " + strings.Join(found, ", ")
+	}
+	if len(missing) > 0 {
+		rationale += "; missing: " + strings.Join(missing, ", ")
+	}
+	return &QuestionScore{
+		QuestionId: question.Id,
+		Awarded:    question.Marks * len(found) / len(keywords),
+		MaxMarks:   question.Marks,
+		Rationale:  rationale,
+	}
+}
+
+// Awards full marks when the first number in the answer is within the
+// question's tolerance of the expected answer or one of the alternatives
+func gradeNumeric(question models.MemorandumQuestion, answer string) *QuestionScore {
+	var expected []float64
+	for _, candidate := range expectedAnswers(question) {
+		if value, ok := parseNumber(candidate); ok {
+			expected = append(expected, value)
+		}
+	}
+	if len(expected) == 0 {
+		return nil
+	}
+
+	given, ok := parseNumber(answer)
+	if !ok {
+		return &QuestionScore{QuestionId: question.Id, MaxMarks: question.Marks, Rationale: "The answer contains no number"}
+	}
+
+	// Allow for floating point noise so a tolerance of 0.1 accepts 0.1 off
+	const epsilon = 1e-9
+	for _, value := range expected {
+		if math.Abs(given-value) <= question.Tolerance+epsilon {
+			return &QuestionScore{
+				QuestionId: question.Id,
+				Awarded:    question.Marks,
+				MaxMarks:   question.Marks,
+				Rationale:  fmt.Sprintf("%g is within %g of the expected %g", given, question.Tolerance, value),
+			}
+		}
+	}
+	return &QuestionScore{
+		QuestionId: question.Id,
+		MaxMarks:   question.Marks,
+		Rationale:  fmt.Sprintf("%g is not within %g of the expected %g", given, question.Tolerance, expected[0]),
+	}
+}
+
+func expectedAnswers(question models.MemorandumQuestion) []string {
+	var expected []string
+	for _, candidate := range append([]string{question.ExpectedAnswer}, question.AlternativeAnswers...) {
+		if strings.TrimSpace(candidate) != "" {
+			expected = append(expected, candidate)
+		}
+	}
+	return expected
+}
+
+// Lowercases an answer and reduces it to words separated by single spaces
+func normalizeAnswer(answer string) string {
+	words := strings.FieldsFunc(strings.ToLower(answer), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// Parses the first number in a string, accepting a comma as decimal separator
+func parseNumber(s string) (float64, bool) {
+	match := numberPattern.FindString(s)
+	if match == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(strings.Replace(match, ",", ".", 1), 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/smartik/api/internal/models"
+)
+
+func TestRuleBasedGrader(t *testing.T) {
+	exact := testQuestion(models.MatchModeExact, 2)
+	exact.ExpectedAnswer = "Photosynthesis"
+	exact.AlternativeAnswers = []string{"", "photo synthesis"}
+
+	keywords := testQuestion(models.MatchModeKeywords, 4)
+	keywords.Keywords = []string{"chlorophyll", "light energy"}
+
+	threeKeywords := testQuestion(models.MatchModeKeywords, 4)
+	threeKeywords.Keywords = []string{"sun", "water", "carbon dioxide"}
+
+	keywordsFallback := testQuestion(models.MatchModeKeywords, 3)
+	keywordsFallback.ExpectedAnswer = "Mitochondria"
+	keywordsFallback.Keywords = []string{" ", "--"}
+
+	numeric := testQuestion(models.MatchModeNumeric, 3)
+	numeric.ExpectedAnswer = "9.8"
+	numeric.AlternativeAnswers = []string{"10"}
+	numeric.Tolerance = 0.1
+
+	strictNumeric := testQuestion(models.MatchModeNumeric, 1)
+	strictNumeric.ExpectedAnswer = "-3"
+
+	defaultMode := testQuestion("", 1)
+	defaultMode.ExpectedAnswer = "Paris"
+
+	noAnswer := testQuestion(models.MatchModeExact, 2)
+	noAnswer.AlternativeAnswers = []string{"  "}
+
+	notNumeric := testQuestion(models.MatchModeNumeric, 2)
+	notNumeric.ExpectedAnswer = "about ten"
+
+	tests := []struct {
+		name          string
+		question      models.MemorandumQuestion
+		answer        *string // nil when no answer was extracted for the question
+		wantSkipped   bool    // Left out of the result to be marked by hand
+		wantAwarded   int
+		wantRationale string
+	}{
+		// exact
+		{name: "exact match", question: exact, answer: stringPtr("Photosynthesis"), wantAwarded: 2, wantRationale: "Matches the expected answer"},
+		{name: "exact ignores case, punctuation and spacing", question: exact, answer: stringPtr("  PHOTOSYNTHESIS! "), wantAwarded: 2, wantRationale: "Matches the expected answer"},
+		{name: "exact matches an alternative", question: exact, answer: stringPtr("Photo-synthesis"), wantAwarded: 2, wantRationale: `alternative answer "photo synthesis"`},
+		{name: "exact wrong answer", question: exact, answer: stringPtr("Respiration"), wantAwarded: 0, wantRationale: "Does not match"},
+		{name: "exact empty answer", question: exact, answer: stringPtr("  "), wantAwarded: 0, wantRationale: "No answer was given"},
+		{name: "exact missing answer is skipped", question: exact, wantSkipped: true},
+		{name: "exact without expected answer is skipped", question: noAnswer, answer: stringPtr("anything"), wantSkipped: true},
+		{name: "no match mode grades exactly", question: defaultMode, answer: stringPtr("paris"), wantAwarded: 1, wantRationale: "Matches the expected answer"},
+
+		// keywords
+		{name: "all keywords", question: keywords, answer: stringPtr("Chlorophyll absorbs light energy."), wantAwarded: 4, wantRationale: "Found 2 of 2 keywords"},
+		{name: "half the keywords earn half the marks", question: keywords, answer: stringPtr("It uses chlorophyll"), wantAwarded: 2, wantRationale: "missing: light energy"},
+		{name: "partial credit is rounded down", question: threeKeywords, answer: stringPtr("Water"), wantAwarded: 1, wantRationale: "Found 1 of 3 keywords: water"},
+		{name: "keywords match whole words", question: keywords, answer: stringPtr("chlorophyllous lightenergy"), wantAwarded: 0, wantRationale: "Found 0 of 2 keywords"},
+		{name: "keywords ignore case and punctuation", question: keywords, answer: stringPtr("LIGHT-ENERGY, CHLOROPHYLL"), wantAwarded: 4},
+		{name: "keywords empty answer", question: keywords, answer: stringPtr(""), wantAwarded: 0, wantRationale: "No answer was given"},
+		{name: "keywords missing answer is skipped", question: keywords, wantSkipped: true},
+		{name: "blank keywords fall back to exact", question: keywordsFallback, answer: stringPtr("mitochondria"), wantAwarded: 3, wantRationale: "Matches the expected answer"},
+
+		// numeric
+		{name: "numeric exact value", question: numeric, answer: stringPtr("9.8"), wantAwarded: 3, wantRationale: "within 0.1 of the expected 9.8"},
+		{name: "numeric at the tolerance", question: numeric, answer: stringPtr("g = 9.7 m/s²"), wantAwarded: 3},
+		{name: "numeric decimal comma", question: numeric, answer: stringPtr("9,85"), wantAwarded: 3},
+		{name: "numeric matches an alternative", question: numeric, answer: stringPtr("10.05"), wantAwarded: 3, wantRationale: "of the expected 10"},
+		{name: "numeric outside the tolerance", question: numeric, answer: stringPtr("9.6"), wantAwarded: 0, wantRationale: "9.6 is not within 0.1 of the expected 9.8"},
+		{name: "numeric uses the first number", question: numeric, answer: stringPtr("9.0, or maybe 9.8"), wantAwarded: 0},
+		{name: "numeric negative value", question: strictNumeric, answer: stringPtr("-3"), wantAwarded: 1},
+		{name: "numeric sign matters", question: strictNumeric, answer: stringPtr("3"), wantAwarded: 0},
+		{name: "numeric answer without a number", question: numeric, answer: stringPtr("about ten"), wantAwarded: 0, wantRationale: "The answer contains no number"},
+		{name: "numeric empty answer", question: numeric, answer: stringPtr(""), wantAwarded: 0, wantRationale: "No answer was given"},
+		{name: "numeric missing answer is skipped", question: numeric, wantSkipped: true},
+		{name: "numeric without a numeric expected answer is skipped", question: notNumeric, answer: stringPtr("10"), wantSkipped: true},
+	}
+
+	grader := NewRuleBasedGrader()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answers := map[string]string{}
+			if tt.answer != nil {
+				answers[tt.question.Id] = *tt.answer
+			}
+
+			scores, err := grader.Grade(context.Background(), answers, []models.MemorandumQuestion{tt.question})
+			if err != nil {
+				t.Fatalf("Grade: %v", err)
+			}
+			if tt.wantSkipped {
+				if len(scores) != 0 {
+					t.Errorf("scored %+v, want the question skipped", scores[0])
+				}
+				return
+			}
+			if len(scores) != 1 {
+				t.Fatalf("got %d scores, want 1", len(scores))
+			}
+
+			score := scores[0]
+			if score.QuestionId != tt.question.Id || score.MaxMarks != tt.question.Marks {
+				t.Errorf("score is for %s out of %d, want %s out of %d",
+					score.QuestionId, score.MaxMarks, tt.question.Id, tt.question.Marks)
+			}
+			if score.Awarded != tt.wantAwarded {
+				t.Errorf("awarded %d, want %d (%s)", score.Awarded, tt.wantAwarded, score.Rationale)
+			}
+			if !strings.Contains(score.Rationale, tt.wantRationale) {
+				t.Errorf("rationale %q does not contain %q", score.Rationale, tt.wantRationale)
+			}
+		})
+	}
+}
+
+func TestRuleBasedGraderGradesEveryQuestion(t *testing.T) {
+	first := testQuestion(models.MatchModeExact, 2)
+	first.Id = "q1"
+	first.ExpectedAnswer = "A"
+	skipped := testQuestion(models.MatchModeExact, 2)
+	skipped.Id = "q2"
+	last := testQuestion(models.MatchModeExact, 3)
+	last.Id = "q3"
+	last.ExpectedAnswer = "C"
+
+	scores, err := NewRuleBasedGrader().Grade(context.Background(),
+		map[string]string{"q1": "a", "q3": "c", "unknown": "b"},
+		[]models.MemorandumQuestion{first, skipped, last})
+	if err != nil {
+		t.Fatalf("Grade: %v", err)
+	}
+	if len(scores) != 2 || scores[0].QuestionId != "q1" || scores[1].QuestionId != "q3" {
+		t.Fatalf("scores = %+v, want q1 and q3", scores)
+	}
+	if scores[0].Awarded != 2 || scores[1].Awarded != 3 {
+		t.Errorf("awarded %d and %d, want 2 and 3", scores[0].Awarded, scores[1].Awarded)
+	}
+}
+
+func TestRuleBasedGraderStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	question := testQuestion(models.MatchModeExact, 1)
+	question.ExpectedAnswer = "A"
+	if _, err := NewRuleBasedGrader().Grade(ctx, map[string]string{}, []models.MemorandumQuestion{question}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Grade = %v, want context.Canceled", err)
+	}
+}
+
+func testQuestion(mode models.MatchMode, marks int) models.MemorandumQuestion {
+	question := models.MemorandumQuestion{Number: "1", MatchMode: mode, Marks: marks}
+	question.Id = "q"
+	return question
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
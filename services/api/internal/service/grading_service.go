@@ -0,0 +1,425 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/auth"
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Prefix of the marked_by value of marks awarded by a grader
+const autoMarkerPrefix = "auto:"
+
+// Longest rationale kept as the comment of a mark
+const maxRationaleLength = 1000
+
+const (
+	// How often a running job refreshes its heartbeat
+	jobHeartbeatInterval = 30 * time.Second
+	// How long a job may go without a heartbeat before it is considered
+	// abandoned by a process that stopped
+	jobStaleAfter = 2 * time.Minute
+)
+
+var (
+	// Returned when an exam is already being graded
+	ErrGradingJobActive = errors.New("the exam is already being graded")
+
+	// Returned when extracted answers cannot be recorded as submitted
+	ErrInvalidAnswer = errors.New("invalid answer")
+
+	// Stops a job that is no longer running, e.g. failed as abandoned
+	errGradingJobStopped = errors.New("the grading job is no longer running")
+)
+
+// Handles extracted answers and automated grading of answer scripts
+type GradingService struct {
+	jobRepo      *repository.GradingJobRepository
+	examRepo     *repository.ExamRepository
+	scriptRepo   *repository.AnswerScriptRepository
+	questionRepo *repository.MemorandumQuestionRepository
+	answerRepo   *repository.ExtractedAnswerRepository
+	markRepo     *repository.QuestionMarkRepository
+	grader       Grader
+	audit        *AuditService
+}
+
+// Creates a new instance of GradingService
+func NewGradingService(
+	jobRepo *repository.GradingJobRepository,
+	examRepo *repository.ExamRepository,
+	scriptRepo *repository.AnswerScriptRepository,
+	questionRepo *repository.MemorandumQuestionRepository,
+	answerRepo *repository.ExtractedAnswerRepository,
+	markRepo *repository.QuestionMarkRepository,
+	grader Grader,
+	audit *AuditService,
+) *GradingService {
+	return &GradingService{
+		jobRepo:      jobRepo,
+		examRepo:     examRepo,
+		scriptRepo:   scriptRepo,
+		questionRepo: questionRepo,
+		answerRepo:   answerRepo,
+		markRepo:     markRepo,
+		grader:       grader,
+		audit:        audit,
+	}
+}
+
+// Retrieves the answers extracted from an answer script
+func (s *GradingService) GetAnswers(answerScriptId string) (*[]models.ExtractedAnswer, error) {
+	if _, err := s.scriptRepo.GetById(answerScriptId); err != nil {
+		return nil, err
+	}
+	return s.answerRepo.GetByAnswerScriptId(answerScriptId)
+}
+
+// Records answers extracted from an answer script, replacing answers
+// recorded earlier for the same questions
+func (s *GradingService) SaveAnswers(ctx context.Context, answerScriptId string, data *models.RecordExtractedAnswers) (*[]models.ExtractedAnswer, error) {
+	answerScript, err := s.scriptRepo.GetById(answerScriptId)
+	if err != nil {
+		return nil, err
+	}
+	if answerScript.ExamId == nil {
+		return nil, fmt.Errorf("%w: the answer script is not linked to an exam", ErrInvalidAnswer)
+	}
+	if err := requireUnlockedExam(s.examRepo, *answerScript.ExamId, "answers cannot be changed"); err != nil {
+		return nil, err
+	}
+
+	questions, err := markableQuestions(s.questionRepo, *answerScript.ExamId)
+	if err != nil {
+		return nil, err
+	}
+
+	answers := make([]models.ExtractedAnswer, 0, len(data.Answers))
+	seen := map[string]bool{}
+	for _, input := range data.Answers {
+		question, ok := questions[input.QuestionId]
+		if !ok {
+			return nil, fmt.Errorf("%w: question %s is not a markable question of this exam", ErrInvalidAnswer, input.QuestionId)
+		}
+		if seen[input.QuestionId] {
+			return nil, fmt.Errorf("%w: question %s is answered more than once", ErrInvalidAnswer, question.Number)
+		}
+		seen[input.QuestionId] = true
+
+		answers = append(answers, models.ExtractedAnswer{
+			AnswerScriptId: answerScriptId,
+			QuestionId:     input.QuestionId,
+			Text:           input.Text,
+		})
+	}
+
+	var saved *[]models.ExtractedAnswer
+	err = s.audit.Transaction(func(tx *gorm.DB) error {
+		repo := s.answerRepo.WithTx(tx)
+		previous, err := repo.GetByAnswerScriptId(answerScriptId)
+		if err != nil {
+			return err
+		}
+
+		if err := repo.Save(answers); err != nil {
+			return err
+		}
+
+		saved, err = repo.GetByAnswerScriptId(answerScriptId)
+		if err != nil {
+			return err
+		}
+
+		before := make(map[string]models.ExtractedAnswer, len(*previous))
+		for _, answer := range *previous {
+			before[answer.QuestionId] = answer
+		}
+		for i := range *saved {
+			answer := &(*saved)[i]
+			if old, ok := before[answer.QuestionId]; ok {
+				err = s.audit.Record(ctx, tx, models.AuditActionUpdate, AuditEntityExtractedAnswer, answer.Id, &old, answer)
+			} else {
+				err = s.audit.Record(ctx, tx, models.AuditActionCreate, AuditEntityExtractedAnswer, answer.Id, nil, answer)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// Queues a job that grades every answer script of an exam and runs it in
+// the background. Marks awarded by people are kept unless overwrite is set.
+// Exams can only be graded while they are being marked.
+func (s *GradingService) StartJob(examId string, data *models.StartGradingJob) (*models.GradingJob, error) {
+	exam, err := s.examRepo.GetById(examId)
+	if err != nil {
+		return nil, err
+	}
+	if exam.Status != models.ExamStatusMarking {
+		return nil, &ExamStatusError{exam.Status, "exams can only be graded while they are being marked"}
+	}
+
+	// A job abandoned by a stopped process must not block the exam
+	if err := s.FailInterruptedJobs(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job := &models.GradingJob{
+		ExamId:      examId,
+		Grader:      s.grader.Name(),
+		Overwrite:   data.Overwrite,
+		Status:      models.GradingJobQueued,
+		HeartbeatAt: &now,
+	}
+	created, err := s.jobRepo.CreateIfIdle(job)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrGradingJobActive
+	}
+
+	// Marks are recorded in the name of the grader rather than the user who started the job
+	ctx := auth.WithSystemActor(context.Background(), autoMarkerPrefix+s.grader.Name())
+	go s.run(ctx, *job)
+	return job, nil
+}
+
+// Retrieves a specific grading job
+func (s *GradingService) GetJob(id string) (*models.GradingJob, error) {
+	return s.jobRepo.GetById(id)
+}
+
+// Retrieves the grading jobs of an exam, newest first
+func (s *GradingService) GetJobs(examId string) (*[]models.GradingJob, error) {
+	if _, err := s.examRepo.GetById(examId); err != nil {
+		return nil, err
+	}
+	return s.jobRepo.GetByExamId(examId)
+}
+
+// Marks jobs left queued or running by a process that stopped as failed,
+// so that their exams can be graded again. Jobs of processes that are
+// still running keep their heartbeat fresh and are left alone.
+func (s *GradingService) FailInterruptedJobs() error {
+	failed, err := s.jobRepo.FailStale(time.Now().Add(-jobStaleAfter), "interrupted, the process running the job stopped")
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		log.Warnf("Failed %d grading jobs abandoned by a stopped process", failed)
+	}
+	return nil
+}
+
+func (s *GradingService) run(ctx context.Context, job models.GradingJob) {
+	beating, stop := context.WithCancel(ctx)
+	defer stop()
+	go s.heartbeat(beating, job.Id)
+
+	now := time.Now()
+	started, err := s.jobRepo.UpdateFieldsIfStatus(job.Id, models.GradingJobQueued, map[string]interface{}{
+		"status":     models.GradingJobRunning,
+		"started_at": now,
+	})
+	if err != nil {
+		log.Errorf("Failed to start grading job %s: %v", job.Id, err)
+		return
+	}
+	if !started {
+		log.Warnf("Grading job %s was no longer queued, not starting it", job.Id)
+		return
+	}
+
+	if err := s.gradeExam(ctx, &job); err != nil {
+		if errors.Is(err, errGradingJobStopped) {
+			log.Warnf("Grading job %s was stopped while running, e.g. failed as abandoned", job.Id)
+			return
+		}
+		log.Errorf("Grading job %s failed: %v", job.Id, err)
+		message := err.Error()
+		s.finish(job, models.GradingJobFailed, &message)
+		return
+	}
+	s.finish(job, models.GradingJobCompleted, nil)
+}
+
+// Refreshes the heartbeat of a job until ctx is done, telling other
+// processes that the job is still being worked on
+func (s *GradingService) heartbeat(ctx context.Context, jobId string) {
+	ticker := time.NewTicker(jobHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			running, err := s.jobRepo.UpdateFieldsIfStatus(jobId, models.GradingJobRunning, map[string]interface{}{"heartbeat_at": time.Now()})
+			if err != nil {
+				log.Warnf("Failed to refresh the heartbeat of grading job %s: %v", jobId, err)
+			} else if !running {
+				return
+			}
+		}
+	}
+}
+
+func (s *GradingService) gradeExam(ctx context.Context, job *models.GradingJob) error {
+	questions, err := markableQuestions(s.questionRepo, job.ExamId)
+	if err != nil {
+		return err
+	}
+	if len(questions) == 0 {
+		return errors.New("the exam has no memorandum questions")
+	}
+
+	leaves := make([]models.MemorandumQuestion, 0, len(questions))
+	for _, question := range questions {
+		leaves = append(leaves, question)
+	}
+
+	answerScripts, err := s.scriptRepo.GetByExamId(job.ExamId)
+	if err != nil {
+		return err
+	}
+	job.TotalScripts = len(*answerScripts)
+	if err := s.updateRunning(job, map[string]interface{}{"total_scripts": job.TotalScripts}); err != nil {
+		return err
+	}
+
+	for _, answerScript := range *answerScripts {
+		graded, err := s.gradeScript(ctx, answerScript.Id, leaves, job.Overwrite)
+		switch {
+		case err != nil:
+			log.Warnf("Failed to grade answer script %s: %v", answerScript.Id, err)
+			job.FailedScripts++
+		case graded:
+			job.GradedScripts++
+		default:
+			job.SkippedScripts++
+		}
+
+		if err := s.updateRunning(job, map[string]interface{}{
+			"graded_scripts":  job.GradedScripts,
+			"skipped_scripts": job.SkippedScripts,
+			"failed_scripts":  job.FailedScripts,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Grades a single answer script. Scripts without extracted answers are
+// skipped and reported as not graded.
+func (s *GradingService) gradeScript(ctx context.Context, answerScriptId string, questions []models.MemorandumQuestion, overwrite bool) (bool, error) {
+	extracted, err := s.answerRepo.GetByAnswerScriptId(answerScriptId)
+	if err != nil {
+		return false, err
+	}
+	if len(*extracted) == 0 {
+		return false, nil
+	}
+
+	answers := make(map[string]string, len(*extracted))
+	for _, answer := range *extracted {
+		answers[answer.QuestionId] = answer.Text
+	}
+
+	scores, err := s.grader.Grade(ctx, answers, questions)
+	if err != nil {
+		return false, err
+	}
+
+	err = s.audit.Transaction(func(tx *gorm.DB) error {
+		repo := s.markRepo.WithTx(tx)
+		existing, err := repo.GetByAnswerScriptId(answerScriptId)
+		if err != nil {
+			return err
+		}
+		markedByPerson := map[string]bool{}
+		for _, mark := range *existing {
+			if !strings.HasPrefix(mark.MarkedBy, autoMarkerPrefix) {
+				markedByPerson[mark.QuestionId] = true
+			}
+		}
+
+		marks := make([]models.QuestionMark, 0, len(scores))
+		for _, score := range scores {
+			if markedByPerson[score.QuestionId] && !overwrite {
+				continue
+			}
+
+			rationale := score.Rationale
+			if runes := []rune(rationale); len(runes) > maxRationaleLength {
+				rationale = string(runes[:maxRationaleLength])
+			}
+			marks = append(marks, models.QuestionMark{
+				AnswerScriptId: answerScriptId,
+				QuestionId:     score.QuestionId,
+				Awarded:        score.Awarded,
+				MaxMarks:       score.MaxMarks,
+				MarkedBy:       autoMarkerPrefix + s.grader.Name(),
+				Comment:        rationale,
+			})
+		}
+		if len(marks) == 0 {
+			return nil
+		}
+		if err := repo.Save(answerScriptId, marks); err != nil {
+			return err
+		}
+
+		saved, err := repo.GetByAnswerScriptId(answerScriptId)
+		if err != nil {
+			return err
+		}
+		return auditMarkChanges(ctx, tx, s.audit, *existing, *saved)
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Records the progress of a running job. Fails with errGradingJobStopped
+// once the job is no longer running, so that grading stops.
+func (s *GradingService) updateRunning(job *models.GradingJob, fields map[string]interface{}) error {
+	running, err := s.jobRepo.UpdateFieldsIfStatus(job.Id, models.GradingJobRunning, fields)
+	if err != nil {
+		return err
+	}
+	if !running {
+		return errGradingJobStopped
+	}
+	return nil
+}
+
+// Finishes a running job. A job that was failed as abandoned in the
+// meantime keeps that outcome.
+func (s *GradingService) finish(job models.GradingJob, status models.GradingJobStatus, message *string) {
+	finished, err := s.jobRepo.UpdateFieldsIfStatus(job.Id, models.GradingJobRunning, map[string]interface{}{
+		"status":      status,
+		"error":       message,
+		"finished_at": time.Now(),
+	})
+	if err != nil {
+		log.Errorf("Failed to finish grading job %s: %v", job.Id, err)
+	} else if !finished {
+		log.Warnf("Grading job %s was no longer running, leaving its status", job.Id)
+	}
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/xuri/excelize/v2"
+)
+
+// Writes the sections and rows of a mark sheet in a file format
+type markSheetWriter interface {
+	StartSection(title string, header []string) error
+	WriteRow(row []string) error
+	Close() error
+}
+
+// Writes rows as they are received. Every row starts with the
+// section title so that the sections of a subject can be told apart,
+// sections are separated by an empty line.
+type csvMarkSheetWriter struct {
+	writer   *csv.Writer
+	section  string
+	sections int
+}
+
+func newCSVMarkSheetWriter(w io.Writer) *csvMarkSheetWriter {
+	return &csvMarkSheetWriter{writer: csv.NewWriter(w)}
+}
+
+func (c *csvMarkSheetWriter) StartSection(title string, header []string) error {
+	if c.sections > 0 {
+		if err := c.writer.Write([]string{}); err != nil {
+			return err
+		}
+	}
+	c.sections++
+	c.section = escapeFormula(title)
+	return c.writer.Write(append([]string{"Exam"}, escapeFormulas(header)...))
+}
+
+func (c *csvMarkSheetWriter) WriteRow(row []string) error {
+	return c.writer.Write(append([]string{c.section}, escapeFormulas(row)...))
+}
+
+func (c *csvMarkSheetWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// Writes a sheet per section. Rows are streamed into the workbook, which
+// keeps large sheets in a temporary file until the workbook is written.
+type xlsxMarkSheetWriter struct {
+	out    io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+	names  map[string]bool
+}
+
+func newXLSXMarkSheetWriter(w io.Writer) *xlsxMarkSheetWriter {
+	return &xlsxMarkSheetWriter{out: w, file: excelize.NewFile(), names: map[string]bool{}}
+}
+
+func (x *xlsxMarkSheetWriter) StartSection(title string, header []string) error {
+	if err := x.flush(); err != nil {
+		return err
+	}
+
+	name := x.sheetName(title)
+	if len(x.names) == 1 {
+		if err := x.file.SetSheetName(x.file.GetSheetName(0), name); err != nil {
+			return err
+		}
+	} else if _, err := x.file.NewSheet(name); err != nil {
+		return err
+	}
+
+	stream, err := x.file.NewStreamWriter(name)
+	if err != nil {
+		return err
+	}
+	x.stream = stream
+	x.row = 0
+
+	values := make([]interface{}, len(header))
+	for i, label := range header {
+		values[i] = escapeFormula(label)
+	}
+	return x.setRow(values)
+}
+
+// Writes marks as numbers so that they can be summed, the student columns
+// stay text to keep leading zeros of exam numbers
+func (x *xlsxMarkSheetWriter) WriteRow(row []string) error {
+	values := make([]interface{}, len(row))
+	for i, value := range row {
+		values[i] = escapeFormula(value)
+		if number, err := strconv.ParseFloat(value, 64); err == nil && i >= 3 {
+			values[i] = number
+		}
+	}
+	return x.setRow(values)
+}
+
+func (x *xlsxMarkSheetWriter) setRow(values []interface{}) error {
+	x.row++
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	return x.stream.SetRow(cell, values)
+}
+
+func (x *xlsxMarkSheetWriter) Close() error {
+	defer x.file.Close()
+	if err := x.flush(); err != nil {
+		return err
+	}
+	_, err := x.file.WriteTo(x.out)
+	return err
+}
+
+func (x *xlsxMarkSheetWriter) flush() error {
+	if x.stream == nil {
+		return nil
+	}
+	return x.stream.Flush()
+}
+
+// Turns a section title into a unique sheet name. Sheet names are limited
+// to 31 characters and cannot contain some punctuation.
+func (x *xlsxMarkSheetWriter) sheetName(title string) string {
+	base := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, title)
+	if runes := []rune(base); len(runes) > 28 {
+		base = string(runes[:28])
+	}
+
+	name := base
+	for i := 2; x.names[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s %d", base, i)
+	}
+	x.names[strings.ToLower(name)] = true
+	return name
+}
+
+// Quotes text that a spreadsheet program would run as a formula, such as
+// a name starting with "=", so that it is shown as written. Numbers, which
+// may start with a sign, are left alone.
+func escapeFormula(value string) string {
+	if value == "" || !strings.ContainsRune("=+-@", rune(value[0])) {
+		return value
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	return "'" + value
+}
+
+func escapeFormulas(values []string) []string {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = escapeFormula(value)
+	}
+	return escaped
+}
+
+// Sizes of the printed schedule in millimetres
+const (
+	pdfMargin         = 10.0
+	pdfRowHeight      = 6.0
+	pdfNameWidth      = 32.0
+	pdfMinColumnWidth = 9.0
+)
+
+// Writes a printable schedule on landscape A4 pages, starting every
+// section on a new page and repeating the header on every page. The
+// document is assembled in memory and written out on Close.
+type pdfMarkSheetWriter struct {
+	out       io.Writer
+	pdf       *fpdf.Fpdf
+	translate func(string) string
+	title     string
+	section   string
+	header    []string
+	widths    []float64
+	fontSize  float64
+}
+
+func newPDFMarkSheetWriter(w io.Writer, title string) *pdfMarkSheetWriter {
+	pdf := fpdf.New("L", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(false, pdfMargin)
+	pdf.AliasNbPages("")
+	pdf.SetTitle(title, true)
+
+	writer := &pdfMarkSheetWriter{
+		out:       w,
+		pdf:       pdf,
+		translate: pdf.UnicodeTranslatorFromDescriptor(""),
+		title:     title,
+	}
+	generated := time.Now().Format("2006-01-02 15:04")
+	pdf.SetFooterFunc(func() {
+		pageWidth, _ := pdf.GetPageSize()
+		half := (pageWidth - 2*pdfMargin) / 2
+		pdf.SetY(-pdfMargin)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(half, 5, writer.translate(fmt.Sprintf("%s, generated %s", title, generated)), "", 0, "L", false, 0, "")
+		pdf.CellFormat(half, 5, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	return writer
+}
+
+func (p *pdfMarkSheetWriter) StartSection(title string, header []string) error {
+	p.section = title
+	p.header = header
+
+	// Names get a fixed width, the remaining columns share the rest of the page
+	pageWidth, _ := p.pdf.GetPageSize()
+	available := pageWidth - 2*pdfMargin - 3*pdfNameWidth
+	width := available / float64(len(header)-3)
+	p.fontSize = 9
+	if width < pdfMinColumnWidth {
+		p.fontSize = 6
+	}
+
+	p.widths = make([]float64, len(header))
+	for i := range header {
+		if i < 3 {
+			p.widths[i] = pdfNameWidth
+		} else {
+			p.widths[i] = width
+		}
+	}
+
+	p.newPage()
+	return p.pdf.Error()
+}
+
+func (p *pdfMarkSheetWriter) WriteRow(row []string) error {
+	_, pageHeight := p.pdf.GetPageSize()
+	if p.pdf.GetY()+pdfRowHeight > pageHeight-pdfMargin-pdfRowHeight {
+		p.newPage()
+	}
+
+	p.pdf.SetFont("Helvetica", "", p.fontSize)
+	for i, value := range row {
+		align := "R"
+		if i < 3 {
+			align = "L"
+		}
+		p.pdf.CellFormat(p.widths[i], pdfRowHeight, p.fit(value, p.widths[i]), "1", 0, align, false, 0, "")
+	}
+	p.pdf.Ln(-1)
+	return p.pdf.Error()
+}
+
+func (p *pdfMarkSheetWriter) Close() error {
+	if p.pdf.PageCount() == 0 {
+		p.pdf.AddPage()
+		p.pdf.SetFont("Helvetica", "B", 14)
+		p.pdf.CellFormat(0, 10, p.translate(p.title), "", 1, "L", false, 0, "")
+		p.pdf.SetFont("Helvetica", "", 10)
+		p.pdf.CellFormat(0, 8, "No answer scripts to show.", "", 1, "L", false, 0, "")
+	}
+	return p.pdf.Output(p.out)
+}
+
+// Starts a page with the titles and the table header
+func (p *pdfMarkSheetWriter) newPage() {
+	p.pdf.AddPage()
+	p.pdf.SetFont("Helvetica", "B", 14)
+	p.pdf.CellFormat(0, 8, p.translate(p.title), "", 1, "L", false, 0, "")
+	p.pdf.SetFont("Helvetica", "", 11)
+	p.pdf.CellFormat(0, 7, p.translate(p.section), "", 1, "L", false, 0, "")
+	p.pdf.Ln(2)
+
+	p.pdf.SetFont("Helvetica", "B", p.fontSize)
+	p.pdf.SetFillColor(230, 230, 230)
+	for i, label := range p.header {
+		p.pdf.CellFormat(p.widths[i], pdfRowHeight, p.fit(label, p.widths[i]), "1", 0, "C", true, 0, "")
+	}
+	p.pdf.Ln(-1)
+}
+
+// Shortens text that does not fit into a cell
+func (p *pdfMarkSheetWriter) fit(text string, width float64) string {
+	text = p.translate(text)
+	for len(text) > 1 && p.pdf.GetStringWidth(text) > width-1 {
+		text = text[:len(text)-1]
+	}
+	return text
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEscapeFormula(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "=HYPERLINK(\"http://example.com\")", want: "'=HYPERLINK(\"http://example.com\")"},
+		{value: "+27 82 555 0100", want: "'+27 82 555 0100"},
+		{value: "-Smith", want: "'-Smith"},
+		{value: "@SUM(A1)", want: "'@SUM(A1)"},
+		{value: "-3", want: "-3"},
+		{value: "+1.5", want: "+1.5"},
+		{value: "Naidoo", want: "Naidoo"},
+		{value: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := escapeFormula(tt.value); got != tt.want {
+			t.Errorf("escapeFormula(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCSVMarkSheetWriterEscapesFormulas(t *testing.T) {
+	var out bytes.Buffer
+	writer := newCSVMarkSheetWriter(&out)
+	if err := writer.StartSection("=Exam", []string{"Exam number", "First name", "Last name", "1"}); err != nil {
+		t.Fatalf("StartSection: %v", err)
+	}
+	if err := writer.WriteRow([]string{"JOH5196", "=cmd", "@Doe", "-2"}); err != nil {
+		t.Fatalf("WriteRow: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	want := "Exam,Exam number,First name,Last name,1\n'=Exam,JOH5196,'=cmd,'@Doe,-2\n"
+	if out.String() != want {
+		t.Errorf("wrote %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,377 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	// Returned when a match is confirmed without a student to link the script to
+	ErrNoStudentToConfirm = errors.New("no student to link the answer script to")
+	// Returned when a reviewer links a script to a student that does not exist
+	ErrStudentNotFound = errors.New("student not found")
+)
+
+// Cost of substituting characters OCR commonly mistakes for each other
+const confusionCost = 0.25
+
+// How many characters a scanned exam number may be longer or shorter
+// than a student's exam number and still be considered a candidate
+const maxLengthDifference = 2
+
+// Characters that OCR engines commonly confuse, grouped together
+var confusionGroups = []string{"0ODQ", "1IL", "2Z", "5S", "6G", "8B"}
+
+// Maps every confusable character to the index of its group
+var confusionIndex = func() map[rune]int {
+	index := map[rune]int{}
+	for i, group := range confusionGroups {
+		for _, r := range group {
+			index[r] = i
+		}
+	}
+	return index
+}()
+
+// A student that a scanned exam number could belong to
+type MatchCandidate struct {
+	Student    models.Student `json:"student"`
+	Similarity float32        `json:"similarity"` // Between 0 and 1
+}
+
+// Links answer scripts to students using the exam number read from the script
+type MatchingService struct {
+	studentRepo *repository.StudentRepository
+	scriptRepo  *repository.AnswerScriptRepository
+	threshold   float32
+	audit       *AuditService
+}
+
+// Creates a new instance of MatchingService
+func NewMatchingService(
+	studentRepo *repository.StudentRepository,
+	scriptRepo *repository.AnswerScriptRepository,
+	threshold float32,
+	audit *AuditService,
+) *MatchingService {
+	return &MatchingService{
+		studentRepo: studentRepo,
+		scriptRepo:  scriptRepo,
+		threshold:   threshold,
+		audit:       audit,
+	}
+}
+
+// Returns the confidence below which matches require manual review
+func (s *MatchingService) Threshold() float32 {
+	return s.threshold
+}
+
+// Matches an answer script to the student whose exam number is closest to
+// the scanned one. The script is only linked when the combined OCR and
+// similarity confidence reaches the threshold and the best candidate is
+// unambiguous, otherwise it is flagged for manual review.
+func (s *MatchingService) MatchScript(ctx context.Context, id string) (*models.AnswerScript, error) {
+	answerScript, err := s.scriptRepo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if answerScript.ScannedExamNumber == nil || answerScript.StudentId != nil {
+		return answerScript, nil
+	}
+
+	candidates, err := s.findCandidates(*answerScript.ScannedExamNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	ocrConfidence := float32(1)
+	if answerScript.MatchingConfidence != nil {
+		ocrConfidence = *answerScript.MatchingConfidence
+	}
+
+	action := models.AuditActionUpdate
+	fields := decideMatch(candidates, ocrConfidence, s.threshold)
+	if fields["match_status"] == models.MatchStatusAutoMatched {
+		action = models.AuditActionAutoMatch
+	}
+
+	return s.updateAndAudit(ctx, action, answerScript, fields)
+}
+
+// Retrieves the answer scripts waiting for a reviewer to link them to a student
+func (s *MatchingService) GetReviewQueue(params repository.ListParams) (*repository.Page[models.AnswerScript], error) {
+	return s.scriptRepo.GetReviewQueue(s.threshold, params)
+}
+
+// Retrieves an answer script together with the students it most likely
+// belongs to, ranked by how similar their exam number is to the scanned one
+func (s *MatchingService) GetCandidates(id string, limit int) (*models.AnswerScript, []MatchCandidate, error) {
+	answerScript, err := s.scriptRepo.GetById(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if answerScript.ScannedExamNumber == nil {
+		return answerScript, []MatchCandidate{}, nil
+	}
+
+	candidates, err := s.RankCandidates(*answerScript.ScannedExamNumber, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	return answerScript, candidates, nil
+}
+
+// Confirms the student an answer script is linked to. A student can be
+// given when the script has not been linked to one yet.
+func (s *MatchingService) ConfirmMatch(ctx context.Context, id string, review *models.MatchReview) (*models.AnswerScript, error) {
+	answerScript, err := s.scriptRepo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	studentId := answerScript.StudentId
+	if review.StudentId != nil {
+		studentId = review.StudentId
+	}
+	if studentId == nil {
+		return nil, ErrNoStudentToConfirm
+	}
+
+	return s.linkStudent(ctx, models.AuditActionConfirmMatch, answerScript, *studentId, review.ReviewedBy)
+}
+
+// Links an answer script to a different student than the proposed one
+func (s *MatchingService) ReassignMatch(ctx context.Context, id string, review *models.MatchReview) (*models.AnswerScript, error) {
+	if review.StudentId == nil {
+		return nil, ErrNoStudentToConfirm
+	}
+
+	answerScript, err := s.scriptRepo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.linkStudent(ctx, models.AuditActionReassignMatch, answerScript, *review.StudentId, review.ReviewedBy)
+}
+
+// Unlinks an answer script from any student and removes it from the review queue
+func (s *MatchingService) RejectMatch(ctx context.Context, id string, review *models.MatchReview) (*models.AnswerScript, error) {
+	answerScript, err := s.scriptRepo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.updateAndAudit(ctx, models.AuditActionRejectMatch, answerScript, map[string]interface{}{
+		"student_id":   nil,
+		"matched_at":   nil,
+		"match_status": models.MatchStatusRejected,
+		"reviewed_by":  review.ReviewedBy,
+		"reviewed_at":  time.Now(),
+	})
+}
+
+// Records a reviewer's decision to link an answer script to a student
+func (s *MatchingService) linkStudent(ctx context.Context, action models.AuditAction, answerScript *models.AnswerScript, studentId, reviewedBy string) (*models.AnswerScript, error) {
+	if _, err := s.studentRepo.GetById(studentId); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStudentNotFound
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	fields := map[string]interface{}{
+		"student_id":   studentId,
+		"match_status": models.MatchStatusConfirmed,
+		"reviewed_by":  reviewedBy,
+		"reviewed_at":  now,
+	}
+	if answerScript.StudentId == nil || *answerScript.StudentId != studentId {
+		fields["matched_at"] = now
+	}
+
+	return s.updateAndAudit(ctx, action, answerScript, fields)
+}
+
+// Writes the match of an answer script, then reloads it and records the
+// change, all in a single transaction
+func (s *MatchingService) updateAndAudit(ctx context.Context, action models.AuditAction, before *models.AnswerScript, fields map[string]interface{}) (*models.AnswerScript, error) {
+	var answerScript *models.AnswerScript
+	err := s.audit.Transaction(func(tx *gorm.DB) error {
+		repo := s.scriptRepo.WithTx(tx)
+		if err := repo.UpdateFields(before.Id, fields); err != nil {
+			return err
+		}
+
+		var err error
+		answerScript, err = repo.GetById(before.Id)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, action, AuditEntityAnswerScript, before.Id, before, answerScript)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return answerScript, nil
+}
+
+// Returns the best two candidates for a scanned exam number. An exact
+// match is looked up first so that most scripts do not have to be
+// compared against every student. Exam numbers are compared normalized on
+// both sides, as students may have been registered with separators.
+func (s *MatchingService) findCandidates(scannedExamNumber string) ([]MatchCandidate, error) {
+	scanned := normalizeExamNumber(scannedExamNumber)
+	if scanned == "" {
+		return []MatchCandidate{}, nil
+	}
+
+	students, err := s.studentRepo.GetByNormalizedExamNumber(scanned)
+	if err != nil {
+		return nil, err
+	}
+	if len(*students) > 0 {
+		// Two students only told apart by separators are equally good matches
+		candidates := make([]MatchCandidate, 0, len(*students))
+		for _, student := range *students {
+			candidates = append(candidates, MatchCandidate{Student: student, Similarity: 1})
+		}
+		return candidates, nil
+	}
+
+	return s.RankCandidates(scannedExamNumber, 2)
+}
+
+// Returns up to limit students ordered by how similar their exam number
+// is to the scanned one, most similar first
+func (s *MatchingService) RankCandidates(scannedExamNumber string, limit int) ([]MatchCandidate, error) {
+	scanned := normalizeExamNumber(scannedExamNumber)
+	if scanned == "" {
+		return []MatchCandidate{}, nil
+	}
+
+	length := len([]rune(scanned))
+	students, err := s.studentRepo.GetExamNumbersByLength(length-maxLengthDifference, length+maxLengthDifference)
+	if err != nil {
+		return nil, err
+	}
+
+	return rankCandidates(scanned, *students, limit), nil
+}
+
+// Orders students by how similar their exam number is to the scanned one,
+// most similar first, and keeps up to limit of them
+func rankCandidates(scannedExamNumber string, students []models.Student, limit int) []MatchCandidate {
+	candidates := make([]MatchCandidate, 0, len(students))
+	for _, student := range students {
+		similarity := ExamNumberSimilarity(scannedExamNumber, student.ExamNumber)
+		if similarity > 0 {
+			candidates = append(candidates, MatchCandidate{Student: student, Similarity: similarity})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Similarity > candidates[j].Similarity
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+// Returns the fields to update on a script given its best candidates. The
+// best candidate is linked when the match score, the OCR confidence times
+// the similarity, reaches the threshold and no other candidate is as
+// similar, otherwise the script needs review. The OCR confidence itself is
+// left as it is.
+func decideMatch(candidates []MatchCandidate, ocrConfidence, threshold float32) map[string]interface{} {
+	fields := map[string]interface{}{"match_status": models.MatchStatusNeedsReview, "match_score": nil}
+	if len(candidates) == 0 {
+		return fields
+	}
+
+	best := candidates[0]
+	score := best.Similarity * ocrConfidence
+	fields["match_score"] = score
+
+	ambiguous := len(candidates) > 1 && candidates[1].Similarity == best.Similarity
+	if score >= threshold && !ambiguous {
+		fields["student_id"] = best.Student.Id
+		fields["matched_at"] = time.Now()
+		fields["match_status"] = models.MatchStatusAutoMatched
+	}
+	return fields
+}
+
+// Scores how similar two exam numbers are between 0 (nothing in common)
+// and 1 (identical). Uses the edit distance where substitutions between
+// characters OCR commonly confuses, such as 0 and O, are cheaper.
+func ExamNumberSimilarity(a, b string) float32 {
+	ra := []rune(normalizeExamNumber(a))
+	rb := []rune(normalizeExamNumber(b))
+
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+
+	similarity := 1 - editDistance(ra, rb)/float32(longest)
+	if similarity < 0 {
+		return 0
+	}
+	return similarity
+}
+
+// Upper cases an exam number and strips separators such as spaces and dashes
+func normalizeExamNumber(examNumber string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, examNumber)
+}
+
+// Weighted Levenshtein distance between two strings
+func editDistance(a, b []rune) float32 {
+	previous := make([]float32, len(b)+1)
+	current := make([]float32, len(b)+1)
+	for j := range previous {
+		previous[j] = float32(j)
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = float32(i)
+		for j := 1; j <= len(b); j++ {
+			current[j] = min(
+				previous[j]+1,  // Deletion
+				current[j-1]+1, // Insertion
+				previous[j-1]+substitutionCost(a[i-1], b[j-1]),
+			)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func substitutionCost(a, b rune) float32 {
+	if a == b {
+		return 0
+	}
+
+	groupA, okA := confusionIndex[a]
+	groupB, okB := confusionIndex[b]
+	if okA && okB && groupA == groupB {
+		return confusionCost
+	}
+	return 1
+}
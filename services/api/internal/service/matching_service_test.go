@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/ocr"
+)
+
+func TestExamNumberSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want float32
+	}{
+		{name: "identical", a: "JOH5196", b: "JOH5196", want: 1},
+		{name: "case and separators", a: "joh-5196", b: "JOH 5196", want: 1},
+		{name: "confused zero and O", a: "J0H5196", b: "JOH5196", want: 1 - 0.25/7},
+		{name: "confused one and I", a: "JOH5I96", b: "JOH5196", want: 1 - 0.25/7},
+		{name: "two confusions", a: "J0H5I96", b: "JOH5196", want: 1 - 0.5/7},
+		{name: "one wrong digit", a: "JOH5197", b: "JOH5196", want: 1 - 1.0/7},
+		{name: "missing character", a: "JOH519", b: "JOH5196", want: 1 - 1.0/7},
+		{name: "extra character", a: "JOH51966", b: "JOH5196", want: 1 - 1.0/8},
+		{name: "nothing in common", a: "XYZ0000", b: "JOH5196", want: 0},
+		{name: "empty", a: "", b: "JOH5196", want: 0},
+		{name: "both empty", a: "", b: "", want: 0},
+		{name: "only separators", a: "--", b: " ", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExamNumberSimilarity(tt.a, tt.b)
+			if math.Abs(float64(got-tt.want)) > 1e-6 {
+				t.Errorf("ExamNumberSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if reverse := ExamNumberSimilarity(tt.b, tt.a); reverse != got {
+				t.Errorf("similarity is not symmetric: %v and %v", got, reverse)
+			}
+		})
+	}
+}
+
+func TestRankCandidates(t *testing.T) {
+	students := []models.Student{
+		testStudent("s1", "SMI1234"),
+		testStudent("s2", "JOH5197"),
+		testStudent("s3", "JOH5196"),
+		testStudent("s4", "XYZ0000"),
+	}
+
+	candidates := rankCandidates("JOH5196", students, 2)
+	if len(candidates) != 2 {
+		t.Fatalf("got %d candidates, want 2", len(candidates))
+	}
+	if candidates[0].Student.Id != "s3" || candidates[1].Student.Id != "s2" {
+		t.Errorf("candidates = %s, %s, want s3, s2", candidates[0].Student.Id, candidates[1].Student.Id)
+	}
+
+	for _, candidate := range rankCandidates("JOH5196", students, 10) {
+		if candidate.Student.Id == "s4" {
+			t.Error("a student without anything in common was ranked")
+		}
+	}
+}
+
+// Runs a fixture through the stub recognizer and decides on a match the
+// way the script processor and MatchScript do
+func TestMatchThresholds(t *testing.T) {
+	const threshold = 0.9
+
+	students := []models.Student{
+		testStudent("john", "JOH5196"),
+		testStudent("smith", "SMI1234"),
+		testStudent("jo", "J0H5196"),
+		testStudent("jon", "JOH519G"),
+	}
+
+	tests := []struct {
+		name          string
+		fixture       string
+		pattern       string  // Overrides the stub's exam number pattern
+		ocrConfidence float32 // Confidence the stub reports, 1 when zero
+		students      []models.Student
+		wantStatus    models.MatchStatus
+		wantStudent   string
+		wantScore     float32 // Zero when no match score should be recorded
+	}{
+		{
+			name:        "exact exam number is linked",
+			fixture:     "Exam number: JOH5196\nQuestion 1 ...",
+			students:    students[:2],
+			wantStatus:  models.MatchStatusAutoMatched,
+			wantStudent: "john",
+			wantScore:   1,
+		},
+		{
+			name:        "OCR confusion above the threshold is linked",
+			fixture:     "Exam number: SM11234",
+			pattern:     `[A-Z0-9]{3}[0-9]{4}`,
+			students:    students[:2],
+			wantStatus:  models.MatchStatusAutoMatched,
+			wantStudent: "smith",
+			wantScore:   1 - 0.25/7,
+		},
+		{
+			name:          "exact exam number read with low OCR confidence needs review",
+			fixture:       "JOH5196",
+			ocrConfidence: 0.8,
+			students:      students[:2],
+			wantStatus:    models.MatchStatusNeedsReview,
+			wantScore:     0.8,
+		},
+		{
+			name:          "confidence exactly at the threshold is linked",
+			fixture:       "JOH5196",
+			ocrConfidence: threshold,
+			students:      students[:2],
+			wantStatus:    models.MatchStatusAutoMatched,
+			wantStudent:   "john",
+			wantScore:     threshold,
+		},
+		{
+			name:       "one wrong digit needs review",
+			fixture:    "JOH5197",
+			students:   students[:2],
+			wantStatus: models.MatchStatusNeedsReview,
+			wantScore:  1 - 1.0/7,
+		},
+		{
+			name:       "equally similar candidates need review",
+			fixture:    "JOH5196",
+			students:   students[2:],
+			wantStatus: models.MatchStatusNeedsReview,
+			wantScore:  1 - 0.25/7,
+		},
+		{
+			name:       "no similar student needs review without a confidence",
+			fixture:    "ABC9999",
+			students:   []models.Student{testStudent("x", "XYZ0000")},
+			wantStatus: models.MatchStatusNeedsReview,
+		},
+		{
+			name:       "no students needs review without a confidence",
+			fixture:    "JOH5196",
+			wantStatus: models.MatchStatusNeedsReview,
+		},
+		{
+			name:       "no exam number on the script stays unmatched",
+			fixture:    "Question 1: 42",
+			students:   students,
+			wantStatus: models.MatchStatusUnmatched,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recognizer := ocr.NewStubRecognizer()
+			if tt.pattern != "" {
+				recognizer.Pattern = regexp.MustCompile(tt.pattern)
+			}
+			if tt.ocrConfidence != 0 {
+				recognizer.Confidence = tt.ocrConfidence
+			}
+
+			result, err := recognizer.Recognize(context.Background(), strings.NewReader(tt.fixture), "text/plain")
+			if tt.wantStatus == models.MatchStatusUnmatched {
+				if !errors.Is(err, ocr.ErrNoExamNumber) {
+					t.Fatalf("Recognize = %v, want ErrNoExamNumber", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Recognize: %v", err)
+			}
+
+			candidates := rankCandidates(result.ExamNumber, tt.students, 2)
+			fields := decideMatch(candidates, result.Confidence, threshold)
+
+			if status := fields["match_status"]; status != tt.wantStatus {
+				t.Errorf("match_status = %v, want %v", status, tt.wantStatus)
+			}
+			studentId, linked := fields["student_id"]
+			if tt.wantStudent == "" && linked {
+				t.Errorf("linked to %v, want no student", studentId)
+			}
+			if tt.wantStudent != "" && studentId != tt.wantStudent {
+				t.Errorf("student_id = %v, want %v", studentId, tt.wantStudent)
+			}
+			if _, ok := fields["matched_at"]; ok != linked {
+				t.Errorf("matched_at set = %v, student linked = %v", ok, linked)
+			}
+
+			if _, ok := fields["matching_confidence"]; ok {
+				t.Error("matching overwrote the OCR confidence")
+			}
+			score, ok := fields["match_score"].(float32)
+			if tt.wantScore == 0 {
+				if ok {
+					t.Errorf("match_score = %v, want none", score)
+				}
+				return
+			}
+			if !ok || math.Abs(float64(score-tt.wantScore)) > 1e-6 {
+				t.Errorf("match_score = %v, want %v", fields["match_score"], tt.wantScore)
+			}
+		})
+	}
+}
+
+func testStudent(id, examNumber string) models.Student {
+	student := models.Student{ExamNumber: examNumber}
+	student.Id = id
+	return student
+}
@@ -0,0 +1,568 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Deepest level of nesting allowed, e.g. question 1.2(a)
+const maxQuestionDepth = 3
+
+var (
+	// Returned when a sub-question references a parent that does not exist
+	ErrParentQuestionNotFound = errors.New("parent question not found")
+	// Returned when questions are nested deeper than maxQuestionDepth
+	ErrQuestionTooDeep = fmt.Errorf("questions can be nested at most %d levels deep", maxQuestionDepth)
+	// Returned when the marks of a question are lowered below marks already
+	// awarded for it
+	ErrMarksAlreadyAwarded = errors.New("marks already awarded exceed the question's marks")
+)
+
+// Compares the marks allocated by a memorandum with the exam's total marks
+type AllocationReport struct {
+	ExamTotalMarks int      `json:"exam_total_marks"`
+	AllocatedMarks int      `json:"allocated_marks"`
+	Complete       bool     `json:"complete"` // Allocations add up and match the exam's total marks
+	Issues         []string `json:"issues"`
+}
+
+// Returned when a change would leave the mark allocation of a memorandum invalid
+type AllocationError struct {
+	Report *AllocationReport
+}
+
+func (e *AllocationError) Error() string {
+	return "invalid mark allocation: " + strings.Join(e.Report.Issues, "; ")
+}
+
+// Handles business logic for the structure of memorandums
+type MemorandumQuestionService struct {
+	repo           *repository.MemorandumQuestionRepository
+	memorandumRepo *repository.MemorandumRepository
+	examRepo       *repository.ExamRepository
+	markRepo       *repository.QuestionMarkRepository
+	audit          *AuditService
+}
+
+// Creates a new instance of MemorandumQuestionService
+func NewMemorandumQuestionService(
+	repo *repository.MemorandumQuestionRepository,
+	memorandumRepo *repository.MemorandumRepository,
+	examRepo *repository.ExamRepository,
+	markRepo *repository.QuestionMarkRepository,
+	audit *AuditService,
+) *MemorandumQuestionService {
+	return &MemorandumQuestionService{
+		repo:           repo,
+		memorandumRepo: memorandumRepo,
+		examRepo:       examRepo,
+		markRepo:       markRepo,
+		audit:          audit,
+	}
+}
+
+// Retrieves the questions of a memorandum as a tree of questions and sub-questions
+func (s *MemorandumQuestionService) GetQuestions(memorandumId string) ([]models.MemorandumQuestion, error) {
+	if _, err := s.memorandumRepo.GetById(memorandumId); err != nil {
+		return nil, err
+	}
+
+	questions, err := s.repo.GetByMemorandumId(memorandumId)
+	if err != nil {
+		return nil, err
+	}
+	return buildQuestionTree(*questions), nil
+}
+
+// Replaces the whole structure of a memorandum. The allocations have to
+// add up to the exam's total marks.
+func (s *MemorandumQuestionService) ReplaceQuestions(ctx context.Context, memorandumId string, inputs []models.MemorandumQuestionInput) ([]models.MemorandumQuestion, *AllocationReport, error) {
+	memorandum, exam, err := s.getRestructurableMemorandum(memorandumId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	questions, err := questionsFromInput(inputs, memorandum, nil, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := checkAllocation(questions, exam.TotalMarks)
+	if !report.Complete {
+		return nil, nil, &AllocationError{report}
+	}
+
+	var tree []models.MemorandumQuestion
+	err = s.audit.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		previous, err := repo.GetByMemorandumId(memorandumId)
+		if err != nil {
+			return err
+		}
+
+		// Marks of the replaced questions are deleted with them
+		if err := auditMemorandumMarks(ctx, tx, s.audit, s.markRepo, memorandumId, func() error {
+			return repo.ReplaceAll(memorandumId, questions)
+		}); err != nil {
+			return err
+		}
+
+		saved, err := repo.GetByMemorandumId(memorandumId)
+		if err != nil {
+			return err
+		}
+		tree = buildQuestionTree(*saved)
+		return s.audit.Record(ctx, tx, models.AuditActionUpdate, AuditEntityMemorandum, memorandumId,
+			map[string]interface{}{"questions": buildQuestionTree(*previous)},
+			map[string]interface{}{"questions": tree},
+		)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return tree, report, nil
+}
+
+// Adds a single question or sub-question to a memorandum. The memorandum
+// may be incomplete afterwards, but never allocate more marks than available.
+func (s *MemorandumQuestionService) CreateQuestion(ctx context.Context, memorandumId string, data *models.CreateMemorandumQuestion) (*models.MemorandumQuestion, *AllocationReport, error) {
+	memorandum, exam, err := s.getRestructurableMemorandum(memorandumId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	existing, err := s.repo.GetByMemorandumId(memorandumId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if data.ParentId != nil {
+		depth := questionDepth(*existing, *data.ParentId)
+		if depth == 0 {
+			return nil, nil, ErrParentQuestionNotFound
+		}
+		if depth >= maxQuestionDepth {
+			return nil, nil, ErrQuestionTooDeep
+		}
+	}
+
+	question := &models.MemorandumQuestion{
+		MemorandumId:       memorandum.Id,
+		ExamId:             memorandum.ExamId,
+		ParentId:           data.ParentId,
+		Number:             strings.TrimSpace(data.Number),
+		Prompt:             data.Prompt,
+		ExpectedAnswer:     data.ExpectedAnswer,
+		AlternativeAnswers: data.AlternativeAnswers,
+		Marks:              data.Marks,
+		MatchMode:          matchModeOrDefault(data.MatchMode),
+		Keywords:           data.Keywords,
+		Tolerance:          data.Tolerance,
+	}
+	if data.Position != nil {
+		question.Position = *data.Position
+	} else {
+		question.Position = countSiblings(*existing, data.ParentId)
+	}
+	if err := models.SetId(&question.Id); err != nil {
+		return nil, nil, err
+	}
+
+	report, err := checkPartialAllocation(append(*existing, *question), exam.TotalMarks)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = s.audit.Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.WithTx(tx).Create(question); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionCreate, AuditEntityMemorandumQuestion, question.Id, nil, question)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return question, report, nil
+}
+
+// Modifies a question of a memorandum. Its marks are fixed once marking
+// started. Changed marks are carried over to the marks already awarded for
+// the question, they cannot be lowered below the most awarded to any
+// answer script.
+func (s *MemorandumQuestionService) UpdateQuestion(ctx context.Context, memorandumId, id string, data *models.UpdateMemorandumQuestion) (*models.MemorandumQuestion, *AllocationReport, error) {
+	_, exam, err := s.getEditableMemorandum(memorandumId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	question, err := s.repo.GetById(memorandumId, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	before := *question
+
+	if data.Number != nil {
+		question.Number = strings.TrimSpace(*data.Number)
+	}
+	if data.Position != nil {
+		question.Position = *data.Position
+	}
+	if data.Prompt != nil {
+		question.Prompt = *data.Prompt
+	}
+	if data.ExpectedAnswer != nil {
+		question.ExpectedAnswer = *data.ExpectedAnswer
+	}
+	if data.AlternativeAnswers != nil {
+		question.AlternativeAnswers = *data.AlternativeAnswers
+	}
+	if data.Marks != nil && *data.Marks != question.Marks {
+		if err := requireUnmarkedExam(exam); err != nil {
+			return nil, nil, err
+		}
+		question.Marks = *data.Marks
+	}
+	if data.MatchMode != nil {
+		question.MatchMode = matchModeOrDefault(*data.MatchMode)
+	}
+	if data.Keywords != nil {
+		question.Keywords = *data.Keywords
+	}
+	if data.Tolerance != nil {
+		question.Tolerance = *data.Tolerance
+	}
+
+	existing, err := s.repo.GetByMemorandumId(memorandumId)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range *existing {
+		if (*existing)[i].Id == question.Id {
+			(*existing)[i] = *question
+		}
+	}
+
+	report, err := checkPartialAllocation(*existing, exam.TotalMarks)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = s.audit.Transaction(func(tx *gorm.DB) error {
+		// The maximum of the marks awarded for the question follows its marks
+		if err := auditMemorandumMarks(ctx, tx, s.audit, s.markRepo, memorandumId, func() error {
+			return s.repo.WithTx(tx).Save(question)
+		}); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionUpdate, AuditEntityMemorandumQuestion, id, &before, question)
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrAwardedAboveMaximum) {
+			return nil, nil, fmt.Errorf("%w: %w", ErrMarksAlreadyAwarded, err)
+		}
+		return nil, nil, err
+	}
+	return question, report, nil
+}
+
+// Removes a question of a memorandum together with its sub-questions
+func (s *MemorandumQuestionService) DeleteQuestion(ctx context.Context, memorandumId, id string) error {
+	if _, _, err := s.getRestructurableMemorandum(memorandumId); err != nil {
+		return err
+	}
+
+	question, err := s.repo.GetById(memorandumId, id)
+	if err != nil {
+		return err
+	}
+
+	return s.audit.Transaction(func(tx *gorm.DB) error {
+		// Marks of the question and its sub-questions are deleted with them
+		if err := auditMemorandumMarks(ctx, tx, s.audit, s.markRepo, memorandumId, func() error {
+			return s.repo.WithTx(tx).Delete(question)
+		}); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionDelete, AuditEntityMemorandumQuestion, id, question, nil)
+	})
+}
+
+// Reports whether the allocations of a memorandum add up to the exam's total marks
+func (s *MemorandumQuestionService) ValidateAllocation(memorandumId string) (*AllocationReport, error) {
+	_, exam, err := s.getMemorandumAndExam(memorandumId)
+	if err != nil {
+		return nil, err
+	}
+
+	questions, err := s.repo.GetByMemorandumId(memorandumId)
+	if err != nil {
+		return nil, err
+	}
+	return checkAllocation(buildQuestionTree(*questions), exam.TotalMarks), nil
+}
+
+func (s *MemorandumQuestionService) getMemorandumAndExam(memorandumId string) (*models.Memorandum, *models.Exam, error) {
+	memorandum, err := s.memorandumRepo.GetById(memorandumId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exam, err := s.examRepo.GetById(memorandum.ExamId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return memorandum, exam, nil
+}
+
+// Like getMemorandumAndExam, but fails once the marks of the exam are locked
+func (s *MemorandumQuestionService) getEditableMemorandum(memorandumId string) (*models.Memorandum, *models.Exam, error) {
+	memorandum, exam, err := s.getMemorandumAndExam(memorandumId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if exam.Status == models.ExamStatusFinalized || exam.Status == models.ExamStatusPublished {
+		return nil, nil, &ExamStatusError{exam.Status, "the memorandum can no longer be changed"}
+	}
+	return memorandum, exam, nil
+}
+
+// Retrieves a memorandum whose questions can be added, removed or given
+// other marks
+func (s *MemorandumQuestionService) getRestructurableMemorandum(memorandumId string) (*models.Memorandum, *models.Exam, error) {
+	memorandum, exam, err := s.getEditableMemorandum(memorandumId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := requireUnmarkedExam(exam); err != nil {
+		return nil, nil, err
+	}
+	return memorandum, exam, nil
+}
+
+// Marks are awarded against the questions of the memorandum once marking
+// started, so from then on only their wording and grading rules can change
+func requireUnmarkedExam(exam *models.Exam) error {
+	if exam.Status == models.ExamStatusMarking || exam.Status == models.ExamStatusModeration {
+		return &ExamStatusError{exam.Status, "questions cannot be added, removed or given other marks once marking started"}
+	}
+	return nil
+}
+
+// Converts submitted question trees into models, ordering siblings as submitted
+func questionsFromInput(inputs []models.MemorandumQuestionInput, memorandum *models.Memorandum, parentId *string, depth int) ([]models.MemorandumQuestion, error) {
+	if depth > maxQuestionDepth && len(inputs) > 0 {
+		return nil, ErrQuestionTooDeep
+	}
+
+	questions := make([]models.MemorandumQuestion, 0, len(inputs))
+	for i, input := range inputs {
+		question := models.MemorandumQuestion{
+			MemorandumId:       memorandum.Id,
+			ExamId:             memorandum.ExamId,
+			ParentId:           parentId,
+			Number:             strings.TrimSpace(input.Number),
+			Position:           i,
+			Prompt:             input.Prompt,
+			ExpectedAnswer:     input.ExpectedAnswer,
+			AlternativeAnswers: input.AlternativeAnswers,
+			Marks:              input.Marks,
+			MatchMode:          matchModeOrDefault(input.MatchMode),
+			Keywords:           input.Keywords,
+			Tolerance:          input.Tolerance,
+		}
+		if err := models.SetId(&question.Id); err != nil {
+			return nil, err
+		}
+
+		subQuestions, err := questionsFromInput(input.SubQuestions, memorandum, &question.Id, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		question.SubQuestions = subQuestions
+
+		// Parents without explicit marks are worth the sum of their sub-questions
+		if question.Marks == 0 && len(subQuestions) > 0 {
+			for _, subQuestion := range subQuestions {
+				question.Marks += subQuestion.Marks
+			}
+		}
+
+		questions = append(questions, question)
+	}
+	return questions, nil
+}
+
+func matchModeOrDefault(mode models.MatchMode) models.MatchMode {
+	if mode == "" {
+		return models.MatchModeExact
+	}
+	return mode
+}
+
+// Arranges a flat list of questions into trees of sub-questions
+func buildQuestionTree(flat []models.MemorandumQuestion) []models.MemorandumQuestion {
+	roots := []models.MemorandumQuestion{}
+	children := map[string][]models.MemorandumQuestion{}
+	for _, question := range flat {
+		question.SubQuestions = nil
+		if question.ParentId == nil {
+			roots = append(roots, question)
+		} else {
+			children[*question.ParentId] = append(children[*question.ParentId], question)
+		}
+	}
+
+	var attach func(questions []models.MemorandumQuestion) []models.MemorandumQuestion
+	attach = func(questions []models.MemorandumQuestion) []models.MemorandumQuestion {
+		for i := range questions {
+			questions[i].SubQuestions = attach(children[questions[i].Id])
+		}
+		return questions
+	}
+	return attach(roots)
+}
+
+// Returns the questions of a tree that marks are awarded for, i.e. those
+// without sub-questions, in the order they appear on the paper
+func leafQuestions(tree []models.MemorandumQuestion) []models.MemorandumQuestion {
+	leaves := []models.MemorandumQuestion{}
+	for _, question := range tree {
+		if len(question.SubQuestions) == 0 {
+			leaves = append(leaves, question)
+		} else {
+			leaves = append(leaves, leafQuestions(question.SubQuestions)...)
+		}
+	}
+	return leaves
+}
+
+// Returns how deep the question with the given Id is nested, starting at 1
+// for top level questions, or 0 when it does not exist
+func questionDepth(flat []models.MemorandumQuestion, id string) int {
+	byId := map[string]models.MemorandumQuestion{}
+	for _, question := range flat {
+		byId[question.Id] = question
+	}
+
+	depth := 0
+	current, ok := byId[id]
+	for ok && depth <= maxQuestionDepth {
+		depth++
+		if current.ParentId == nil {
+			break
+		}
+		current, ok = byId[*current.ParentId]
+	}
+	return depth
+}
+
+func countSiblings(flat []models.MemorandumQuestion, parentId *string) int {
+	count := 0
+	for _, question := range flat {
+		if (question.ParentId == nil && parentId == nil) ||
+			(question.ParentId != nil && parentId != nil && *question.ParentId == *parentId) {
+			count++
+		}
+	}
+	return count
+}
+
+// Checks that sub-question marks add up to their parent's marks, that
+// numbers are unique among siblings and that the memorandum allocates
+// exactly the exam's total marks
+func checkAllocation(tree []models.MemorandumQuestion, examTotalMarks int) *AllocationReport {
+	report := &AllocationReport{
+		ExamTotalMarks: examTotalMarks,
+		Issues:         []string{},
+	}
+
+	for _, question := range tree {
+		report.AllocatedMarks += question.Marks
+	}
+	report.Issues = append(report.Issues, checkSiblings(tree, "")...)
+
+	if report.AllocatedMarks != examTotalMarks {
+		report.Issues = append(report.Issues, fmt.Sprintf(
+			"questions allocate %d marks but the exam is out of %d", report.AllocatedMarks, examTotalMarks,
+		))
+	}
+
+	report.Complete = len(report.Issues) == 0
+	return report
+}
+
+// Reports the issues within a group of sibling questions and their descendants
+func checkSiblings(questions []models.MemorandumQuestion, parentNumber string) []string {
+	issues := []string{}
+	seen := map[string]bool{}
+	for _, question := range questions {
+		label := question.Number
+		if parentNumber != "" {
+			label = parentNumber + " > " + question.Number
+		}
+
+		if seen[question.Number] {
+			issues = append(issues, fmt.Sprintf("question %s appears more than once", label))
+		}
+		seen[question.Number] = true
+
+		if len(question.SubQuestions) > 0 {
+			sum := 0
+			for _, subQuestion := range question.SubQuestions {
+				sum += subQuestion.Marks
+			}
+			if sum != question.Marks {
+				issues = append(issues, fmt.Sprintf(
+					"sub-questions of question %s allocate %d marks but the question is worth %d", label, sum, question.Marks,
+				))
+			}
+			issues = append(issues, checkSiblings(question.SubQuestions, label)...)
+		}
+	}
+	return issues
+}
+
+// Checks a memorandum that is still being built up. Missing marks are
+// reported, but only allocating more marks than available is an error.
+func checkPartialAllocation(flat []models.MemorandumQuestion, examTotalMarks int) (*AllocationReport, error) {
+	tree := buildQuestionTree(flat)
+	report := checkAllocation(tree, examTotalMarks)
+
+	overAllocated := []string{}
+	if report.AllocatedMarks > examTotalMarks {
+		overAllocated = append(overAllocated, fmt.Sprintf(
+			"questions allocate %d marks but the exam is out of %d", report.AllocatedMarks, examTotalMarks,
+		))
+	}
+	overAllocated = append(overAllocated, checkOverAllocatedParents(tree)...)
+
+	if len(overAllocated) > 0 {
+		report.Issues = overAllocated
+		report.Complete = false
+		return nil, &AllocationError{report}
+	}
+	return report, nil
+}
+
+func checkOverAllocatedParents(questions []models.MemorandumQuestion) []string {
+	issues := []string{}
+	for _, question := range questions {
+		sum := 0
+		for _, subQuestion := range question.SubQuestions {
+			sum += subQuestion.Marks
+		}
+		if sum > question.Marks {
+			issues = append(issues, fmt.Sprintf(
+				"sub-questions of question %s allocate %d marks but the question is worth %d", question.Number, sum, question.Marks,
+			))
+		}
+		issues = append(issues, checkOverAllocatedParents(question.SubQuestions)...)
+	}
+	return issues
+}
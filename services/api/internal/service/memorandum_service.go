@@ -2,21 +2,25 @@ package service
 
 import (
 	"context"
-	"fmt"
-	"io"
 	"mime/multipart"
 
 	"github.com/labstack/gommon/log"
-	minio "github.com/minio/minio-go/v7"
 	"github.com/smartik/api/internal/config"
 	"github.com/smartik/api/internal/models"
 	"github.com/smartik/api/internal/repository"
+	"github.com/smartik/api/internal/repository/storage"
+	"gorm.io/gorm"
 )
 
 type MemorandumService struct {
-	repo        *repository.MemorandumRepository
-	minioClient *minio.Client
-	cfg         *config.Env
+	repo     *repository.MemorandumRepository
+	examRepo *repository.ExamRepository
+	markRepo *repository.QuestionMarkRepository
+	storage  storage.Storage
+	versions *fileVersions
+	links    *DownloadLinkService
+	cfg      *config.Env
+	audit    *AuditService
 }
 
 type MemorandumUploadResult struct {
@@ -27,40 +31,61 @@ type MemorandumUploadResult struct {
 // Creates a new instance of MemorandumService
 func NewMemorandumService(
 	repo *repository.MemorandumRepository,
-	minioClient *minio.Client,
+	examRepo *repository.ExamRepository,
+	markRepo *repository.QuestionMarkRepository,
+	versionRepo *repository.FileVersionRepository,
+	storage storage.Storage,
+	links *DownloadLinkService,
 	cfg *config.Env,
+	audit *AuditService,
 ) *MemorandumService {
-	return &MemorandumService{repo, minioClient, cfg}
+	return &MemorandumService{repo, examRepo, markRepo, storage, &fileVersions{versionRepo, storage, audit}, links, cfg, audit}
 }
 
 // Handles the upload of a single memorandum file
-func (s *MemorandumService) UploadFile(file *multipart.FileHeader, examId string, result *MemorandumUploadResult) (*MemorandumUploadResult, error) {
+func (s *MemorandumService) UploadFile(ctx context.Context, file *multipart.FileHeader, examId string, result *MemorandumUploadResult) (*MemorandumUploadResult, error) {
 	src, err := file.Open()
 	if err != nil {
-		result.addUploadError(file.Filename, "Failed to open file: "+err.Error())
+		result.addUploadError(file.Filename, UploadErrorFailed, "Failed to open file: "+err.Error())
+		return result, nil
 	}
 	defer src.Close()
 
-	// Upload the file to MinIO
-	if err := s.uploadToStorage(file.Filename, src, file.Size, file.Header.Get("Content-Type")); err != nil {
-		result.addUploadError(file.Filename, "Failed to open file: "+err.Error())
+	// The content type the client claims is not trusted
+	contentType, err := inspectUpload(src, file.Size, s.uploadLimits())
+	if err != nil {
+		result.addUploadError(file.Filename, uploadErrorCode(err), err.Error())
+		return result, nil
 	}
 
-	// Create database record
-	//
-	// Note: We attempt the creation regardless of the upload success to
-	// enable the upload of other files that might succeed, then we respond
-	// with partial success.
+	// The Id is generated up front so the storage key can be derived from it
 	memorandum := &models.Memorandum{
 		FileName: file.Filename,
 		ExamId:   examId,
 	}
+	if err := models.SetId(&memorandum.Id); err != nil {
+		return nil, err
+	}
+	memorandum.StorageKey = memorandumKey(examId, memorandum.Id, file.Filename)
 
-	if err := s.repo.Create(memorandum); err != nil {
-		if deleteErr := s.repo.Delete(memorandum.Id); deleteErr != nil {
-			log.Errorf("Failed to delete memorandum record after upload error: %v", deleteErr)
+	// Upload the file to storage
+	if _, err := s.storage.Put(ctx, memorandum.StorageKey, src, file.Size, contentType); err != nil {
+		result.addUploadError(file.Filename, UploadErrorFailed, "Failed to upload to storage: "+err.Error())
+		return result, nil
+	}
+
+	// Create database record, removing the stored file if that fails
+	if err := s.audit.Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.WithTx(tx).Create(memorandum); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionCreate, AuditEntityMemorandum, memorandum.Id, nil, memorandum)
+	}); err != nil {
+		if deleteErr := s.storage.Delete(context.Background(), memorandum.StorageKey); deleteErr != nil {
+			log.Errorf("Failed to delete memorandum file after database error: %v", deleteErr)
 		}
-		result.addUploadError(file.Filename, "Failed to open file: "+err.Error())
+		result.addUploadError(file.Filename, UploadErrorFailed, "Failed to save to database: "+err.Error())
+		return result, nil
 	}
 
 	result.SuccessfulUploads = append(result.SuccessfulUploads, *memorandum)
@@ -74,35 +99,70 @@ func (s *MemorandumService) GetFileStream(id string) (*FileStreamResult, error)
 		return nil, err
 	}
 
-	// Get the file from MinIO storage
-	object, err := s.minioClient.GetObject(
-		context.Background(),
-		s.cfg.MinioStorageBucket,
-		memorandum.FileName,
-		minio.GetObjectOptions{},
-	)
+	return openFileStream(s.storage, memorandum.ObjectKey(), memorandum.FileName)
+}
+
+// Issues a short lived link the signed in user can download the
+// memorandum file from without going through the API
+func (s *MemorandumService) GetDownloadLink(ctx context.Context, id string) (*DownloadLink, error) {
+	memorandum, err := s.repo.GetById(id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get file from storage: %v", err)
+		return nil, err
 	}
 
-	// Get file metadata
-	objectInfo, err := object.Stat()
+	return s.links.issue(ctx, memorandum.ObjectKey(), memorandum.FileName)
+}
+
+// Replaces the file of a memorandum with a corrected one. The record keeps
+// its Id and its questions, the previous file is kept as an earlier version.
+func (s *MemorandumService) ReplaceFile(ctx context.Context, id string, file *multipart.FileHeader) (*models.Memorandum, error) {
+	before, err := s.repo.GetById(id)
 	if err != nil {
-		object.Close()
-		return nil, fmt.Errorf("failed to get file info: %v", err)
+		return nil, err
+	}
+	if err := requireUnlockedExam(s.examRepo, before.ExamId, "its memorandum cannot be replaced"); err != nil {
+		return nil, err
 	}
 
-	return &FileStreamResult{
-		Content:     object,
-		ContentType: objectInfo.ContentType,
-		Filename:    memorandum.FileName,
-		Size:        objectInfo.Size,
-	}, nil
+	var memorandum *models.Memorandum
+	key, err := versionKey(memorandumKey(before.ExamId, id, file.Filename), before.Version+1)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.versions.replace(ctx, memorandumFile(before), &models.Memorandum{}, file, s.uploadLimits(), key, map[string]interface{}{}, func(tx *gorm.DB) error {
+		var err error
+		memorandum, err = s.repo.WithTx(tx).GetById(id)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionReplaceFile, AuditEntityMemorandum, id, before, memorandum)
+	}); err != nil {
+		return nil, err
+	}
+	return memorandum, nil
 }
 
-// Retrieves all memorandums from the database
-func (s *MemorandumService) GetAll() (*[]models.Memorandum, error) {
-	return s.repo.GetAll()
+// Lists every file a memorandum has had, newest first
+func (s *MemorandumService) GetVersions(ctx context.Context, id string) ([]models.FileVersion, error) {
+	memorandum, err := s.repo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.versions.list(ctx, memorandumFile(memorandum))
+}
+
+// Retrieves a file stream of a version of a memorandum's file
+func (s *MemorandumService) GetVersionFileStream(id string, version int) (*FileStreamResult, error) {
+	memorandum, err := s.repo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.versions.open(memorandumFile(memorandum), version)
+}
+
+// Retrieves a page of memorandums
+func (s *MemorandumService) GetAll(params repository.ListParams) (*repository.Page[models.Memorandum], error) {
+	return s.repo.GetAll(params)
 }
 
 // Retrieves a specific memorandum by its ID
@@ -110,41 +170,72 @@ func (s *MemorandumService) GetById(id string) (*models.Memorandum, error) {
 	return s.repo.GetById(id)
 }
 
-// Removes a memorandum from the database and MinIO storage
-func (s *MemorandumService) Delete(id string) error {
+// Removes a memorandum from the database and storage, together with its
+// questions and the marks awarded for them. Memorandums of exams that are
+// being marked or later cannot be deleted.
+func (s *MemorandumService) Delete(ctx context.Context, id string) error {
 	memorandum, err := s.repo.GetById(id)
 	if err != nil {
 		return err
 	}
+	if err := requireExamStatus(s.examRepo, memorandum.ExamId, "its memorandum cannot be deleted once marking started",
+		models.ExamStatusDraft, models.ExamStatusOpen); err != nil {
+		return err
+	}
 
-	// Delete from MinIO storage
-	if err := s.deleteFromStorage(memorandum.FileName); err != nil {
+	// Delete from database, then from storage including the files it replaced
+	var replaced []string
+	if err := s.audit.Transaction(func(tx *gorm.DB) error {
+		var err error
+		replaced, err = s.versions.deleteVersions(tx, memorandumFile(memorandum))
+		if err != nil {
+			return err
+		}
+		if err := auditMemorandumMarks(ctx, tx, s.audit, s.markRepo, id, func() error {
+			return s.repo.WithTx(tx).Delete(id)
+		}); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionDelete, AuditEntityMemorandum, id, memorandum, nil)
+	}); err != nil {
 		return err
 	}
+	s.versions.deleteFiles(ctx, append(replaced, memorandum.ObjectKey())...)
+	return nil
+}
 
-	// Delete from database
-	return s.repo.Delete(id)
+// Moves the files of memorandums stored under their original file
+// name to keys derived from their record Id
+func (s *MemorandumService) MigrateStorageKeys(ctx context.Context) (*StorageKeyMigrationResult, error) {
+	memorandums, err := s.repo.GetAllWithoutStorageKey()
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]rekeyTarget, 0, len(*memorandums))
+	for _, memorandum := range *memorandums {
+		targets = append(targets, rekeyTarget{
+			id:     memorandum.Id,
+			oldKey: memorandum.FileName,
+			newKey: memorandumKey(memorandum.ExamId, memorandum.Id, memorandum.FileName),
+		})
+	}
+
+	return rekeyObjects(ctx, s.storage, targets, s.repo.SetStorageKey), nil
 }
 
-// Handles the actual file upload to MinIO
-func (s *MemorandumService) uploadToStorage(filename string, src io.Reader, size int64, contentType string) error {
-	_, err := s.minioClient.PutObject(
-		context.Background(),
-		s.cfg.MinioStorageBucket,
-		filename,
-		src,
-		size,
-		minio.PutObjectOptions{ContentType: contentType},
-	)
-	return err
+func (s *MemorandumService) uploadLimits() uploadLimits {
+	return uploadLimits{maxSize: s.cfg.UploadMaxFileSize, maxPages: s.cfg.UploadMaxPages}
 }
 
-// Removes a file from MinIO storage
-func (s *MemorandumService) deleteFromStorage(filename string) error {
-	return s.minioClient.RemoveObject(
-		context.Background(),
-		s.cfg.MinioStorageBucket,
-		filename,
-		minio.RemoveObjectOptions{},
-	)
+// Describes the file a memorandum currently points at
+func memorandumFile(memorandum *models.Memorandum) currentFile {
+	return currentFile{
+		recordType: models.FileRecordMemorandum,
+		recordId:   memorandum.Id,
+		fileName:   memorandum.FileName,
+		key:        memorandum.ObjectKey(),
+		version:    memorandum.Version,
+		createdAt:  memorandum.CreatedAt,
+	}
 }
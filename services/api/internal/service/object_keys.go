@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository/storage"
+)
+
+// Summary of a storage key migration run
+type StorageKeyMigrationResult struct {
+	Migrated int               `json:"migrated"`
+	Failed   []FileUploadError `json:"failed"`
+}
+
+// A record whose file has to be moved to a new key
+type rekeyTarget struct {
+	id     string
+	oldKey string
+	newKey string
+}
+
+// Builds the storage key of an answer script from its record Id.
+// Scripts that are not yet linked to an exam are kept under "scripts/".
+func answerScriptKey(examId *string, id, filename string) string {
+	if examId == nil || *examId == "" {
+		return fmt.Sprintf("scripts/%s%s", id, fileExtension(filename))
+	}
+	return fmt.Sprintf("exams/%s/scripts/%s%s", *examId, id, fileExtension(filename))
+}
+
+// Builds the storage key of a memorandum from its record Id
+func memorandumKey(examId, id, filename string) string {
+	return fmt.Sprintf("exams/%s/memoranda/%s%s", examId, id, fileExtension(filename))
+}
+
+// Derives the storage key of a later version from the key of a first file,
+// e.g. exams/1/scripts/2.pdf becomes exams/1/scripts/2.v3.<random Id>.pdf.
+// Concurrent replacements aim for the same version, the random Id keeps
+// them from writing to the same object.
+func versionKey(key string, version int) (string, error) {
+	var unique string
+	if err := models.SetId(&unique); err != nil {
+		return "", err
+	}
+	ext := path.Ext(key)
+	return fmt.Sprintf("%s.v%d.%s%s", strings.TrimSuffix(key, ext), version, unique, ext), nil
+}
+
+// Builds the storage key of a part of a resumable upload. Parts are
+// removed once the upload is completed, aborted or has expired. Every time
+// a part is sent it is stored under a new key, so sending it again never
+// overwrites the content of an upload that is being completed.
+func uploadPartKey(sessionId string, number int) (string, error) {
+	var unique string
+	if err := models.SetId(&unique); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s.%s", legacyUploadPartKey(sessionId, number), unique), nil
+}
+
+// Returns the key a stored part of a resumable upload is kept under
+func storedPartKey(part *models.UploadPart) string {
+	if part.StorageKey != "" {
+		return part.StorageKey
+	}
+	return legacyUploadPartKey(part.SessionId, part.Number)
+}
+
+// The key parts were stored under before every upload of a part got its own
+func legacyUploadPartKey(sessionId string, number int) string {
+	return fmt.Sprintf("uploads/%s/parts/%05d", sessionId, number)
+}
+
+// Returns the lower cased extension of a client supplied file name,
+// dropping it entirely if it contains anything unexpected
+func fileExtension(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if len(ext) < 2 || len(ext) > 10 {
+		return ""
+	}
+	for _, r := range ext[1:] {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return ""
+		}
+	}
+	return ext
+}
+
+// Copies every target's object to its new key and saves the new key.
+// Old objects are only removed once every record that shared them has
+// been moved, since legacy records could point at the same file name.
+func rekeyObjects(ctx context.Context, store storage.Storage, targets []rekeyTarget, save func(id, key string) error) *StorageKeyMigrationResult {
+	result := &StorageKeyMigrationResult{Failed: []FileUploadError{}}
+
+	byOldKey := map[string][]rekeyTarget{}
+	order := []string{}
+	for _, target := range targets {
+		if _, ok := byOldKey[target.oldKey]; !ok {
+			order = append(order, target.oldKey)
+		}
+		byOldKey[target.oldKey] = append(byOldKey[target.oldKey], target)
+	}
+
+	for _, oldKey := range order {
+		moved := true
+		for _, target := range byOldKey[oldKey] {
+			if err := copyObject(ctx, store, target.oldKey, target.newKey); err != nil {
+				result.Failed = append(result.Failed, FileUploadError{Filename: oldKey, Error: err.Error()})
+				moved = false
+				continue
+			}
+			if err := save(target.id, target.newKey); err != nil {
+				result.Failed = append(result.Failed, FileUploadError{Filename: oldKey, Error: err.Error()})
+				moved = false
+				continue
+			}
+			result.Migrated++
+		}
+
+		if moved {
+			if err := store.Delete(ctx, oldKey); err != nil {
+				log.Warnf("Failed to remove %s after re-keying: %v", oldKey, err)
+			}
+		}
+	}
+
+	return result
+}
+
+// Copies a stored object to another key
+func copyObject(ctx context.Context, store storage.Storage, src, dst string) error {
+	object, info, err := store.Get(ctx, src)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			return fmt.Errorf("object %s does not exist", src)
+		}
+		return err
+	}
+	defer object.Close()
+
+	_, err = store.Put(ctx, dst, object, info.Size, info.ContentType)
+	return err
+}
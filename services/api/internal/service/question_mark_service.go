@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Returned when marks cannot be recorded as submitted
+var ErrInvalidMark = errors.New("invalid mark")
+
+// Handles business logic for marks awarded per question
+type QuestionMarkService struct {
+	repo         *repository.QuestionMarkRepository
+	scriptRepo   *repository.AnswerScriptRepository
+	questionRepo *repository.MemorandumQuestionRepository
+	examRepo     *repository.ExamRepository
+	audit        *AuditService
+}
+
+// Creates a new instance of QuestionMarkService
+func NewQuestionMarkService(
+	repo *repository.QuestionMarkRepository,
+	scriptRepo *repository.AnswerScriptRepository,
+	questionRepo *repository.MemorandumQuestionRepository,
+	examRepo *repository.ExamRepository,
+	audit *AuditService,
+) *QuestionMarkService {
+	return &QuestionMarkService{
+		repo:         repo,
+		scriptRepo:   scriptRepo,
+		questionRepo: questionRepo,
+		examRepo:     examRepo,
+		audit:        audit,
+	}
+}
+
+// Retrieves the marks recorded for an answer script
+func (s *QuestionMarkService) GetMarks(answerScriptId string) (*[]models.QuestionMark, error) {
+	if _, err := s.scriptRepo.GetById(answerScriptId); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByAnswerScriptId(answerScriptId)
+}
+
+// Records marks for questions of the answer script's exam, replacing marks
+// recorded earlier for the same questions, and updates the script's totals.
+// Marks can only be recorded while the exam is marked or moderated.
+func (s *QuestionMarkService) RecordMarks(ctx context.Context, answerScriptId string, data *models.RecordQuestionMarks) (*models.AnswerScript, *[]models.QuestionMark, error) {
+	answerScript, err := s.scriptRepo.GetById(answerScriptId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if answerScript.ExamId == nil {
+		return nil, nil, fmt.Errorf("%w: the answer script is not linked to an exam", ErrInvalidMark)
+	}
+	if err := s.requireMarking(*answerScript.ExamId); err != nil {
+		return nil, nil, err
+	}
+
+	questions, err := s.markableQuestions(*answerScript.ExamId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	marks := make([]models.QuestionMark, 0, len(data.Marks))
+	seen := map[string]bool{}
+	for _, input := range data.Marks {
+		question, ok := questions[input.QuestionId]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: question %s is not a markable question of this exam", ErrInvalidMark, input.QuestionId)
+		}
+		if seen[input.QuestionId] {
+			return nil, nil, fmt.Errorf("%w: question %s is marked more than once", ErrInvalidMark, question.Number)
+		}
+		if input.Awarded > question.Marks {
+			return nil, nil, fmt.Errorf("%w: question %s is worth %d marks but %d were awarded",
+				ErrInvalidMark, question.Number, question.Marks, input.Awarded)
+		}
+		seen[input.QuestionId] = true
+
+		marks = append(marks, models.QuestionMark{
+			AnswerScriptId: answerScriptId,
+			QuestionId:     input.QuestionId,
+			Awarded:        input.Awarded,
+			MaxMarks:       question.Marks,
+			MarkedBy:       data.MarkedBy,
+			Comment:        input.Comment,
+		})
+	}
+
+	return s.changeMarks(ctx, answerScriptId, func(repo *repository.QuestionMarkRepository) error {
+		err := repo.Save(answerScriptId, marks)
+		// The question's marks were lowered since they were checked
+		if errors.Is(err, repository.ErrAwardedAboveMaximum) {
+			return fmt.Errorf("%w: %w", ErrInvalidMark, err)
+		}
+		return err
+	})
+}
+
+// Removes the mark of a single question and updates the script's totals
+func (s *QuestionMarkService) DeleteMark(ctx context.Context, answerScriptId, questionId string) (*models.AnswerScript, *[]models.QuestionMark, error) {
+	answerScript, err := s.scriptRepo.GetById(answerScriptId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if answerScript.ExamId != nil {
+		if err := s.requireMarking(*answerScript.ExamId); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return s.changeMarks(ctx, answerScriptId, func(repo *repository.QuestionMarkRepository) error {
+		return repo.Delete(answerScriptId, questionId)
+	})
+}
+
+func (s *QuestionMarkService) requireMarking(examId string) error {
+	return requireExamStatus(s.examRepo, examId, "marks can only change while the exam is marked or moderated",
+		models.ExamStatusMarking, models.ExamStatusModeration)
+}
+
+// Changes the marks of an answer script through a repository bound to a
+// transaction, then reloads the script with its marks and records the
+// changed marks in the audit log, all in that transaction
+func (s *QuestionMarkService) changeMarks(ctx context.Context, answerScriptId string, change func(repo *repository.QuestionMarkRepository) error) (*models.AnswerScript, *[]models.QuestionMark, error) {
+	var answerScript *models.AnswerScript
+	var marks *[]models.QuestionMark
+	err := s.audit.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		previous, err := repo.GetByAnswerScriptId(answerScriptId)
+		if err != nil {
+			return err
+		}
+		if err := change(repo); err != nil {
+			return err
+		}
+
+		answerScript, err = s.scriptRepo.WithTx(tx).GetById(answerScriptId)
+		if err != nil {
+			return err
+		}
+		marks, err = repo.GetByAnswerScriptId(answerScriptId)
+		if err != nil {
+			return err
+		}
+		return auditMarkChanges(ctx, tx, s.audit, *previous, *marks)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return answerScript, marks, nil
+}
+
+// Returns the questions of an exam marks can be awarded for, keyed by Id.
+// Questions with sub-questions are marked through their sub-questions.
+func (s *QuestionMarkService) markableQuestions(examId string) (map[string]models.MemorandumQuestion, error) {
+	return markableQuestions(s.questionRepo, examId)
+}
+
+func markableQuestions(repo *repository.MemorandumQuestionRepository, examId string) (map[string]models.MemorandumQuestion, error) {
+	questions, err := repo.GetByExamId(examId)
+	if err != nil {
+		return nil, err
+	}
+
+	markable := map[string]models.MemorandumQuestion{}
+	for _, question := range leafQuestions(buildQuestionTree(*questions)) {
+		markable[question.Id] = question
+	}
+	return markable, nil
+}
+
+// Records the marks that were created, changed or removed between two
+// states in the audit log, in the transaction tx that changed them
+func auditMarkChanges(ctx context.Context, tx *gorm.DB, audit *AuditService, before, after []models.QuestionMark) error {
+	previous := make(map[string]models.QuestionMark, len(before))
+	for _, mark := range before {
+		previous[mark.Id] = mark
+	}
+
+	for i := range after {
+		mark := &after[i]
+		old, existed := previous[mark.Id]
+		delete(previous, mark.Id)
+
+		var err error
+		if !existed {
+			err = audit.Record(ctx, tx, models.AuditActionCreate, AuditEntityQuestionMark, mark.Id, nil, mark)
+		} else {
+			err = audit.Record(ctx, tx, models.AuditActionUpdate, AuditEntityQuestionMark, mark.Id, &old, mark)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, mark := range previous {
+		if err := audit.Record(ctx, tx, models.AuditActionDelete, AuditEntityQuestionMark, mark.Id, &mark, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Makes a change to the questions of a memorandum in the transaction tx and
+// records the marks it changed or removed along with it, e.g. the marks of
+// questions that were deleted
+func auditMemorandumMarks(ctx context.Context, tx *gorm.DB, audit *AuditService, markRepo *repository.QuestionMarkRepository, memorandumId string, change func() error) error {
+	marks := markRepo.WithTx(tx)
+	before, err := marks.GetByMemorandumId(memorandumId)
+	if err != nil {
+		return err
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	after, err := marks.GetByMemorandumId(memorandumId)
+	if err != nil {
+		return err
+	}
+	return auditMarkChanges(ctx, tx, audit, *before, *after)
+}
@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/smartik/api/internal/auth"
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository"
+	"gorm.io/gorm"
+)
+
+// A record answer scripts can be attached to
+type ScriptOwner string
+
+const (
+	ScriptOwnerStudent ScriptOwner = "student"
+	ScriptOwnerSubject ScriptOwner = "subject"
+	ScriptOwnerExam    ScriptOwner = "exam"
+)
+
+// Returns the column of an answer script that links it to the owner
+func (o ScriptOwner) column() string {
+	return string(o) + "_id"
+}
+
+// Returns the Id of the owner an answer script is attached to, if any
+func (o ScriptOwner) idOf(answerScript *models.AnswerScript) *string {
+	switch o {
+	case ScriptOwnerStudent:
+		return answerScript.StudentId
+	case ScriptOwnerSubject:
+		return answerScript.SubjectId
+	default:
+		return answerScript.ExamId
+	}
+}
+
+// Returned when some of the answer scripts to attach do not exist, or
+// some of those to detach are not attached to the record
+type ScriptsNotFoundError struct {
+	Ids []string
+}
+
+func (e *ScriptsNotFoundError) Error() string {
+	return "answer scripts not found: " + strings.Join(e.Ids, ", ")
+}
+
+// Handles attaching answer scripts to and detaching them from students,
+// subjects and exams
+type ScriptLinkService struct {
+	scriptRepo  *repository.AnswerScriptRepository
+	studentRepo *repository.StudentRepository
+	subjectRepo *repository.SubjectRepository
+	examRepo    *repository.ExamRepository
+	audit       *AuditService
+}
+
+// Creates a new instance of ScriptLinkService
+func NewScriptLinkService(
+	scriptRepo *repository.AnswerScriptRepository,
+	studentRepo *repository.StudentRepository,
+	subjectRepo *repository.SubjectRepository,
+	examRepo *repository.ExamRepository,
+	audit *AuditService,
+) *ScriptLinkService {
+	return &ScriptLinkService{
+		scriptRepo:  scriptRepo,
+		studentRepo: studentRepo,
+		subjectRepo: subjectRepo,
+		examRepo:    examRepo,
+		audit:       audit,
+	}
+}
+
+// Attaches answer scripts to a student, subject or exam. Either all of
+// them are attached or none is. Scripts attached to an exam take its
+// subject, and scripts cannot be moved out of exams whose marks are locked.
+// Scripts moved to another exam lose their marks and extracted answers.
+func (s *ScriptLinkService) Attach(ctx context.Context, owner ScriptOwner, ownerId string, ids []string) (*[]models.AnswerScript, error) {
+	if err := s.checkOwner(owner, ownerId); err != nil {
+		return nil, err
+	}
+
+	ids = uniqueIds(ids)
+	scripts, err := s.loadScripts(ids, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	exams := examCache{repo: s.examRepo}
+	fields := map[string]interface{}{owner.column(): ownerId}
+	switch owner {
+	case ScriptOwnerStudent:
+		// Linking a student by hand is a reviewed match
+		now := time.Now()
+		fields["matched_at"] = now
+		fields["match_status"] = models.MatchStatusConfirmed
+		fields["reviewed_by"] = currentUserId(ctx)
+		fields["reviewed_at"] = now
+
+	case ScriptOwnerSubject:
+		for _, script := range scripts {
+			if script.ExamId == nil {
+				continue
+			}
+			exam, err := exams.get(*script.ExamId)
+			if err != nil {
+				return nil, err
+			}
+			if exam.SubjectId != nil && *exam.SubjectId != ownerId {
+				return nil, ErrSubjectMismatch
+			}
+		}
+
+	case ScriptOwnerExam:
+		if err := requireUploadableExam(s.examRepo, ownerId); err != nil {
+			return nil, err
+		}
+		exam, err := exams.get(ownerId)
+		if err != nil {
+			return nil, err
+		}
+		if exam.SubjectId != nil {
+			for _, script := range scripts {
+				if script.SubjectId != nil && *script.SubjectId != *exam.SubjectId {
+					return nil, ErrSubjectMismatch
+				}
+			}
+			fields["subject_id"] = *exam.SubjectId
+		}
+	}
+
+	if err := s.requireMovable(scripts); err != nil {
+		return nil, err
+	}
+	return s.update(ctx, ids, scripts, nil, fields)
+}
+
+// Detaches answer scripts from a student, subject or exam. Either all of
+// them are detached or none is. Scripts of an exam with a subject keep
+// that subject, scripts removed from an exam lose their marks.
+func (s *ScriptLinkService) Detach(ctx context.Context, owner ScriptOwner, ownerId string, ids []string) (*[]models.AnswerScript, error) {
+	if err := s.checkOwner(owner, ownerId); err != nil {
+		return nil, err
+	}
+
+	ids = uniqueIds(ids)
+	conditions := map[string]interface{}{owner.column(): ownerId}
+	scripts, err := s.loadScripts(ids, func(script *models.AnswerScript) bool {
+		id := owner.idOf(script)
+		return id != nil && *id == ownerId
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exams := examCache{repo: s.examRepo}
+	fields := map[string]interface{}{owner.column(): nil}
+	switch owner {
+	case ScriptOwnerStudent:
+		fields["matched_at"] = nil
+		fields["match_status"] = models.MatchStatusUnmatched
+		fields["reviewed_by"] = currentUserId(ctx)
+		fields["reviewed_at"] = time.Now()
+
+	case ScriptOwnerSubject:
+		for _, script := range scripts {
+			if script.ExamId == nil {
+				continue
+			}
+			exam, err := exams.get(*script.ExamId)
+			if err != nil {
+				return nil, err
+			}
+			if exam.SubjectId != nil {
+				return nil, ErrSubjectMismatch
+			}
+		}
+	}
+
+	if err := s.requireMovable(scripts); err != nil {
+		return nil, err
+	}
+	return s.update(ctx, ids, scripts, conditions, fields)
+}
+
+// Makes sure the record the scripts are attached to or detached from exists
+func (s *ScriptLinkService) checkOwner(owner ScriptOwner, ownerId string) error {
+	var err error
+	switch owner {
+	case ScriptOwnerStudent:
+		_, err = s.studentRepo.GetById(ownerId)
+	case ScriptOwnerSubject:
+		_, err = s.subjectRepo.GetById(ownerId)
+	default:
+		_, err = s.examRepo.GetById(ownerId)
+	}
+	return err
+}
+
+// Retrieves the answer scripts with the given IDs, failing with a
+// ScriptsNotFoundError when any does not exist or is not accepted
+func (s *ScriptLinkService) loadScripts(ids []string, accept func(*models.AnswerScript) bool) ([]models.AnswerScript, error) {
+	found, err := s.scriptRepo.GetByIds(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	scripts := make([]models.AnswerScript, 0, len(*found))
+	existing := make(map[string]bool, len(*found))
+	for _, script := range *found {
+		if accept == nil || accept(&script) {
+			scripts = append(scripts, script)
+			existing[script.Id] = true
+		}
+	}
+
+	var missing []string
+	for _, id := range ids {
+		if !existing[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &ScriptsNotFoundError{missing}
+	}
+	return scripts, nil
+}
+
+// Fails with an ExamStatusError when any of the scripts belongs to an
+// exam whose marks are locked
+func (s *ScriptLinkService) requireMovable(scripts []models.AnswerScript) error {
+	checked := map[string]bool{}
+	for _, script := range scripts {
+		if script.ExamId == nil || checked[*script.ExamId] {
+			continue
+		}
+		if err := requireUnlockedExam(s.examRepo, *script.ExamId, "its answer scripts cannot be moved"); err != nil {
+			return err
+		}
+		checked[*script.ExamId] = true
+	}
+	return nil
+}
+
+// Writes the change to all scripts at once and records it for each
+func (s *ScriptLinkService) update(ctx context.Context, ids []string, before []models.AnswerScript, conditions, fields map[string]interface{}) (*[]models.AnswerScript, error) {
+	previous := make(map[string]*models.AnswerScript, len(before))
+	for i := range before {
+		previous[before[i].Id] = &before[i]
+	}
+
+	var after *[]models.AnswerScript
+	err := s.audit.Transaction(func(tx *gorm.DB) error {
+		repo := s.scriptRepo.WithTx(tx)
+		// The scripts may have changed since they were checked
+		missing, err := repo.UpdateFieldsOfMany(ids, conditions, fields)
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			return &ScriptsNotFoundError{missing}
+		}
+		if err := clearMarking(ctx, tx, s.audit, repo, movedScripts(before, fields)...); err != nil {
+			return err
+		}
+
+		after, err = repo.GetByIds(ids)
+		if err != nil {
+			return err
+		}
+		for i := range *after {
+			script := &(*after)[i]
+			if err := s.audit.Record(ctx, tx, models.AuditActionUpdate, AuditEntityAnswerScript, script.Id, previous[script.Id], script); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+// Returns the IDs of the scripts the change takes out of their exam. Their
+// marks were given against the memorandum of that exam and no longer apply.
+func movedScripts(scripts []models.AnswerScript, fields map[string]interface{}) []string {
+	examId, ok := fields["exam_id"]
+	if !ok {
+		return nil
+	}
+	target, _ := examId.(string)
+
+	var moved []string
+	for _, script := range scripts {
+		if script.ExamId != nil && *script.ExamId != target {
+			moved = append(moved, script.Id)
+		}
+	}
+	return moved
+}
+
+// Deletes the question marks and extracted answers of answer scripts in
+// the transaction tx and records each of them as deleted
+func clearMarking(ctx context.Context, tx *gorm.DB, audit *AuditService, repo *repository.AnswerScriptRepository, ids ...string) error {
+	marks, answers, err := repo.ClearMarking(ids...)
+	if err != nil {
+		return err
+	}
+	if err := auditMarkChanges(ctx, tx, audit, marks, nil); err != nil {
+		return err
+	}
+	for i := range answers {
+		answer := &answers[i]
+		if err := audit.Record(ctx, tx, models.AuditActionDelete, AuditEntityExtractedAnswer, answer.Id, answer, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Looks up exams once per Id
+type examCache struct {
+	repo  *repository.ExamRepository
+	exams map[string]*models.Exam
+}
+
+func (c *examCache) get(id string) (*models.Exam, error) {
+	if exam, ok := c.exams[id]; ok {
+		return exam, nil
+	}
+	exam, err := c.repo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if c.exams == nil {
+		c.exams = map[string]*models.Exam{}
+	}
+	c.exams[id] = exam
+	return exam, nil
+}
+
+// Returns the Id of the signed in user, nil for work done by the system
+func currentUserId(ctx context.Context) *string {
+	if user := auth.UserFromContext(ctx); user != nil {
+		return &user.Id
+	}
+	return nil
+}
+
+// Removes repeated IDs, keeping the first occurrence of each
+func uniqueIds(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
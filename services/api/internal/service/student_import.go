@@ -0,0 +1,297 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/smartik/api/internal/models"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// The most learners accepted in a single class list
+const maxImportRows = 5000
+
+// Returned when a class list cannot be read as a whole
+var ErrInvalidImportFile = errors.New("invalid class list")
+
+// Column names accepted for each student field, compared after lowering
+// the case and turning spaces and dashes into underscores
+var importColumns = map[string][]string{
+	"exam_number": {"exam_number", "exam_no", "examination_number"},
+	"first_name":  {"first_name", "firstname", "name"},
+	"last_name":   {"last_name", "lastname", "surname"},
+}
+
+// Creates and updates students from a CSV or XLSX class list. Rows are
+// matched to existing students by exam number. Invalid rows are reported
+// and skipped, the others are saved together unless dryRun is set.
+// validate checks a student against its validation tags.
+func (s *StudentService) Import(ctx context.Context, file io.Reader, fileName string, dryRun bool, validate func(interface{}) error) (*models.StudentImportResult, error) {
+	records, err := readClassList(file, fileName)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidImportFile)
+	}
+
+	columns, err := importColumnIndexes(records[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(records)-1 > maxImportRows {
+		return nil, fmt.Errorf("%w: at most %d rows can be imported at once", ErrInvalidImportFile, maxImportRows)
+	}
+
+	result := &models.StudentImportResult{DryRun: dryRun, Rows: []models.StudentImportRow{}}
+	students := map[int]*models.Student{}
+	firstRow := map[string]int{}
+	for i, record := range records[1:] {
+		rowNumber := i + 2
+		student := models.Student{
+			ExamNumber: importValue(record, columns, "exam_number"),
+			FirstName:  importValue(record, columns, "first_name"),
+			LastName:   importValue(record, columns, "last_name"),
+		}
+		if student.ExamNumber == "" && student.FirstName == "" && student.LastName == "" {
+			continue
+		}
+
+		row := models.StudentImportRow{Row: rowNumber, ExamNumber: student.ExamNumber}
+		if err := validate(&student); err != nil {
+			row.Errors = importErrors(err)
+		} else if first, ok := firstRow[student.ExamNumber]; ok {
+			row.Errors = []string{fmt.Sprintf("exam_number is repeated, it was first used on row %d", first)}
+		} else {
+			firstRow[student.ExamNumber] = rowNumber
+			students[len(result.Rows)] = &student
+		}
+		if row.Errors != nil {
+			row.Status = models.ImportRowRejected
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	examNumbers := make([]string, 0, len(students))
+	for _, student := range students {
+		examNumbers = append(examNumbers, student.ExamNumber)
+	}
+	existing := map[string]models.Student{}
+	if len(examNumbers) > 0 {
+		found, err := s.repo.GetByExamNumbers(examNumbers)
+		if err != nil {
+			return nil, err
+		}
+		for _, student := range *found {
+			existing[student.ExamNumber] = student
+		}
+	}
+
+	// Decide what each valid row does. Blank names keep the current ones.
+	var created, updated, before []models.Student
+	var createdRows []int
+	for i := range result.Rows {
+		row := &result.Rows[i]
+		student, ok := students[i]
+		if !ok {
+			continue
+		}
+
+		current, found := existing[student.ExamNumber]
+		if !found {
+			row.Status = models.ImportRowCreated
+			created = append(created, *student)
+			createdRows = append(createdRows, i)
+			continue
+		}
+
+		row.StudentId = &current.Id
+		changed := current
+		if student.FirstName != "" {
+			changed.FirstName = student.FirstName
+		}
+		if student.LastName != "" {
+			changed.LastName = student.LastName
+		}
+		if changed.FirstName == current.FirstName && changed.LastName == current.LastName {
+			row.Status = models.ImportRowUnchanged
+			continue
+		}
+		row.Status = models.ImportRowUpdated
+		before = append(before, current)
+		updated = append(updated, changed)
+	}
+
+	if dryRun || (len(created) == 0 && len(updated) == 0) {
+		countImportRows(result)
+		return result, nil
+	}
+
+	var taken map[string]bool
+	err = s.audit.Transaction(func(tx *gorm.DB) error {
+		examNumbers, err := s.repo.WithTx(tx).Import(created, updated)
+		if err != nil {
+			return err
+		}
+		taken = make(map[string]bool, len(examNumbers))
+		for _, examNumber := range examNumbers {
+			taken[examNumber] = true
+		}
+
+		for i := range created {
+			if taken[created[i].ExamNumber] {
+				continue
+			}
+			if err := s.audit.Record(ctx, tx, models.AuditActionCreate, AuditEntityStudent, created[i].Id, nil, &created[i]); err != nil {
+				return err
+			}
+		}
+		for i := range updated {
+			if err := s.audit.Record(ctx, tx, models.AuditActionUpdate, AuditEntityStudent, updated[i].Id, &before[i], &updated[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range created {
+		row := &result.Rows[createdRows[i]]
+		if taken[created[i].ExamNumber] {
+			row.Status = models.ImportRowRejected
+			row.Errors = []string{"exam_number was registered by someone else during the import, import the row again to update the student"}
+			continue
+		}
+		row.StudentId = &created[i].Id
+	}
+	countImportRows(result)
+	return result, nil
+}
+
+// Counts the rows of an import by their status
+func countImportRows(result *models.StudentImportResult) {
+	for _, row := range result.Rows {
+		switch row.Status {
+		case models.ImportRowCreated:
+			result.Summary.Created++
+		case models.ImportRowUpdated:
+			result.Summary.Updated++
+		case models.ImportRowUnchanged:
+			result.Summary.Unchanged++
+		case models.ImportRowRejected:
+			result.Summary.Rejected++
+		}
+	}
+}
+
+// Reads the rows of a class list, choosing the format by the file extension
+func readClassList(file io.Reader, fileName string) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return readCSV(file)
+	case ".xlsx":
+		return readXLSX(file)
+	default:
+		return nil, fmt.Errorf("%w: only .csv and .xlsx files can be imported", ErrInvalidImportFile)
+	}
+}
+
+func readCSV(file io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	// Spreadsheet programs often add a byte order mark
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	// Class lists saved in locales that use a decimal comma are separated by semicolons
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+	return records, nil
+}
+
+// Reads the first sheet of a workbook
+func readXLSX(file io.Reader) ([][]string, error) {
+	workbook, err := excelize.OpenReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+	defer workbook.Close()
+
+	sheets := workbook.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("%w: the workbook has no sheets", ErrInvalidImportFile)
+	}
+	records, err := workbook.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+	return records, nil
+}
+
+// Finds the position of each student field in the header row
+func importColumnIndexes(header []string) (map[string]int, error) {
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+		for field, aliases := range importColumns {
+			for _, alias := range aliases {
+				if _, seen := columns[field]; !seen && name == alias {
+					columns[field] = i
+				}
+			}
+		}
+	}
+
+	if _, ok := columns["exam_number"]; !ok {
+		return nil, fmt.Errorf("%w: the header row has no exam_number column", ErrInvalidImportFile)
+	}
+	return columns, nil
+}
+
+// Reads a field of a row, blank when the file has no column for it
+func importValue(record []string, columns map[string]int, field string) string {
+	if column, ok := columns[field]; ok && column < len(record) {
+		return strings.TrimSpace(record[column])
+	}
+	return ""
+}
+
+// Describes each failed validation of a row, e.g. "first_name failed min=3"
+func importErrors(err error) []string {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return []string{err.Error()}
+	}
+
+	messages := make([]string, 0, len(validationErrors))
+	for _, fieldError := range validationErrors {
+		tag := fieldError.Tag()
+		if fieldError.Param() != "" {
+			tag += "=" + fieldError.Param()
+		}
+		messages = append(messages, fmt.Sprintf("%s failed %s", fieldError.Field(), tag))
+	}
+	return messages
+}
@@ -1,30 +1,49 @@
 package service
 
 import (
+	"context"
+
 	"github.com/smartik/api/internal/models"
 	"github.com/smartik/api/internal/repository"
+	"gorm.io/gorm"
 )
 
 // Handles business logic for student operations
 type StudentService struct {
-	repo *repository.StudentRepository
+	repo       *repository.StudentRepository
+	examRepo   *repository.ExamRepository
+	scriptRepo *repository.AnswerScriptRepository
+	audit      *AuditService
 }
 
 // Creates a new instance of StudentService
-func NewStudentService(repo *repository.StudentRepository) *StudentService {
+func NewStudentService(
+	repo *repository.StudentRepository,
+	examRepo *repository.ExamRepository,
+	scriptRepo *repository.AnswerScriptRepository,
+	audit *AuditService,
+) *StudentService {
 	return &StudentService{
-		repo: repo,
+		repo:       repo,
+		examRepo:   examRepo,
+		scriptRepo: scriptRepo,
+		audit:      audit,
 	}
 }
 
 // Creates a new student record in the database
-func (s *StudentService) Create(student *models.Student) error {
-	return s.repo.Create(student)
+func (s *StudentService) Create(ctx context.Context, student *models.Student) error {
+	return s.audit.Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.WithTx(tx).Create(student); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionCreate, AuditEntityStudent, student.Id, nil, student)
+	})
 }
 
-// Retrieves all students from the database
-func (s *StudentService) GetAll() (*[]models.Student, error) {
-	return s.repo.GetAll()
+// Retrieves a page of students
+func (s *StudentService) GetAll(params repository.ListParams) (*repository.Page[models.Student], error) {
+	return s.repo.GetAll(params)
 }
 
 // Retrieves a specific student by their ID
@@ -33,11 +52,52 @@ func (s *StudentService) GetById(id string) (*models.Student, error) {
 }
 
 // Modifies an existing student record
-func (s *StudentService) Update(id string, updateData *models.UpdateStudent) (*models.Student, error) {
-	return s.repo.Update(id, updateData)
+func (s *StudentService) Update(ctx context.Context, id string, updateData *models.UpdateStudent) (*models.Student, error) {
+	var student *models.Student
+	err := s.audit.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		before, err := repo.GetById(id)
+		if err != nil {
+			return err
+		}
+
+		student, err = repo.Update(id, updateData)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionUpdate, AuditEntityStudent, id, before, student)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return student, nil
 }
 
-// Removes a student from the database
-func (s *StudentService) Delete(id string) error {
-	return s.repo.Delete(id)
+// Removes a student from the database. The database deletes the student's
+// answer scripts with it, so students with scripts in exams whose marks
+// are locked are refused.
+func (s *StudentService) Delete(ctx context.Context, id string) error {
+	return s.audit.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		before, err := repo.GetById(id)
+		if err != nil {
+			return err
+		}
+
+		scripts, err := s.scriptRepo.WithTx(tx).GetByStudentId(id)
+		if err != nil {
+			return err
+		}
+		if err := requireUnlockedScripts(s.examRepo.WithTx(tx), *scripts, "its students' answer scripts cannot be deleted"); err != nil {
+			return err
+		}
+
+		if err := repo.Delete(id); err != nil {
+			return err
+		}
+		if err := s.audit.Record(ctx, tx, models.AuditActionDelete, AuditEntityStudent, id, before, nil); err != nil {
+			return err
+		}
+		return recordCascadedScripts(ctx, tx, s.audit, *scripts)
+	})
 }
@@ -1,30 +1,53 @@
 package service
 
 import (
+	"context"
+	"errors"
+
 	"github.com/smartik/api/internal/models"
 	"github.com/smartik/api/internal/repository"
+	"gorm.io/gorm"
 )
 
+// Returned when a subject that still has exams is deleted
+var ErrSubjectHasExams = errors.New("the subject still has exams, delete them first")
+
 // Handles business logic for subject operations
 type SubjectService struct {
-	repo *repository.SubjectRepository
+	repo       *repository.SubjectRepository
+	examRepo   *repository.ExamRepository
+	scriptRepo *repository.AnswerScriptRepository
+	audit      *AuditService
 }
 
 // Creates a new instance of SubjectService
-func NewSubjectService(repo *repository.SubjectRepository) *SubjectService {
+func NewSubjectService(
+	repo *repository.SubjectRepository,
+	examRepo *repository.ExamRepository,
+	scriptRepo *repository.AnswerScriptRepository,
+	audit *AuditService,
+) *SubjectService {
 	return &SubjectService{
-		repo: repo,
+		repo:       repo,
+		examRepo:   examRepo,
+		scriptRepo: scriptRepo,
+		audit:      audit,
 	}
 }
 
 // Creates a new subject record in the database
-func (s *SubjectService) Create(subject *models.Subject) error {
-	return s.repo.Create(subject)
+func (s *SubjectService) Create(ctx context.Context, subject *models.Subject) error {
+	return s.audit.Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.WithTx(tx).Create(subject); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionCreate, AuditEntitySubject, subject.Id, nil, subject)
+	})
 }
 
-// Retrieves all subjects from the database
-func (s *SubjectService) GetAll() (*[]models.Subject, error) {
-	return s.repo.GetAll()
+// Retrieves a page of subjects
+func (s *SubjectService) GetAll(params repository.ListParams) (*repository.Page[models.Subject], error) {
+	return s.repo.GetAll(params)
 }
 
 // Retrieves a specific subject by its ID
@@ -33,11 +56,61 @@ func (s *SubjectService) GetById(id string) (*models.Subject, error) {
 }
 
 // Modifies an existing subject record
-func (s *SubjectService) Update(id string, updateData *models.UpdateSubject) (*models.Subject, error) {
-	return s.repo.Update(id, updateData)
+func (s *SubjectService) Update(ctx context.Context, id string, updateData *models.UpdateSubject) (*models.Subject, error) {
+	var subject *models.Subject
+	err := s.audit.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		before, err := repo.GetById(id)
+		if err != nil {
+			return err
+		}
+
+		subject, err = repo.Update(id, updateData)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionUpdate, AuditEntitySubject, id, before, subject)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subject, nil
 }
 
-// Removes a subject from the database
-func (s *SubjectService) Delete(id string) error {
-	return s.repo.Delete(id)
+// Removes a subject from the database. Subjects with exams are refused,
+// the exams would otherwise go with them unchecked. The database deletes
+// the subject's answer scripts with it, so subjects with scripts in exams
+// whose marks are locked are refused as well.
+func (s *SubjectService) Delete(ctx context.Context, id string) error {
+	return s.audit.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		before, err := repo.GetById(id)
+		if err != nil {
+			return err
+		}
+
+		exams, err := s.examRepo.WithTx(tx).CountBySubjectId(id)
+		if err != nil {
+			return err
+		}
+		if exams > 0 {
+			return ErrSubjectHasExams
+		}
+
+		scripts, err := s.scriptRepo.WithTx(tx).GetBySubjectId(id)
+		if err != nil {
+			return err
+		}
+		if err := requireUnlockedScripts(s.examRepo.WithTx(tx), *scripts, "subjects with answer scripts for it cannot be deleted"); err != nil {
+			return err
+		}
+
+		if err := repo.Delete(id); err != nil {
+			return err
+		}
+		if err := s.audit.Record(ctx, tx, models.AuditActionDelete, AuditEntitySubject, id, before, nil); err != nil {
+			return err
+		}
+		return recordCascadedScripts(ctx, tx, s.audit, *scripts)
+	})
 }
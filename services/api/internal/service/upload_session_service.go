@@ -0,0 +1,499 @@
+package service
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/auth"
+	"github.com/smartik/api/internal/config"
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository"
+	"github.com/smartik/api/internal/repository/storage"
+	"gorm.io/gorm"
+)
+
+var (
+	// Returned when a part does not fit the upload it is sent for
+	ErrInvalidUploadPart = errors.New("invalid upload part")
+	// Returned when an upload is completed before all of its parts were received
+	ErrUploadIncomplete = errors.New("not all parts of the upload have been received")
+	// Returned when parts are sent for an upload that is being or has been completed
+	ErrUploadNotActive = errors.New("the upload has already been completed")
+	// Returned when an upload is continued after it expired
+	ErrUploadExpired = errors.New("the upload has expired, start it again")
+)
+
+// Handles business logic for resumable uploads of large answer scripts
+type UploadSessionService struct {
+	repo     *repository.UploadSessionRepository
+	examRepo *repository.ExamRepository
+	scripts  *AnswerScriptService
+	storage  storage.Storage
+	cfg      *config.Env
+}
+
+// An upload session together with what is still missing
+type UploadSessionProgress struct {
+	*models.UploadSession
+	PartCount     int   `json:"part_count"`
+	ReceivedBytes int64 `json:"received_bytes"`
+	MissingParts  []int `json:"missing_parts"`
+}
+
+// Creates a new instance of UploadSessionService
+func NewUploadSessionService(
+	repo *repository.UploadSessionRepository,
+	examRepo *repository.ExamRepository,
+	scripts *AnswerScriptService,
+	storage storage.Storage,
+	cfg *config.Env,
+) *UploadSessionService {
+	return &UploadSessionService{
+		repo:     repo,
+		examRepo: examRepo,
+		scripts:  scripts,
+		storage:  storage,
+		cfg:      cfg,
+	}
+}
+
+// Starts a resumable upload for the signed in user. The upload becomes a
+// single answer script, so it is held to the same size limit as one. When
+// an exam is given it has to be open for uploads, like with UploadFiles.
+func (s *UploadSessionService) Create(ctx context.Context, data *models.CreateUploadSession) (*UploadSessionProgress, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, errors.New("uploads can only be started by a signed in user")
+	}
+	if err := checkUploadSize(data.Size, s.cfg.UploadMaxFileSize); err != nil {
+		return nil, err
+	}
+
+	if _, err := scriptSubject(s.examRepo, data.ExamId, nil); err != nil {
+		return nil, err
+	}
+	if data.ExamId != nil {
+		if err := requireUploadableExam(s.examRepo, *data.ExamId); err != nil {
+			return nil, err
+		}
+	}
+
+	s.deleteExpired(ctx)
+
+	session := &models.UploadSession{
+		UserId:      user.Id,
+		ExamId:      data.ExamId,
+		FileName:    data.FileName,
+		ContentType: data.ContentType,
+		Size:        data.Size,
+		PartSize:    s.cfg.UploadPartSize,
+		Status:      models.UploadSessionActive,
+		ExpiresAt:   time.Now().Add(s.cfg.UploadSessionTTL),
+		Parts:       []models.UploadPart{},
+	}
+	if session.ContentType == "" {
+		session.ContentType = "application/octet-stream"
+	}
+	if err := s.repo.Create(session); err != nil {
+		return nil, err
+	}
+	return uploadProgress(session), nil
+}
+
+// Retrieves an upload of the signed in user with the parts received so far
+func (s *UploadSessionService) GetById(ctx context.Context, id string) (*UploadSessionProgress, error) {
+	session, err := s.getOwnSession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return uploadProgress(session), nil
+}
+
+// Stores a part of an upload. Sending a part again replaces it. length is
+// the size announced by the client, negative when unknown. checksum is
+// the hex encoded MD5 of the part and is verified when given. A part is
+// only recorded while the upload is still active when it has been stored.
+func (s *UploadSessionService) UploadPart(ctx context.Context, id string, number int, r io.Reader, length int64, checksum string) (*models.UploadPart, error) {
+	session, err := s.getOwnSession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != models.UploadSessionActive {
+		return nil, ErrUploadNotActive
+	}
+	// Expired uploads are only removed when the next upload starts
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrUploadExpired
+	}
+	if number < 1 || number > session.PartCount() {
+		return nil, fmt.Errorf("%w: part numbers run from 1 to %d", ErrInvalidUploadPart, session.PartCount())
+	}
+
+	expected := session.PartLength(number)
+	if length >= 0 && length != expected {
+		return nil, fmt.Errorf("%w: part %d must be %d bytes, not %d", ErrInvalidUploadPart, number, expected, length)
+	}
+
+	// Read no more than the part may hold, so an oversized body is noticed
+	// below instead of being stored
+	hash := md5.New()
+	counter := &countingReader{r: io.LimitReader(r, expected)}
+	key, err := uploadPartKey(session.Id, number)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.storage.Put(ctx, key, io.TeeReader(counter, hash), expected, "application/octet-stream"); err != nil {
+		if counter.eof && counter.n < expected {
+			return nil, fmt.Errorf("%w: received %d of the %d bytes of part %d", ErrInvalidUploadPart, counter.n, expected, number)
+		}
+		return nil, err
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	var reject error
+	if n, _ := r.Read(make([]byte, 1)); n > 0 {
+		reject = fmt.Errorf("%w: part %d is larger than %d bytes", ErrInvalidUploadPart, number, expected)
+	} else if checksum != "" && !strings.EqualFold(checksum, sum) {
+		reject = fmt.Errorf("%w: the checksum of part %d does not match its content", ErrInvalidUploadPart, number)
+	}
+	if reject != nil {
+		s.deletePart(key)
+		return nil, reject
+	}
+
+	// The upload may have been completed while the part was being stored
+	part := &models.UploadPart{SessionId: session.Id, Number: number, Size: expected, Checksum: sum, StorageKey: key}
+	saved, replaced, err := s.repo.SavePart(part)
+	if err != nil || !saved {
+		s.deletePart(key)
+		if err == nil {
+			err = ErrUploadNotActive
+		}
+		return nil, err
+	}
+	if replaced != nil {
+		s.deletePart(storedPartKey(replaced))
+	}
+
+	// Uploads expire once nothing has been sent for a while
+	if err := s.repo.UpdateFields(session.Id, map[string]interface{}{
+		"expires_at": time.Now().Add(s.cfg.UploadSessionTTL),
+	}); err != nil {
+		return nil, err
+	}
+	return part, nil
+}
+
+// Joins the parts of an upload into a new answer script. Completing an
+// upload again returns the same answer script, so a client that lost the
+// response can safely retry. created is false in that case.
+func (s *UploadSessionService) Complete(ctx context.Context, id string) (answerScript *models.AnswerScript, created bool, err error) {
+	session, err := s.getOwnSession(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	if session.Status == models.UploadSessionCompleted && session.AnswerScriptId != nil {
+		answerScript, err := s.scripts.repo.GetById(*session.AnswerScriptId)
+		return answerScript, false, err
+	}
+	if session.Status != models.UploadSessionActive {
+		return nil, false, ErrUploadNotActive
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, false, ErrUploadExpired
+	}
+
+	if missing := missingParts(session); len(missing) > 0 {
+		return nil, false, fmt.Errorf("%w, missing parts: %s", ErrUploadIncomplete, joinInts(missing))
+	}
+
+	// The exam may have moved on since the upload started
+	subjectId, err := scriptSubject(s.examRepo, session.ExamId, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if session.ExamId != nil {
+		if err := requireUploadableExam(s.examRepo, *session.ExamId); err != nil {
+			return nil, false, err
+		}
+	}
+
+	// Only one request gets to join the parts, and no part is recorded
+	// once the upload is claimed
+	claimed, err := s.repo.UpdateStatus(session.Id, models.UploadSessionActive, models.UploadSessionCompleting)
+	if err != nil {
+		return nil, false, err
+	}
+	if !claimed {
+		return nil, false, ErrUploadNotActive
+	}
+	reopen := func() {
+		if _, err := s.repo.UpdateStatus(session.Id, models.UploadSessionCompleting, models.UploadSessionActive); err != nil {
+			log.Errorf("Failed to reopen upload %s: %v", session.Id, err)
+		}
+	}
+
+	// Parts may have been sent again before the upload was claimed
+	session, err = s.repo.GetById(session.Id)
+	if err != nil {
+		reopen()
+		return nil, false, err
+	}
+	if missing := missingParts(session); len(missing) > 0 {
+		reopen()
+		return nil, false, fmt.Errorf("%w, missing parts: %s", ErrUploadIncomplete, joinInts(missing))
+	}
+
+	// The joined parts are checked like a file uploaded in one request.
+	// The content type the client claims is not trusted.
+	parts := newPartsReader(ctx, s.storage, session)
+	defer parts.Close()
+	contentType, err := inspectUpload(parts, session.Size, s.scripts.uploadLimits())
+	if err != nil {
+		reopen()
+		return nil, false, err
+	}
+
+	answerScript, err = s.scripts.storeScript(ctx, session.FileName, parts, session.Size, contentType, session.ExamId, subjectId)
+	if err != nil {
+		reopen()
+		return nil, false, err
+	}
+
+	if err := s.repo.UpdateFields(session.Id, map[string]interface{}{
+		"status":           models.UploadSessionCompleted,
+		"answer_script_id": answerScript.Id,
+	}); err != nil {
+		return nil, false, err
+	}
+	s.deleteParts(ctx, session)
+	return answerScript, true, nil
+}
+
+// Cancels an upload and removes the parts received so far
+func (s *UploadSessionService) Abort(ctx context.Context, id string) error {
+	session, err := s.getOwnSession(ctx, id)
+	if err != nil {
+		return err
+	}
+	if session.Status == models.UploadSessionCompleting {
+		return ErrUploadNotActive
+	}
+
+	s.deleteParts(ctx, session)
+	return s.repo.Delete(session.Id)
+}
+
+// Retrieves an upload session, treating uploads of other users as missing
+func (s *UploadSessionService) getOwnSession(ctx context.Context, id string) (*models.UploadSession, error) {
+	session, err := s.repo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if user := auth.UserFromContext(ctx); user == nil || user.Id != session.UserId {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return session, nil
+}
+
+// Removes expired uploads along with their parts. Failures are logged,
+// the next run tries again.
+func (s *UploadSessionService) deleteExpired(ctx context.Context) {
+	sessions, err := s.repo.GetExpired()
+	if err != nil {
+		log.Warnf("Failed to find expired uploads: %v", err)
+		return
+	}
+
+	for i := range *sessions {
+		session := &(*sessions)[i]
+		s.deleteParts(ctx, session)
+		if err := s.repo.Delete(session.Id); err != nil {
+			log.Warnf("Failed to delete expired upload %s: %v", session.Id, err)
+		}
+	}
+}
+
+// Removes the stored parts of an upload
+func (s *UploadSessionService) deleteParts(ctx context.Context, session *models.UploadSession) {
+	for _, key := range partKeys(session) {
+		if err := s.storage.Delete(ctx, key); err != nil {
+			log.Warnf("Failed to remove upload part %s: %v", key, err)
+		}
+	}
+}
+
+// Removes a stored part that no record points at
+func (s *UploadSessionService) deletePart(key string) {
+	if err := s.storage.Delete(context.Background(), key); err != nil {
+		log.Warnf("Failed to remove upload part %s: %v", key, err)
+	}
+}
+
+func uploadProgress(session *models.UploadSession) *UploadSessionProgress {
+	progress := &UploadSessionProgress{
+		UploadSession: session,
+		PartCount:     session.PartCount(),
+		MissingParts:  missingParts(session),
+	}
+	for _, part := range session.Parts {
+		progress.ReceivedBytes += part.Size
+	}
+	return progress
+}
+
+// Returns the numbers of the parts that have not been received, in order
+func missingParts(session *models.UploadSession) []int {
+	received := map[int]bool{}
+	for _, part := range session.Parts {
+		received[part.Number] = true
+	}
+
+	missing := []int{}
+	for number := 1; number <= session.PartCount(); number++ {
+		if !received[number] {
+			missing = append(missing, number)
+		}
+	}
+	return missing
+}
+
+func partKeys(session *models.UploadSession) []string {
+	keys := make([]string, 0, len(session.Parts))
+	for _, part := range session.Parts {
+		keys = append(keys, storedPartKey(&part))
+	}
+	return keys
+}
+
+func joinInts(values []int) string {
+	names := make([]string, len(values))
+	for i, value := range values {
+		names[i] = fmt.Sprint(value)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Counts the bytes read from the underlying reader and whether it ran out
+type countingReader struct {
+	r   io.Reader
+	n   int64
+	eof bool
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if err == io.EOF {
+		c.eof = true
+	}
+	return n, err
+}
+
+// Reads the recorded parts of a complete upload as one file, opening each
+// part only when it is reached. Seeking is supported so the joined file can
+// be inspected before it is stored.
+type partsReader struct {
+	ctx     context.Context
+	storage storage.Storage
+	keys    []string
+	sizes   []int64
+	size    int64 // Of all parts together
+	offset  int64 // Where the next read starts
+	current storage.Object
+}
+
+func newPartsReader(ctx context.Context, storage storage.Storage, session *models.UploadSession) *partsReader {
+	p := &partsReader{ctx: ctx, storage: storage}
+	for i := range session.Parts {
+		part := &session.Parts[i]
+		p.keys = append(p.keys, storedPartKey(part))
+		p.sizes = append(p.sizes, part.Size)
+		p.size += part.Size
+	}
+	return p
+}
+
+func (p *partsReader) Read(b []byte) (int, error) {
+	for {
+		if p.offset >= p.size {
+			return 0, io.EOF
+		}
+		if p.current == nil {
+			if err := p.open(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := p.current.Read(b)
+		p.offset += int64(n)
+		if err == io.EOF {
+			p.current.Close()
+			p.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// Opens the part holding the current offset, positioned at the offset
+func (p *partsReader) open() error {
+	start := int64(0)
+	for i, size := range p.sizes {
+		if p.offset >= start+size {
+			start += size
+			continue
+		}
+
+		object, _, err := p.storage.Get(p.ctx, p.keys[i])
+		if err != nil {
+			return fmt.Errorf("failed to open upload part %s: %w", p.keys[i], err)
+		}
+		if within := p.offset - start; within > 0 {
+			if _, err := object.Seek(within, io.SeekStart); err != nil {
+				object.Close()
+				return err
+			}
+		}
+		p.current = object
+		return nil
+	}
+	return io.EOF
+}
+
+func (p *partsReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += p.offset
+	case io.SeekEnd:
+		offset += p.size
+	}
+	if offset < 0 {
+		return 0, errors.New("seek before the start of the upload")
+	}
+
+	if err := p.Close(); err != nil {
+		return 0, err
+	}
+	p.offset = offset
+	return offset, nil
+}
+
+func (p *partsReader) Close() error {
+	if p.current == nil {
+		return nil
+	}
+	err := p.current.Close()
+	p.current = nil
+	return err
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository/storage"
+)
+
+func TestPartsReaderJoinsAndSeeksAcrossParts(t *testing.T) {
+	ctx := context.Background()
+	content := "the quick brown fox jumps"
+	session := &models.UploadSession{Size: int64(len(content)), PartSize: 10}
+	session.Id = "upload"
+
+	store := storage.NewMemoryStorage()
+	for number := 1; number <= session.PartCount(); number++ {
+		start := int64(number-1) * session.PartSize
+		part := content[start : start+session.PartLength(number)]
+		key, err := uploadPartKey(session.Id, number)
+		if err != nil {
+			t.Fatalf("uploadPartKey(%d): %v", number, err)
+		}
+		if _, err := store.Put(ctx, key, strings.NewReader(part), int64(len(part)), ""); err != nil {
+			t.Fatalf("Put part %d: %v", number, err)
+		}
+		session.Parts = append(session.Parts, models.UploadPart{
+			SessionId: session.Id, Number: number, Size: int64(len(part)), StorageKey: key,
+		})
+	}
+
+	parts := newPartsReader(ctx, store, session)
+	defer parts.Close()
+
+	joined, err := io.ReadAll(parts)
+	if err != nil || string(joined) != content {
+		t.Fatalf("ReadAll = %q, %v, want %q", joined, err, content)
+	}
+
+	tests := []struct {
+		offset int64
+		whence int
+		want   string
+	}{
+		{offset: 0, whence: io.SeekStart, want: "the quick "},
+		{offset: 11, whence: io.SeekStart, want: "rown fox j"},
+		{offset: -5, whence: io.SeekEnd, want: "jumps"},
+		{offset: 25, whence: io.SeekStart, want: ""},
+	}
+	for _, tt := range tests {
+		if _, err := parts.Seek(tt.offset, tt.whence); err != nil {
+			t.Fatalf("Seek(%d, %d): %v", tt.offset, tt.whence, err)
+		}
+		got, err := io.ReadAll(io.LimitReader(parts, 10))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("after Seek(%d, %d) read %q, %v, want %q", tt.offset, tt.whence, got, err, tt.want)
+		}
+	}
+
+	if _, err := parts.Seek(-1, io.SeekStart); err == nil {
+		t.Error("Seek before the start succeeded")
+	}
+}
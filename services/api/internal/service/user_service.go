@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/smartik/api/internal/auth"
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	// Returned when another user already has the email address
+	ErrEmailTaken = errors.New("a user with this email already exists")
+
+	// Returned when admins try to delete, deactivate or demote themselves
+	ErrSelfModification = errors.New("you cannot delete, deactivate or demote your own account")
+)
+
+// Handles business logic for managing user accounts
+type UserService struct {
+	repo        *repository.UserRepository
+	sessionRepo *repository.SessionRepository
+	audit       *AuditService
+}
+
+// Creates a new instance of UserService
+func NewUserService(repo *repository.UserRepository, sessionRepo *repository.SessionRepository, audit *AuditService) *UserService {
+	return &UserService{
+		repo:        repo,
+		sessionRepo: sessionRepo,
+		audit:       audit,
+	}
+}
+
+// Creates a new user account
+func (s *UserService) Create(ctx context.Context, data *models.CreateUser) (*models.User, error) {
+	if err := s.checkEmailAvailable(data.Email, ""); err != nil {
+		return nil, err
+	}
+
+	hash, err := auth.HashPassword(data.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Email:        data.Email,
+		Name:         data.Name,
+		PasswordHash: hash,
+		Role:         data.Role,
+		Active:       true,
+	}
+	err = s.audit.Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.WithTx(tx).Create(user); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionCreate, AuditEntityUser, user.Id, nil, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Retrieves a page of users
+func (s *UserService) GetAll(params repository.ListParams) (*repository.Page[models.User], error) {
+	return s.repo.GetAll(params)
+}
+
+// Retrieves a specific user by their ID
+func (s *UserService) GetById(id string) (*models.User, error) {
+	return s.repo.GetById(id)
+}
+
+// Modifies a user account. Changing the password or deactivating the
+// account signs the user out everywhere.
+func (s *UserService) Update(ctx context.Context, id string, data *models.UpdateUser) (*models.User, error) {
+	before, err := s.repo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if isCurrentUser(ctx, id) && ((data.Role != nil && *data.Role != models.RoleAdmin) || (data.Active != nil && !*data.Active)) {
+		return nil, ErrSelfModification
+	}
+
+	fields := map[string]interface{}{}
+	if data.Email != nil {
+		if err := s.checkEmailAvailable(*data.Email, id); err != nil {
+			return nil, err
+		}
+		fields["email"] = *data.Email
+	}
+	if data.Name != nil {
+		fields["name"] = *data.Name
+	}
+	if data.Role != nil {
+		fields["role"] = *data.Role
+	}
+	if data.Active != nil {
+		fields["active"] = *data.Active
+	}
+	if data.Password != nil {
+		hash, err := auth.HashPassword(*data.Password)
+		if err != nil {
+			return nil, err
+		}
+		fields["password_hash"] = hash
+	}
+
+	var user *models.User
+	err = s.audit.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		if len(fields) > 0 {
+			if err := repo.UpdateFields(id, fields); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		user, err = repo.GetById(id)
+		if err != nil {
+			return err
+		}
+
+		// The password hash is never part of the snapshots, record that it changed
+		after := auditSnapshot(user)
+		if data.Password != nil && after != nil {
+			after["password_changed"] = true
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionUpdate, AuditEntityUser, id, before, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if data.Password != nil || (data.Active != nil && !*data.Active) {
+		if err := s.sessionRepo.DeleteByUserId(id); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// Removes a user account
+func (s *UserService) Delete(ctx context.Context, id string) error {
+	if isCurrentUser(ctx, id) {
+		return ErrSelfModification
+	}
+
+	return s.audit.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		before, err := repo.GetById(id)
+		if err != nil {
+			return err
+		}
+
+		if err := repo.Delete(id); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, models.AuditActionDelete, AuditEntityUser, id, before, nil)
+	})
+}
+
+// Reports whether the user with the given Id is the one signed in
+func isCurrentUser(ctx context.Context, id string) bool {
+	user := auth.UserFromContext(ctx)
+	return user != nil && user.Id == id
+}
+
+func (s *UserService) checkEmailAvailable(email, exceptId string) error {
+	existing, err := s.repo.GetByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.Id != exceptId {
+		return ErrEmailTaken
+	}
+	return nil
+}
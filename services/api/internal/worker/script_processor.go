@@ -0,0 +1,167 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"github.com/smartik/api/internal/auth"
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/ocr"
+	"github.com/smartik/api/internal/repository/storage"
+)
+
+// How long a claimed script may stay in processing before another
+// worker is allowed to pick it up again
+const claimTimeout = 10 * time.Minute
+
+// Longest exam number that fits the scanned_exam_number column
+const maxExamNumberLength = 20
+
+// How often scripts are polled for when no usable interval is configured
+const defaultPollInterval = 5 * time.Second
+
+// Hands out answer scripts awaiting processing and records the outcome
+type Scripts interface {
+	ClaimForProcessing(limit int, staleAfter time.Duration) ([]models.AnswerScript, error)
+	RecordRecognized(ctx context.Context, id, examNumber string, confidence float32) error
+	RecordProcessingFailure(ctx context.Context, id, reason string) error
+}
+
+// Links a processed answer script to a student
+type Matcher interface {
+	MatchScript(ctx context.Context, id string) (*models.AnswerScript, error)
+}
+
+// Picks up newly uploaded answer scripts and extracts the exam number
+// written on them. Several processors, even in different processes, can
+// share a database since every script is claimed before it is processed.
+type ScriptProcessor struct {
+	scripts      Scripts
+	storage      storage.Storage
+	recognizer   ocr.Recognizer
+	matcher      Matcher
+	concurrency  int
+	pollInterval time.Duration
+}
+
+// Creates a new instance of ScriptProcessor
+func NewScriptProcessor(
+	scripts Scripts,
+	storage storage.Storage,
+	recognizer ocr.Recognizer,
+	matcher Matcher,
+	concurrency int,
+	pollInterval time.Duration,
+) *ScriptProcessor {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	return &ScriptProcessor{
+		scripts:      scripts,
+		storage:      storage,
+		recognizer:   recognizer,
+		matcher:      matcher,
+		concurrency:  concurrency,
+		pollInterval: pollInterval,
+	}
+}
+
+// Polls for scripts awaiting processing until ctx is cancelled
+func (p *ScriptProcessor) Run(ctx context.Context) {
+	ctx = auth.WithSystemActor(ctx, "worker")
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep draining while full batches are being returned
+		for p.processBatch(ctx) == p.concurrency {
+			if ctx.Err() != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Claims and processes one batch of scripts, returning how many were claimed
+func (p *ScriptProcessor) processBatch(ctx context.Context) int {
+	answerScripts, err := p.scripts.ClaimForProcessing(p.concurrency, claimTimeout)
+	if err != nil {
+		log.Errorf("Failed to claim answer scripts for processing: %v", err)
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	for _, answerScript := range answerScripts {
+		wg.Add(1)
+		go func(answerScript models.AnswerScript) {
+			defer wg.Done()
+			p.process(ctx, &answerScript)
+		}(answerScript)
+	}
+	wg.Wait()
+
+	return len(answerScripts)
+}
+
+// Runs a single script through the recognizer and records the outcome
+func (p *ScriptProcessor) process(ctx context.Context, answerScript *models.AnswerScript) {
+	result, err := p.recognize(ctx, answerScript)
+	if err != nil {
+		if ctx.Err() != nil {
+			return // Shutting down, the claim will expire and the script is retried
+		}
+
+		log.Warnf("Failed to process answer script %s: %v", answerScript.Id, err)
+		if err := p.scripts.RecordProcessingFailure(ctx, answerScript.Id, err.Error()); err != nil {
+			log.Errorf("Failed to save processing result for answer script %s: %v", answerScript.Id, err)
+		}
+		return
+	}
+
+	if err := p.scripts.RecordRecognized(ctx, answerScript.Id, result.ExamNumber, result.Confidence); err != nil {
+		log.Errorf("Failed to save processing result for answer script %s: %v", answerScript.Id, err)
+		return
+	}
+
+	if _, err := p.matcher.MatchScript(ctx, answerScript.Id); err != nil {
+		log.Errorf("Failed to match answer script %s to a student: %v", answerScript.Id, err)
+	}
+}
+
+func (p *ScriptProcessor) recognize(ctx context.Context, answerScript *models.AnswerScript) (*ocr.Result, error) {
+	object, info, err := p.storage.Get(ctx, answerScript.ObjectKey())
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			return nil, errors.New("file is missing from storage")
+		}
+		return nil, err
+	}
+	defer object.Close()
+
+	result, err := p.recognizer.Recognize(ctx, object, info.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	result.ExamNumber = strings.ToUpper(strings.TrimSpace(result.ExamNumber))
+	if runes := []rune(result.ExamNumber); len(runes) > maxExamNumberLength {
+		result.ExamNumber = string(runes[:maxExamNumberLength])
+	}
+	if result.ExamNumber == "" {
+		return nil, ocr.ErrNoExamNumber
+	}
+	return result, nil
+}
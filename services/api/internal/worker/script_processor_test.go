@@ -0,0 +1,218 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/smartik/api/internal/models"
+	"github.com/smartik/api/internal/ocr"
+	"github.com/smartik/api/internal/repository/storage"
+)
+
+// Hands out queued scripts like the database does and keeps what was recorded
+type fakeScripts struct {
+	mu         sync.Mutex
+	queued     []models.AnswerScript
+	claimLimit int
+	recognized map[string]ocr.Result
+	failed     map[string]string
+}
+
+func newFakeScripts(scripts ...models.AnswerScript) *fakeScripts {
+	return &fakeScripts{queued: scripts, recognized: map[string]ocr.Result{}, failed: map[string]string{}}
+}
+
+func (f *fakeScripts) ClaimForProcessing(limit int, staleAfter time.Duration) ([]models.AnswerScript, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.claimLimit = limit
+	n := min(limit, len(f.queued))
+	claimed := f.queued[:n]
+	f.queued = f.queued[n:]
+	return claimed, nil
+}
+
+func (f *fakeScripts) RecordRecognized(ctx context.Context, id, examNumber string, confidence float32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.recognized[id] = ocr.Result{ExamNumber: examNumber, Confidence: confidence}
+	return nil
+}
+
+func (f *fakeScripts) RecordProcessingFailure(ctx context.Context, id, reason string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failed[id] = reason
+	return nil
+}
+
+type fakeMatcher struct {
+	mu      sync.Mutex
+	matched []string
+}
+
+func (m *fakeMatcher) MatchScript(ctx context.Context, id string) (*models.AnswerScript, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.matched = append(m.matched, id)
+	return nil, nil
+}
+
+func TestProcessBatchRecordsWhatWasRecognized(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	files := map[string]string{
+		"found":   "Exam number: JOH5196",
+		"blank":   "nothing written here",
+		"missing": "",
+	}
+	var queued []models.AnswerScript
+	for _, id := range []string{"found", "blank", "missing"} {
+		script := models.AnswerScript{FileName: id + ".pdf"}
+		script.Id = id
+		queued = append(queued, script)
+		if id == "missing" {
+			continue
+		}
+		if _, err := store.Put(ctx, script.ObjectKey(), strings.NewReader(files[id]), int64(len(files[id])), "application/pdf"); err != nil {
+			t.Fatalf("Put %s: %v", id, err)
+		}
+	}
+
+	scripts := newFakeScripts(queued...)
+	matcher := &fakeMatcher{}
+	processor := NewScriptProcessor(scripts, store, ocr.NewStubRecognizer(), matcher, 3, time.Second)
+
+	if claimed := processor.processBatch(ctx); claimed != 3 {
+		t.Fatalf("processBatch claimed %d scripts, want 3", claimed)
+	}
+	if scripts.claimLimit != 3 {
+		t.Errorf("claimed up to %d scripts, want the concurrency of 3", scripts.claimLimit)
+	}
+
+	if got := scripts.recognized["found"]; got.ExamNumber != "JOH5196" || got.Confidence != 1 {
+		t.Errorf("recognized %+v, want JOH5196 with confidence 1", got)
+	}
+	if len(matcher.matched) != 1 || matcher.matched[0] != "found" {
+		t.Errorf("matched %v, want only the recognized script", matcher.matched)
+	}
+
+	if reason := scripts.failed["blank"]; reason != ocr.ErrNoExamNumber.Error() {
+		t.Errorf("blank script failed with %q, want %q", reason, ocr.ErrNoExamNumber)
+	}
+	if reason := scripts.failed["missing"]; reason != "file is missing from storage" {
+		t.Errorf("missing script failed with %q", reason)
+	}
+	if _, ok := scripts.recognized["blank"]; ok {
+		t.Error("a script without an exam number was recorded as recognized")
+	}
+
+	if claimed := processor.processBatch(ctx); claimed != 0 {
+		t.Errorf("second batch claimed %d scripts, want 0", claimed)
+	}
+}
+
+// Recognizes a fixed result, or fails with err
+type fixedRecognizer struct {
+	result ocr.Result
+	err    error
+}
+
+func (r *fixedRecognizer) Recognize(ctx context.Context, _ io.Reader, contentType string) (*ocr.Result, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	result := r.result
+	return &result, nil
+}
+
+func TestProcessNormalizesTheExamNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		scanned string
+		want    string
+	}{
+		{name: "upper cased and trimmed", scanned: "  joh5196\n", want: "JOH5196"},
+		{name: "cut to the column", scanned: strings.Repeat("A", 25), want: strings.Repeat("A", maxExamNumberLength)},
+		{name: "cut on characters", scanned: strings.Repeat("Ø", 25), want: strings.Repeat("Ø", maxExamNumberLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := testScript(t, "script")
+			scripts := newFakeScripts()
+			processor := NewScriptProcessor(scripts, script.store, &fixedRecognizer{result: ocr.Result{ExamNumber: tt.scanned, Confidence: 0.5}}, &fakeMatcher{}, 1, time.Second)
+
+			processor.process(context.Background(), &script.AnswerScript)
+			if got := scripts.recognized["script"].ExamNumber; got != tt.want {
+				t.Errorf("recorded %q, want %q", got, tt.want)
+			}
+			if !utf8.ValidString(scripts.recognized["script"].ExamNumber) {
+				t.Error("recorded exam number is not valid UTF-8")
+			}
+		})
+	}
+}
+
+func TestProcessRecordsRecognizerFailures(t *testing.T) {
+	script := testScript(t, "script")
+	scripts := newFakeScripts()
+	matcher := &fakeMatcher{}
+	processor := NewScriptProcessor(scripts, script.store, &fixedRecognizer{err: errors.New("ocr service unavailable")}, matcher, 1, time.Second)
+
+	processor.process(context.Background(), &script.AnswerScript)
+	if reason := scripts.failed["script"]; reason != "ocr service unavailable" {
+		t.Errorf("failed with %q, want the recognizer error", reason)
+	}
+	if len(matcher.matched) != 0 {
+		t.Errorf("matched %v after a failure", matcher.matched)
+	}
+}
+
+func TestProcessLeavesScriptsClaimedWhenShuttingDown(t *testing.T) {
+	script := testScript(t, "script")
+	scripts := newFakeScripts()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	processor := NewScriptProcessor(scripts, script.store, &fixedRecognizer{err: context.Canceled}, &fakeMatcher{}, 1, time.Second)
+
+	processor.process(ctx, &script.AnswerScript)
+	if len(scripts.failed) != 0 || len(scripts.recognized) != 0 {
+		t.Errorf("recorded %v and %v while shutting down, want nothing", scripts.failed, scripts.recognized)
+	}
+}
+
+func TestNewScriptProcessorClampsSettings(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		processor := NewScriptProcessor(newFakeScripts(), storage.NewMemoryStorage(), ocr.NewStubRecognizer(), &fakeMatcher{}, 0, interval)
+		if processor.pollInterval != defaultPollInterval {
+			t.Errorf("poll interval %v became %v, want %v", interval, processor.pollInterval, defaultPollInterval)
+		}
+		if processor.concurrency != 1 {
+			t.Errorf("concurrency became %d, want 1", processor.concurrency)
+		}
+	}
+}
+
+type storedScript struct {
+	models.AnswerScript
+	store *storage.MemoryStorage
+}
+
+// Stores a small file for a new answer script with the given Id
+func testScript(t *testing.T, id string) storedScript {
+	t.Helper()
+	script := storedScript{store: storage.NewMemoryStorage()}
+	script.Id = id
+	script.FileName = id + ".pdf"
+	if _, err := script.store.Put(context.Background(), script.ObjectKey(), strings.NewReader("%PDF"), 4, "application/pdf"); err != nil {
+		t.Fatalf("Put %s: %v", id, err)
+	}
+	return script
+}